@@ -0,0 +1,332 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/fabyo/gordon-watcher/internal/api"
+	"github.com/fabyo/gordon-watcher/internal/config"
+	"github.com/fabyo/gordon-watcher/internal/storage"
+	"github.com/fabyo/gordon-watcher/internal/watcher"
+)
+
+const usage = `Usage:
+  gordon-watcher                       run the watcher
+  gordon-watcher config validate       check the configuration and compile every pattern
+  gordon-watcher storage migrate ...   copy state between storage backends
+
+Run "gordon-watcher storage migrate -h" for the migrate options.
+`
+
+// runCommand runs a subcommand and returns the process exit code
+func runCommand(args []string) int {
+	switch {
+	case len(args) >= 2 && args[0] == "config" && args[1] == "validate":
+		return runConfigValidate(args[2:], os.Stdout, os.Stderr)
+	case len(args) >= 2 && args[0] == "storage" && args[1] == "migrate":
+		return runStorageMigrate(args[2:], os.Stdout, os.Stderr)
+	case args[0] == "help" || args[0] == "-h" || args[0] == "--help":
+		fmt.Print(usage)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command: %v\n\n%s", args, usage)
+		return 2
+	}
+}
+
+// runConfigValidate implements "config validate": it loads the
+// configuration like the watcher would and reports the first problem
+func runConfigValidate(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("config validate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: gordon-watcher config validate")
+		fmt.Fprintln(stderr, "\nChecks the config file and environment the watcher would start with.")
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to load config: %v\n", err)
+		return 1
+	}
+
+	if err := checkConfig(cfg); err != nil {
+		fmt.Fprintf(stderr, "Invalid configuration: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintln(stdout, "Configuration is valid")
+	return 0
+}
+
+// checkConfig validates the configuration and compiles the watcher's file
+// patterns, so a bad pattern is reported before anything starts
+func checkConfig(cfg *config.Config) error {
+	if err := config.Validate(cfg); err != nil {
+		return err
+	}
+
+	if _, err := watcher.CompilePatterns(cfg.Watcher.FilePatterns); err != nil {
+		return fmt.Errorf("watcher.file_patterns: %w", err)
+	}
+	if _, err := watcher.CompilePatterns(cfg.Watcher.ExcludePatterns); err != nil {
+		return fmt.Errorf("watcher.exclude_patterns: %w", err)
+	}
+
+	for i, src := range cfg.Watcher.Sources {
+		if _, err := watcher.CompilePatterns(src.FilePatterns); err != nil {
+			return fmt.Errorf("watcher.sources[%d].file_patterns: %w", i, err)
+		}
+		if _, err := watcher.CompilePatterns(src.ExcludePatterns); err != nil {
+			return fmt.Errorf("watcher.sources[%d].exclude_patterns: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// migrateState is the progress file of storage migrate
+type migrateState struct {
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	storage.MigrateProgress
+}
+
+// runStorageMigrate implements "storage migrate". Progress is saved after
+// every batch so an interrupted run continues where it stopped.
+func runStorageMigrate(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("storage migrate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	from := fs.String("from", "", "source storage type (redis, bolt, postgres, sqlite, memory)")
+	to := fs.String("to", "", "destination storage type (redis, bolt, postgres, sqlite)")
+	dryRun := fs.Bool("dry-run", false, "count the records to migrate without writing them")
+	batchSize := fs.Int("batch-size", 500, "records read and written per batch")
+	progressPath := fs.String("progress", "", "progress file (default <working_dir>/storage-migrate-<from>-<to>.json)")
+	restart := fs.Bool("restart", false, "ignore an existing progress file and start over")
+	apiURL := fs.String("api-url", "", "API of the running watcher to read memory storage from (default from api.addr)")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: gordon-watcher storage migrate --from <type> --to <type> [options]")
+		fmt.Fprintln(stderr, "\nBoth backends are configured by the regular config file and environment.")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	if err := validateMigrateTypes(*from, *to); err != nil {
+		fmt.Fprintf(stderr, "%v\n\n", err)
+		fs.Usage()
+		return 2
+	}
+	if *batchSize <= 0 {
+		fmt.Fprintln(stderr, "--batch-size must be greater than 0")
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to load config: %v\n", err)
+		return 1
+	}
+
+	if *progressPath == "" {
+		*progressPath = filepath.Join(cfg.Watcher.WorkingDir, fmt.Sprintf("storage-migrate-%s-%s.json", *from, *to))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Memory storage is read from the running watcher through its API
+	var source storage.Querier
+	if *from == "memory" {
+		if *apiURL == "" {
+			*apiURL = localAPIURL(cfg.API.Addr)
+		}
+		source = api.NewClient(*apiURL)
+	} else {
+		store, err := newStorage(cfg, *from)
+		if err != nil {
+			fmt.Fprintf(stderr, "Failed to open %s storage: %v\n", *from, err)
+			return 1
+		}
+		defer store.Close()
+		source = store
+	}
+
+	opts := storage.MigrateOptions{BatchSize: *batchSize, DryRun: *dryRun}
+
+	// A dry run only reads the source
+	var dest storage.Importer
+	if !*dryRun {
+		target, err := newStorage(cfg, *to)
+		if err != nil {
+			fmt.Fprintf(stderr, "Failed to open %s storage: %v\n", *to, err)
+			return 1
+		}
+		defer target.Close()
+
+		importer, ok := target.(storage.Importer)
+		if !ok {
+			fmt.Fprintf(stderr, "%s storage does not support importing records\n", *to)
+			return 1
+		}
+		dest = importer
+
+		if !*restart {
+			state, err := loadMigrateState(*progressPath)
+			if err != nil {
+				fmt.Fprintf(stderr, "Failed to read progress file: %v\n", err)
+				return 1
+			}
+			if state != nil {
+				if state.From != *from || state.To != *to {
+					fmt.Fprintf(stderr, "Progress file %s belongs to a %s -> %s migration; use --restart or --progress\n",
+						*progressPath, state.From, state.To)
+					return 1
+				}
+				opts.Resume = state.MigrateProgress
+				fmt.Fprintf(stdout, "Resuming after %d records (%s)\n", opts.Resume.Total(), *progressPath)
+			}
+		}
+
+		opts.OnBatch = func(p storage.MigrateProgress) error {
+			fmt.Fprintf(stdout, "Migrated %d records\n", p.Total())
+			return saveMigrateState(*progressPath, migrateState{From: *from, To: *to, UpdatedAt: time.Now(), MigrateProgress: p})
+		}
+	}
+
+	progress, err := storage.Migrate(ctx, source, dest, opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "Migration stopped: %v\n", err)
+		if !*dryRun {
+			fmt.Fprintf(stderr, "Run the same command again to resume from %s\n", *progressPath)
+		}
+		return 1
+	}
+
+	if *dryRun {
+		fmt.Fprintf(stdout, "Dry run: %d records would be migrated from %s to %s\n", progress.Total(), *from, *to)
+	} else {
+		fmt.Fprintf(stdout, "Migrated %d records from %s to %s\n", progress.Total(), *from, *to)
+		if err := os.Remove(*progressPath); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(stderr, "Failed to remove progress file: %v\n", err)
+		}
+	}
+
+	statuses := make([]string, 0, len(progress.Counts))
+	for status := range progress.Counts {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	for _, status := range statuses {
+		fmt.Fprintf(stdout, "  %-10s %d\n", status, progress.Counts[status])
+	}
+	if progress.Skipped > 0 {
+		fmt.Fprintf(stdout, "  %-10s %d (expired during the migration)\n", "skipped", progress.Skipped)
+	}
+	if progress.FencingToken > 0 {
+		if *dryRun {
+			fmt.Fprintf(stdout, "Fencing tokens of %s would start above %d\n", *to, progress.FencingToken)
+		} else {
+			fmt.Fprintf(stdout, "Fencing tokens of %s start above %d\n", *to, progress.FencingToken)
+		}
+	}
+
+	return 0
+}
+
+// validateMigrateTypes checks the migrate endpoints. Memory storage only
+// exists inside a running watcher: it can be read through that watcher's
+// API, but nothing written there by a separate process would survive.
+func validateMigrateTypes(from, to string) error {
+	if from == "" || to == "" {
+		return errors.New("--from and --to are required")
+	}
+	if from == to {
+		return errors.New("--from and --to must be different storage types")
+	}
+	if to == "memory" {
+		return errors.New("memory storage is lost when the watcher stops; switch storage.type and restart instead")
+	}
+
+	for _, t := range []string{from, to} {
+		switch t {
+		case "redis", "bolt", "postgres", "sqlite", "memory":
+		default:
+			return fmt.Errorf("unsupported storage type: %s", t)
+		}
+	}
+	return nil
+}
+
+// localAPIURL returns the URL of the API listening on addr on this host
+func localAPIURL(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "http://" + addr
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
+
+// loadMigrateState reads the progress file (nil if it does not exist)
+func loadMigrateState(path string) (*migrateState, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var state migrateState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid progress file %s: %w", path, err)
+	}
+	return &state, nil
+}
+
+// saveMigrateState writes the progress file atomically, so a crash leaves
+// either the previous or the new progress
+func saveMigrateState(path string, state migrateState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
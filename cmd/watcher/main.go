@@ -2,13 +2,16 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"runtime"
 	"syscall"
 	"time"
 
+	"github.com/fabyo/gordon-watcher/internal/api"
 	"github.com/fabyo/gordon-watcher/internal/config"
 	"github.com/fabyo/gordon-watcher/internal/health"
 	"github.com/fabyo/gordon-watcher/internal/logger"
@@ -26,6 +29,11 @@ var (
 )
 
 func main() {
+	// Subcommands (config validate, storage migrate) run instead of the watcher
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
@@ -33,7 +41,7 @@ func main() {
 	}
 
 	// Validate configuration
-	if err := config.Validate(cfg); err != nil {
+	if err := checkConfig(cfg); err != nil {
 		log.Fatalf("Invalid configuration: %v", err)
 	}
 
@@ -103,24 +111,28 @@ func main() {
 	}
 
 	// Initialize storage
-	var store storage.Storage
-	if cfg.Redis.Enabled {
-		redisStore, err := storage.NewRedisStorage(storage.RedisConfig{
-			Addr:     cfg.Redis.Addr,
-			Password: cfg.Redis.Password,
-			DB:       cfg.Redis.DB,
-		})
-		if err != nil {
-			appLog.Error("Failed to initialize Redis", "error", err)
-			appLog.Info("Falling back to memory storage")
-			store = storage.NewMemoryStorage()
-		} else {
-			store = redisStore
-			appLog.Info("Redis storage initialized")
-		}
-	} else {
-		store = storage.NewMemoryStorage()
-		appLog.Info("Memory storage initialized")
+	store, err := newStorage(cfg, cfg.Storage.Type)
+	switch {
+	case err != nil && cfg.Storage.Type == "redis":
+		appLog.Error("Failed to initialize Redis", "error", err)
+		appLog.Info("Falling back to memory storage")
+		store, _ = newStorage(cfg, "memory")
+	case err != nil:
+		appLog.Error("Failed to initialize storage", "type", cfg.Storage.Type, "error", err)
+		os.Exit(1)
+	default:
+		appLog.Info("Storage initialized", "type", cfg.Storage.Type)
+	}
+
+	// Start the file state API
+	var apiServer *api.Server
+	if cfg.API.Enabled {
+		apiServer = api.NewServer(cfg.API.Addr, store, appLog)
+		go func() {
+			if err := apiServer.Start(); err != nil && err != http.ErrServerClosed {
+				appLog.Error("API server failed", "error", err)
+			}
+		}()
 	}
 
 	defer func() {
@@ -132,20 +144,19 @@ func main() {
 	// Initialize queue
 	var q queue.Queue
 	if cfg.Queue.Enabled {
-		rabbitQueue, err := queue.NewRabbitMQQueue(queue.RabbitMQConfig{
-			URL:        cfg.Queue.RabbitMQ.URL,
-			Exchange:   cfg.Queue.RabbitMQ.Exchange,
-			QueueName:  cfg.Queue.RabbitMQ.QueueName,
-			RoutingKey: cfg.Queue.RabbitMQ.RoutingKey,
-			Durable:    cfg.Queue.RabbitMQ.Durable,
-		}, appLog)
+		// No fallback: publishing into a NoOp queue would silently drop files
+		brokerQueue, err := newQueue(cfg, store, appLog)
 		if err != nil {
-			appLog.Error("Failed to initialize RabbitMQ", "error", err)
-			appLog.Info("Falling back to NoOp queue")
-			q = queue.NewNoOpQueue(appLog)
-		} else {
-			q = rabbitQueue
-			appLog.Info("RabbitMQ queue initialized")
+			appLog.Error("Failed to initialize queue", "type", cfg.Queue.Type, "error", err)
+			os.Exit(1)
+		}
+		q = brokerQueue
+		appLog.Info("Queue initialized", "type", cfg.Queue.Type)
+
+		// Report not-ready while the broker connection is down; liveness
+		// stays up so the supervisor can reconnect instead of being restarted
+		if hc, ok := q.(queue.HealthChecker); ok {
+			healthServer.AddReadinessCheck("queue", hc.Check)
 		}
 	} else {
 		q = queue.NewNoOpQueue(appLog)
@@ -161,12 +172,16 @@ func main() {
 	// Create watcher
 	w, err := watcher.New(watcher.Config{
 		Paths:             cfg.Watcher.Paths,
+		Sources:           watcherSources(cfg.Watcher.Sources),
 		FilePatterns:      cfg.Watcher.FilePatterns,
 		ExcludePatterns:   cfg.Watcher.ExcludePatterns,
+		PathOptions:       pathOptions(cfg.Watcher.PathOptions),
 		MinFileSize:       cfg.Watcher.MinFileSize,
 		MaxFileSize:       cfg.Watcher.MaxFileSize,
+		StabilityMode:     cfg.Watcher.StabilityMode,
+		Marker:            markerConfig(cfg.Watcher.Marker),
 		StableAttempts:    cfg.Watcher.StableAttempts,
-		StableDelay:       time.Duration(cfg.Watcher.StableDelay),     // ✅ Converter aqui
+		StableDelay:       cfg.Watcher.StableDelay,
 		CleanupInterval:   time.Duration(cfg.Watcher.CleanupInterval), // ✅ Converter aqui
 		MaxWorkers:        cfg.Watcher.MaxWorkers,
 		MaxFilesPerSecond: cfg.Watcher.MaxFilesPerSecond,
@@ -243,6 +258,7 @@ func main() {
 			select {
 			case <-ticker.C:
 				metrics.GoroutineCount.Set(float64(runtime.NumGoroutine()))
+				updateStorageEntries(ctx, store)
 			case <-ctx.Done():
 				return
 			}
@@ -273,5 +289,443 @@ func main() {
 		appLog.Error("Error shutting down health server", "error", err)
 	}
 
+	if apiServer != nil {
+		if err := apiServer.Shutdown(shutdownCtx); err != nil {
+			appLog.Error("Error shutting down API server", "error", err)
+		}
+	}
+
 	appLog.Info("Gordon Watcher stopped")
 }
+
+// newStorage creates the storage backend of the given type (storage.type
+// or a storage migrate endpoint)
+func newStorage(cfg *config.Config, storageType string) (storage.Storage, error) {
+	ttl := storage.TTLConfig{
+		Enqueued:  cfg.Storage.TTL.Enqueued,
+		Processed: cfg.Storage.TTL.Processed,
+		Failed:    cfg.Storage.TTL.Failed,
+		Lock:      cfg.Storage.TTL.Lock,
+	}
+
+	switch storageType {
+	case "redis":
+		redisCfg := redisConfig(cfg.Redis)
+		redisCfg.TTL = ttl
+		return storage.NewRedisStorage(redisCfg)
+	case "bolt":
+		return storage.NewBoltStorage(storage.BoltConfig{
+			Path:            cfg.Storage.Bolt.Path,
+			CompactInterval: cfg.Storage.Bolt.CompactInterval,
+			TTL:             ttl,
+		})
+	case "postgres":
+		return storage.NewPostgresStorage(storage.PostgresConfig{
+			DSN:               cfg.Storage.Postgres.DSN,
+			MaxConns:          cfg.Storage.Postgres.MaxConns,
+			RetentionInterval: cfg.Storage.Postgres.RetentionInterval,
+			TTL:               ttl,
+		})
+	case "sqlite":
+		return storage.NewSQLiteStorage(storage.SQLiteConfig{
+			Path:              cfg.Storage.SQLite.Path,
+			TTL:               ttl,
+			Retention:         cfg.Storage.SQLite.Retention,
+			RetentionInterval: cfg.Storage.SQLite.RetentionInterval,
+		})
+	case "memory":
+		return storage.NewMemoryStorageWithConfig(storage.MemoryConfig{
+			TTL:             ttl,
+			JanitorInterval: cfg.Storage.Memory.JanitorInterval,
+		}), nil
+	default:
+		return nil, fmt.Errorf("unsupported storage type: %s", storageType)
+	}
+}
+
+// newQueue creates the queue backend selected by queue.type
+func newQueue(cfg *config.Config, store storage.Storage, log *logger.Logger) (queue.Queue, error) {
+	encoders := newQueueEncoders(cfg.Queue.Encoding)
+	encoder, err := encoders.format("")
+	if err != nil {
+		return nil, err
+	}
+
+	targets := newSourceTargets(cfg.Watcher.Sources)
+	destinations, err := encoders.destinations(targets.formats)
+	if err != nil {
+		return nil, err
+	}
+
+	switch cfg.Queue.Type {
+	case "rabbitmq":
+		// Source targets are matched before the configured routes
+		routes, err := rabbitMQRoutes(append(targets.routes, cfg.Queue.RabbitMQ.Routes...), encoders)
+		if err != nil {
+			return nil, err
+		}
+		encoder, err = encoders.destination("", cfg.Queue.RabbitMQ.RoutingKey)
+		if err != nil {
+			return nil, err
+		}
+
+		return queue.NewRabbitMQQueue(queue.RabbitMQConfig{
+			URL:            cfg.Queue.RabbitMQ.URL,
+			Exchange:       cfg.Queue.RabbitMQ.Exchange,
+			QueueName:      cfg.Queue.RabbitMQ.QueueName,
+			RoutingKey:     cfg.Queue.RabbitMQ.RoutingKey,
+			Durable:        cfg.Queue.RabbitMQ.Durable,
+			ResultsQueue:   cfg.Queue.RabbitMQ.ResultsQueue,
+			ConfirmTimeout: cfg.Queue.RabbitMQ.ConfirmTimeout,
+
+			ReconnectInitialDelay: cfg.Queue.RabbitMQ.ReconnectInitialDelay,
+			ReconnectMaxDelay:     cfg.Queue.RabbitMQ.ReconnectMaxDelay,
+
+			Routes: routes,
+
+			DLQEnabled:  cfg.Queue.RabbitMQ.DLQEnabled,
+			DLQExchange: cfg.Queue.RabbitMQ.DLQExchange,
+			DLQQueue:    cfg.Queue.RabbitMQ.DLQQueue,
+
+			MessageTTL: cfg.Queue.RabbitMQ.MessageTTL,
+			MaxLength:  cfg.Queue.RabbitMQ.MaxLength,
+			Overflow:   cfg.Queue.RabbitMQ.Overflow,
+			Encoder:    encoder,
+		}, log)
+
+	case "kafka":
+		return queue.NewKafkaQueue(queue.KafkaConfig{
+			Brokers:      cfg.Queue.Kafka.Brokers,
+			ClientID:     cfg.Queue.Kafka.ClientID,
+			Version:      cfg.Queue.Kafka.Version,
+			Topic:        cfg.Queue.Kafka.Topic,
+			Topics:       cfg.Queue.Kafka.Topics,
+			SourceTopics: targets.topics,
+			Idempotent:   cfg.Queue.Kafka.Idempotent,
+			MaxRetries:   cfg.Queue.Kafka.MaxRetries,
+			Encoder:      encoder,
+
+			TopicEncoders: destinations,
+		}, log)
+
+	case "nats":
+		return queue.NewNATSQueue(queue.NATSConfig{
+			URL:              cfg.Queue.NATS.URL,
+			Stream:           cfg.Queue.NATS.Stream,
+			SubjectTemplate:  cfg.Queue.NATS.SubjectTemplate,
+			SourceSubjects:   targets.subjects,
+			AutoCreateStream: cfg.Queue.NATS.AutoCreateStream,
+			DuplicateWindow:  cfg.Queue.NATS.DuplicateWindow,
+			AckTimeout:       cfg.Queue.NATS.AckTimeout,
+			Encoder:          encoder,
+			SubjectEncoders:  destinations,
+		}, log)
+
+	case "redis":
+		streamCfg := queue.RedisStreamConfig{
+			Stream:          cfg.Queue.Redis.Stream,
+			Streams:         cfg.Queue.Redis.Streams,
+			SourceStreams:   targets.streams,
+			MaxLen:          cfg.Queue.Redis.MaxLen,
+			ApproximateTrim: cfg.Queue.Redis.ApproximateTrim,
+			Group:           cfg.Queue.Redis.Group,
+			KeyPrefix:       streamKeyPrefix(cfg.Redis.KeyPrefix),
+			Encoder:         encoder,
+			StreamEncoders:  destinations,
+		}
+
+		// Share the storage connection so XADD and MarkEnqueued run in one
+		// MULTI. Cluster transactions cannot span the stream and state slots,
+		// so there the watcher records the state itself.
+		if redisStore, ok := store.(*storage.RedisStorage); ok {
+			var state queue.EnqueuedRecorder
+			if !redisStore.Cluster() {
+				state = redisStore
+			}
+			return queue.NewRedisStreamQueue(streamCfg, redisStore.Client(), state, log)
+		}
+
+		client, err := storage.NewRedisClient(redisConfig(cfg.Redis))
+		if err != nil {
+			return nil, err
+		}
+		return queue.NewRedisStreamQueueWithClient(streamCfg, client, log)
+
+	case "webhook":
+		return queue.NewWebhookQueue(queue.WebhookConfig{
+			URLs:       cfg.Queue.Webhook.URLs,
+			KindURLs:   cfg.Queue.Webhook.KindURLs,
+			SourceURLs: targets.urls,
+			Secret:     cfg.Queue.Webhook.Secret,
+			Timeout:    cfg.Queue.Webhook.Timeout,
+			Headers:    cfg.Queue.Webhook.Headers,
+			Encoder:    encoder,
+
+			URLEncoders: destinations,
+		}, log)
+
+	default:
+		return nil, fmt.Errorf("unsupported queue type: %s", cfg.Queue.Type)
+	}
+}
+
+// queueEncoders builds the encoders of queue.encoding, one per format
+type queueEncoders struct {
+	cfg      config.EncodingConfig
+	byFormat map[string]queue.Encoder
+}
+
+func newQueueEncoders(cfg config.EncodingConfig) *queueEncoders {
+	return &queueEncoders{cfg: cfg, byFormat: make(map[string]queue.Encoder)}
+}
+
+// format returns the encoder of a format; empty selects
+// queue.encoding.format
+func (e *queueEncoders) format(format string) (queue.Encoder, error) {
+	if format == "" {
+		format = e.cfg.Format
+	}
+	if encoder, ok := e.byFormat[format]; ok {
+		return encoder, nil
+	}
+
+	encoder, err := newFormatEncoder(format, e.cfg)
+	if err != nil {
+		return nil, err
+	}
+	e.byFormat[format] = encoder
+	return encoder, nil
+}
+
+// destination returns the encoder of one destination: format if set, else
+// the one queue.encoding.destination_formats gives it, else the default
+func (e *queueEncoders) destination(format, dest string) (queue.Encoder, error) {
+	for _, d := range e.cfg.DestinationFormats {
+		if format == "" && d.Destination == dest {
+			format = d.Format
+		}
+	}
+	return e.format(format)
+}
+
+// destinations returns an encoder per destination listed in
+// queue.encoding.destination_formats or given a format by a source target
+func (e *queueEncoders) destinations(targets map[string]string) (map[string]queue.Encoder, error) {
+	formats := make(map[string]string, len(e.cfg.DestinationFormats)+len(targets))
+	for _, d := range e.cfg.DestinationFormats {
+		formats[d.Destination] = d.Format
+	}
+	for dest, format := range targets {
+		formats[dest] = format
+	}
+
+	result := make(map[string]queue.Encoder, len(formats))
+	for dest, format := range formats {
+		encoder, err := e.format(format)
+		if err != nil {
+			return nil, err
+		}
+		result[dest] = encoder
+	}
+	return result, nil
+}
+
+// newFormatEncoder creates the encoder for a single format
+func newFormatEncoder(format string, cfg config.EncodingConfig) (queue.Encoder, error) {
+	switch format {
+	case "", "json":
+		return queue.JSONEncoder{}, nil
+	case "cloudevents":
+		return queue.NewCloudEventsEncoder(queue.CloudEventsConfig{
+			Mode:    cfg.CloudEvents.Mode,
+			Source:  cfg.CloudEvents.Source,
+			Type:    cfg.CloudEvents.Type,
+			Subject: cfg.CloudEvents.Subject,
+		})
+	case "protobuf":
+		return queue.ProtobufEncoder{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported queue encoding: %s", format)
+	}
+}
+
+// rabbitMQRoutes converts configured routing rules, each with the encoder
+// of its format or routing key
+func rabbitMQRoutes(routes []config.RouteConfig, encoders *queueEncoders) ([]queue.Route, error) {
+	result := make([]queue.Route, 0, len(routes))
+	for _, r := range routes {
+		encoder, err := encoders.destination(r.Format, r.RoutingKey)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, queue.Route{
+			Kind:       r.Kind,
+			Pattern:    r.Pattern,
+			Source:     r.Source,
+			Exchange:   r.Exchange,
+			RoutingKey: r.RoutingKey,
+			Queue:      r.Queue,
+			Encoder:    encoder,
+		})
+	}
+	return result, nil
+}
+
+// sourceTargets holds the destinations of watcher.sources, keyed by source
+// path, in the shape each queue backend takes them
+type sourceTargets struct {
+	routes   []config.RouteConfig // rabbitmq
+	topics   map[string]string
+	streams  map[string]string
+	subjects map[string]string
+	urls     map[string][]string
+
+	// formats holds target.format keyed by destination instead of source,
+	// since encoders are chosen per destination
+	formats map[string]string
+}
+
+// newSourceTargets collects the targets of watcher.sources, skipping
+// sources that leave them empty
+func newSourceTargets(sources []config.SourceConfig) sourceTargets {
+	t := sourceTargets{
+		topics:   make(map[string]string),
+		streams:  make(map[string]string),
+		subjects: make(map[string]string),
+		urls:     make(map[string][]string),
+		formats:  make(map[string]string),
+	}
+
+	for _, src := range sources {
+		target := src.Target
+		if target.RoutingKey != "" {
+			t.routes = append(t.routes, config.RouteConfig{
+				Source:     src.Path,
+				Exchange:   target.Exchange,
+				RoutingKey: target.RoutingKey,
+				Queue:      target.Queue,
+				Format:     target.Format,
+			})
+		}
+		if target.Topic != "" {
+			t.topics[src.Path] = target.Topic
+		}
+		if target.Stream != "" {
+			t.streams[src.Path] = target.Stream
+		}
+		if target.Subject != "" {
+			t.subjects[src.Path] = target.Subject
+		}
+		if len(target.URLs) > 0 {
+			t.urls[src.Path] = target.URLs
+		}
+
+		if target.Format != "" {
+			for _, dest := range append([]string{target.Topic, target.Stream, target.Subject}, target.URLs...) {
+				if dest != "" {
+					t.formats[dest] = target.Format
+				}
+			}
+		}
+	}
+
+	return t
+}
+
+// watcherSources converts the per-path pipelines of watcher.sources
+func watcherSources(sources []config.SourceConfig) []watcher.Source {
+	result := make([]watcher.Source, 0, len(sources))
+	for _, src := range sources {
+		result = append(result, watcher.Source{
+			Path:              src.Path,
+			FilePatterns:      src.FilePatterns,
+			ExcludePatterns:   src.ExcludePatterns,
+			MinFileSize:       src.MinFileSize,
+			MaxFileSize:       src.MaxFileSize,
+			StabilityMode:     src.StabilityMode,
+			StableAttempts:    src.StableAttempts,
+			StableDelay:       src.StableDelay,
+			Marker:            markerConfig(src.Marker),
+			Mode:              src.Mode,
+			PollInterval:      src.PollInterval,
+			MaxFilesPerSecond: src.MaxFilesPerSecond,
+		})
+	}
+	return result
+}
+
+// pathOptions converts the per-path watch modes, keyed by watch path
+func pathOptions(options []config.PathOptionsConfig) map[string]watcher.PathOptions {
+	result := make(map[string]watcher.PathOptions, len(options))
+	for _, o := range options {
+		result[o.Path] = watcher.PathOptions{
+			Mode:         o.Mode,
+			PollInterval: o.PollInterval,
+		}
+	}
+	return result
+}
+
+// markerConfig converts the done-marker settings
+func markerConfig(cfg config.MarkerConfig) watcher.MarkerConfig {
+	return watcher.MarkerConfig{
+		Templates:    cfg.Templates,
+		Timeout:      cfg.Timeout,
+		PollInterval: cfg.PollInterval,
+		Action:       cfg.Action,
+		ArchiveDir:   cfg.ArchiveDir,
+	}
+}
+
+// streamKeyPrefix returns the prefix of Redis stream names. Streams were not
+// namespaced before key_prefix existed, so the default prefix keeps the
+// configured names as they are.
+func streamKeyPrefix(prefix string) string {
+	if prefix == storage.DefaultRedisKeyPrefix {
+		return ""
+	}
+	return prefix
+}
+
+// redisConfig converts the connection settings shared by storage and queue
+func redisConfig(cfg config.RedisConfig) storage.RedisConfig {
+	return storage.RedisConfig{
+		Mode:             cfg.Mode,
+		Addr:             cfg.Addr,
+		Addrs:            cfg.Addrs,
+		MasterName:       cfg.MasterName,
+		SentinelUsername: cfg.SentinelUsername,
+		SentinelPassword: cfg.SentinelPassword,
+		Username:         cfg.Username,
+		Password:         cfg.Password,
+		DB:               cfg.DB,
+		TLS: storage.RedisTLSConfig{
+			Enabled:            cfg.TLS.Enabled,
+			CAFile:             cfg.TLS.CAFile,
+			CertFile:           cfg.TLS.CertFile,
+			KeyFile:            cfg.TLS.KeyFile,
+			ServerName:         cfg.TLS.ServerName,
+			InsecureSkipVerify: cfg.TLS.InsecureSkipVerify,
+		},
+		KeyPrefix: cfg.KeyPrefix,
+	}
+}
+
+// updateStorageEntries reports entry counts for storages that can count
+// cheaply (Redis would need a full SCAN, so it is skipped)
+func updateStorageEntries(ctx context.Context, store storage.Storage) {
+	counter, ok := store.(storage.EntryCounter)
+	if !ok {
+		return
+	}
+
+	counts, err := counter.CountEntries(ctx)
+	if err != nil {
+		metrics.StorageErrors.Inc()
+		return
+	}
+
+	for status, n := range counts {
+		metrics.StorageEntries.WithLabelValues(status).Set(float64(n))
+	}
+}
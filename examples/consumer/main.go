@@ -13,19 +13,32 @@ import (
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+// supportedSchemaVersion is the newest message schema this consumer understands
+const supportedSchemaVersion = 1
+
 // Message represents the structure of messages from gordon-watcher
+// (see internal/queue/schema/message.schema.json)
 type Message struct {
-	Path      string    `json:"path"`
-	Hash      string    `json:"hash"`
-	Size      int64     `json:"size"`
-	Timestamp time.Time `json:"timestamp"`
-	Queue     string    `json:"queue"`
+	SchemaVersion int       `json:"schema_version"`
+	ID            string    `json:"id"`
+	Path          string    `json:"path"`
+	Filename      string    `json:"filename"`
+	Kind          string    `json:"kind"`
+	Size          int64     `json:"size"`
+	Hash          string    `json:"hash"`
+	Source        string    `json:"source,omitempty"`
+	Timestamp     time.Time `json:"timestamp"`
+	FencingToken  uint64    `json:"fencing_token,omitempty"`
 }
 
 type Consumer struct {
 	conn    *amqp.Connection
 	channel *amqp.Channel
 	logger  *slog.Logger
+
+	// Highest fencing token seen per hash. Keep this in your database in
+	// production so it survives restarts and is shared between replicas.
+	fencingTokens map[string]uint64
 }
 
 func NewConsumer(rabbitURL string) (*Consumer, error) {
@@ -52,9 +65,10 @@ func NewConsumer(rabbitURL string) (*Consumer, error) {
 	}
 
 	return &Consumer{
-		conn:    conn,
-		channel: ch,
-		logger:  logger,
+		conn:          conn,
+		channel:       ch,
+		logger:        logger,
+		fencingTokens: make(map[string]uint64),
 	}, nil
 }
 
@@ -99,11 +113,28 @@ func (c *Consumer) Start(ctx context.Context, queueName string) error {
 				return fmt.Errorf("channel closed")
 			}
 
-			if err := c.processMessage(ctx, msg); err != nil {
+			if c.isStale(msg) {
+				// A newer publisher holds the lock for this file; drop silently
+				c.logger.Warn("Dropping message from stale publisher", "message_id", msg.MessageId)
+				msg.Ack(false)
+				continue
+			}
+
+			procErr := c.processMessage(ctx, msg)
+			if procErr != nil {
 				c.logger.Error("Failed to process message",
+					"error", procErr,
+					"message_id", msg.MessageId)
+			}
+
+			// Report the outcome so the watcher can move the file
+			if err := c.reply(ctx, msg, procErr); err != nil {
+				c.logger.Error("Failed to publish result",
 					"error", err,
 					"message_id", msg.MessageId)
+			}
 
+			if procErr != nil {
 				// Reject and requeue (or send to DLQ if configured)
 				msg.Nack(false, false)
 			} else {
@@ -114,17 +145,39 @@ func (c *Consumer) Start(ctx context.Context, queueName string) error {
 	}
 }
 
+// isStale reports whether a newer fencing token was already seen for the
+// message's hash, and records the token otherwise
+func (c *Consumer) isStale(delivery amqp.Delivery) bool {
+	var msg Message
+	if err := json.Unmarshal(delivery.Body, &msg); err != nil || msg.FencingToken == 0 {
+		return false
+	}
+
+	if msg.FencingToken < c.fencingTokens[msg.Hash] {
+		return true
+	}
+
+	c.fencingTokens[msg.Hash] = msg.FencingToken
+	return false
+}
+
 func (c *Consumer) processMessage(ctx context.Context, delivery amqp.Delivery) error {
 	var msg Message
 	if err := json.Unmarshal(delivery.Body, &msg); err != nil {
 		return fmt.Errorf("failed to unmarshal message: %w", err)
 	}
 
+	if msg.SchemaVersion > supportedSchemaVersion {
+		return fmt.Errorf("unsupported message schema version %d", msg.SchemaVersion)
+	}
+
 	c.logger.Info("Processing file",
+		"id", msg.ID,
+		"filename", msg.Filename,
+		"kind", msg.Kind,
 		"path", msg.Path,
 		"hash", msg.Hash,
-		"size", msg.Size,
-		"queue", msg.Queue)
+		"size", msg.Size)
 
 	// TODO: Implement your business logic here
 	// Examples:
@@ -145,6 +198,56 @@ func (c *Consumer) processMessage(ctx context.Context, delivery amqp.Delivery) e
 	return nil
 }
 
+// Result is the outcome reported back to gordon-watcher
+type Result struct {
+	ID        string    `json:"id"`
+	Path      string    `json:"path,omitempty"`
+	Status    string    `json:"status"`
+	Reason    string    `json:"reason,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// reply publishes the processing result to the watcher's results queue
+func (c *Consumer) reply(ctx context.Context, delivery amqp.Delivery, procErr error) error {
+	if delivery.ReplyTo == "" {
+		return nil // Watcher is not collecting results
+	}
+
+	var msg Message
+	_ = json.Unmarshal(delivery.Body, &msg)
+
+	res := Result{
+		ID:        delivery.MessageId,
+		Path:      msg.Path,
+		Status:    "success",
+		Timestamp: time.Now(),
+	}
+	if procErr != nil {
+		res.Status = "failure"
+		res.Reason = procErr.Error()
+	}
+
+	body, err := json.Marshal(res)
+	if err != nil {
+		return fmt.Errorf("failed to marshal result: %w", err)
+	}
+
+	return c.channel.PublishWithContext(
+		ctx,
+		"",               // default exchange
+		delivery.ReplyTo, // routing key (results queue)
+		false,            // mandatory
+		false,            // immediate
+		amqp.Publishing{
+			DeliveryMode:  amqp.Persistent,
+			ContentType:   "application/json",
+			CorrelationId: delivery.MessageId,
+			Body:          body,
+			Timestamp:     time.Now(),
+		},
+	)
+}
+
 func (c *Consumer) Close() error {
 	if c.channel != nil {
 		c.channel.Close()
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fabyo/gordon-watcher/internal/queue"
+)
+
+// These tests keep the example consumer in sync with what the watcher
+// publishes; a field added to queue.Message or queue.Result fails here
+// until the consumer learns about it.
+
+func TestMessage_MatchesProducer(t *testing.T) {
+	assertSameJSONShape(t, reflect.TypeOf(Message{}), reflect.TypeOf(queue.Message{}))
+
+	if supportedSchemaVersion != queue.SchemaVersion {
+		t.Errorf("supportedSchemaVersion = %d, producer SchemaVersion = %d", supportedSchemaVersion, queue.SchemaVersion)
+	}
+}
+
+func TestMessage_DecodesProducerPayload(t *testing.T) {
+	produced := queue.Message{
+		SchemaVersion: queue.SchemaVersion,
+		ID:            "abc",
+		Path:          "/data/processing/nota.xml",
+		Filename:      "nota.xml",
+		Kind:          "xml",
+		Size:          42,
+		Hash:          "abc",
+		Source:        "/data/incoming",
+		Timestamp:     time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		FencingToken:  3,
+	}
+
+	env, err := queue.JSONEncoder{}.Encode(&produced)
+	if err != nil {
+		t.Fatalf("Encode() failed: %v", err)
+	}
+
+	var consumed Message
+	dec := json.NewDecoder(bytes.NewReader(env.Body))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&consumed); err != nil {
+		t.Fatalf("consumer cannot decode producer payload: %v", err)
+	}
+
+	if consumed.ID != produced.ID || consumed.Kind != produced.Kind || consumed.Filename != produced.Filename {
+		t.Errorf("consumed = %+v, produced = %+v", consumed, produced)
+	}
+}
+
+func TestResult_MatchesWatcher(t *testing.T) {
+	assertSameJSONShape(t, reflect.TypeOf(Result{}), reflect.TypeOf(queue.Result{}))
+}
+
+// assertSameJSONShape compares JSON field names and Go kinds of two structs
+func assertSameJSONShape(t *testing.T, consumer, producer reflect.Type) {
+	t.Helper()
+
+	want := jsonShape(producer)
+	got := jsonShape(consumer)
+
+	for name, kind := range want {
+		if got[name] != kind {
+			t.Errorf("%s.%s: consumer has %v, producer has %v", consumer.Name(), name, got[name], kind)
+		}
+	}
+	for name := range got {
+		if _, ok := want[name]; !ok {
+			t.Errorf("%s.%s is not sent by the producer", consumer.Name(), name)
+		}
+	}
+}
+
+func jsonShape(t reflect.Type) map[string]reflect.Kind {
+	shape := make(map[string]reflect.Kind)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		shape[name] = f.Type.Kind()
+	}
+	return shape
+}
@@ -0,0 +1,235 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/fabyo/gordon-watcher/internal/logger"
+	"github.com/fabyo/gordon-watcher/internal/storage"
+)
+
+// maxLimit caps the page size a client can request
+const maxLimit = 1000
+
+// CountResponse is the /files/count response
+type CountResponse struct {
+	Status string `json:"status,omitempty"`
+	Count  int64  `json:"count"`
+}
+
+// ErrorResponse is returned with every non-2xx status
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// Server provides the read-only file state API:
+//
+//	GET /files/{hash}                                   current record
+//	GET /files?status=&since=&until=&limit=&cursor=     records, newest first
+//	GET /files/search?filename=<prefix>&limit=          records by filename prefix
+//	GET /files/count?status=                            number of records
+type Server struct {
+	addr   string
+	server *http.Server
+	store  storage.Querier
+	logger *logger.Logger
+}
+
+// NewServer creates a new API server
+func NewServer(addr string, store storage.Querier, log *logger.Logger) *Server {
+	s := &Server{
+		addr:   addr,
+		store:  store,
+		logger: log,
+	}
+
+	s.server = &http.Server{
+		Addr:         addr,
+		Handler:      s.Handler(),
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 30 * time.Second,
+	}
+
+	return s
+}
+
+// Handler returns the API routes
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /files", s.listHandler)
+	mux.HandleFunc("GET /files/search", s.searchHandler)
+	mux.HandleFunc("GET /files/count", s.countHandler)
+	mux.HandleFunc("GET /files/{hash}", s.getHandler)
+	return mux
+}
+
+// Start starts the API server
+func (s *Server) Start() error {
+	s.logger.Info("Starting API server", "addr", s.addr)
+	return s.server.ListenAndServe()
+}
+
+// Shutdown gracefully shuts down the server
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
+
+// getHandler handles GET /files/{hash}
+func (s *Server) getHandler(w http.ResponseWriter, r *http.Request) {
+	record, err := s.store.Get(r.Context(), r.PathValue("hash"))
+	if errors.Is(err, storage.ErrNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, record)
+}
+
+// listHandler handles GET /files
+func (s *Server) listHandler(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseListOptions(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	page, err := s.store.List(r.Context(), opts)
+	if errors.Is(err, storage.ErrInvalidCursor) {
+		writeError(w, http.StatusBadRequest, storage.ErrInvalidCursor)
+		return
+	}
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+// searchHandler handles GET /files/search
+func (s *Server) searchHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	prefix := q.Get("filename")
+	if prefix == "" {
+		writeError(w, http.StatusBadRequest, errors.New("filename is required"))
+		return
+	}
+
+	limit, err := parseLimit(q.Get("limit"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	records, err := s.store.SearchByFilename(r.Context(), prefix, limit)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, records)
+}
+
+// countHandler handles GET /files/count
+func (s *Server) countHandler(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if err := validateStatus(status); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	n, err := s.store.Count(r.Context(), status)
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, CountResponse{Status: status, Count: n})
+}
+
+// internalError logs err and hides it from the client
+func (s *Server) internalError(w http.ResponseWriter, err error) {
+	s.logger.Error("API request failed", "error", err)
+	writeError(w, http.StatusInternalServerError, errors.New("internal error"))
+}
+
+// parseListOptions reads the /files query parameters
+func parseListOptions(q url.Values) (storage.ListOptions, error) {
+	opts := storage.ListOptions{
+		Status: q.Get("status"),
+		Cursor: q.Get("cursor"),
+	}
+
+	if err := validateStatus(opts.Status); err != nil {
+		return opts, err
+	}
+
+	var err error
+	if opts.Since, err = parseTime(q.Get("since")); err != nil {
+		return opts, err
+	}
+	if opts.Until, err = parseTime(q.Get("until")); err != nil {
+		return opts, err
+	}
+	if opts.Limit, err = parseLimit(q.Get("limit")); err != nil {
+		return opts, err
+	}
+
+	return opts, nil
+}
+
+// validateStatus accepts an empty status or one stored by the backends
+func validateStatus(status string) error {
+	switch status {
+	case "", storage.StatusEnqueued, storage.StatusProcessed, storage.StatusFailed:
+		return nil
+	default:
+		return errors.New("status must be enqueued, processed or failed")
+	}
+}
+
+// parseTime parses an optional RFC 3339 timestamp
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, errors.New("since and until must be RFC 3339 timestamps")
+	}
+	return t, nil
+}
+
+// parseLimit parses an optional page size
+func parseLimit(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 || n > maxLimit {
+		return 0, errors.New("limit must be between 1 and " + strconv.Itoa(maxLimit))
+	}
+	return n, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, ErrorResponse{Error: err.Error()})
+}
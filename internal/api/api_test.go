@@ -0,0 +1,138 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fabyo/gordon-watcher/internal/logger"
+	"github.com/fabyo/gordon-watcher/internal/storage"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	store := storage.NewMemoryStorage()
+	t.Cleanup(func() { _ = store.Close() })
+
+	ctx := context.Background()
+	_ = store.MarkEnqueued(ctx, "h1", "/processing/nfe-1.xml")
+	_ = store.MarkEnqueued(ctx, "h2", "/processing/nfe-2.xml")
+	_ = store.MarkFailed(ctx, "h2", "queue_error")
+
+	log := logger.New(logger.Config{Level: "error", Format: "text", Output: "stdout"})
+	ts := httptest.NewServer(NewServer(":0", store, log).Handler())
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func get(t *testing.T, ts *httptest.Server, path string, v any) int {
+	t.Helper()
+
+	resp, err := http.Get(ts.URL + path)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", path, err)
+	}
+	defer resp.Body.Close()
+
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("GET %s: invalid JSON: %v", path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestServer_Get(t *testing.T) {
+	ts := newTestServer(t)
+
+	var record storage.Record
+	if code := get(t, ts, "/files/h2", &record); code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", code)
+	}
+	if record.Status != storage.StatusFailed || record.Filename != "nfe-2.xml" || record.Reason != "queue_error" {
+		t.Errorf("record = %+v", record)
+	}
+
+	if code := get(t, ts, "/files/missing", nil); code != http.StatusNotFound {
+		t.Errorf("missing hash status = %d, want 404", code)
+	}
+}
+
+func TestServer_ListAndCount(t *testing.T) {
+	ts := newTestServer(t)
+
+	var page storage.Page
+	if code := get(t, ts, "/files?status=enqueued", &page); code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", code)
+	}
+	if len(page.Records) != 1 || page.Records[0].Hash != "h1" {
+		t.Errorf("records = %+v", page.Records)
+	}
+
+	var records []storage.Record
+	get(t, ts, "/files/search?filename=nfe-", &records)
+	if len(records) != 2 {
+		t.Errorf("search returned %d records, want 2", len(records))
+	}
+
+	var count CountResponse
+	get(t, ts, "/files/count?status=failed", &count)
+	if count.Count != 1 {
+		t.Errorf("count = %d, want 1", count.Count)
+	}
+}
+
+func TestServer_BadRequests(t *testing.T) {
+	ts := newTestServer(t)
+
+	for _, path := range []string{
+		"/files?status=unknown",
+		"/files?since=yesterday",
+		"/files?limit=0",
+		"/files?cursor=bogus",
+		"/files/search",
+		"/files/count?status=unknown",
+	} {
+		var resp ErrorResponse
+		if code := get(t, ts, path, &resp); code != http.StatusBadRequest || resp.Error == "" {
+			t.Errorf("GET %s = %d %q, want 400 with error", path, code, resp.Error)
+		}
+	}
+}
+
+func TestClient_ReadsRunningWatcher(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient(newTestServer(t).URL)
+
+	record, err := client.Get(ctx, "h2")
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if record.Status != storage.StatusFailed || record.Reason != "queue_error" {
+		t.Errorf("record = %+v", record)
+	}
+	if _, err := client.Get(ctx, "missing"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Get(missing) error = %v, want ErrNotFound", err)
+	}
+	if _, err := client.List(ctx, storage.ListOptions{Cursor: "bogus"}); !errors.Is(err, storage.ErrInvalidCursor) {
+		t.Errorf("List(bogus cursor) error = %v, want ErrInvalidCursor", err)
+	}
+
+	// Memory state reaches another backend through the API
+	target := storage.NewMemoryStorage()
+	defer target.Close()
+	progress, err := storage.Migrate(ctx, client, target, storage.MigrateOptions{BatchSize: 1})
+	if err != nil {
+		t.Fatalf("Migrate() failed: %v", err)
+	}
+	if progress.Total() != 2 {
+		t.Errorf("migrated %d records, want 2", progress.Total())
+	}
+	if n, _ := target.Count(ctx, storage.StatusFailed); n != 1 {
+		t.Errorf("target has %d failed records, want 1", n)
+	}
+}
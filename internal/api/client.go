@@ -0,0 +1,133 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fabyo/gordon-watcher/internal/storage"
+)
+
+// Client reads file state from a running watcher's API. It implements
+// storage.Querier, so state held only in that process (memory storage) can
+// be migrated to another backend.
+type Client struct {
+	baseURL string
+	http    *http.Client
+}
+
+// NewClient creates a client for the API at baseURL (e.g.
+// http://localhost:8082)
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Get returns the current record for a hash
+func (c *Client) Get(ctx context.Context, hash string) (*storage.Record, error) {
+	var record storage.Record
+	if err := c.get(ctx, "/files/"+url.PathEscape(hash), nil, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// SearchByFilename returns records whose filename starts with prefix
+func (c *Client) SearchByFilename(ctx context.Context, prefix string, limit int) ([]storage.Record, error) {
+	q := url.Values{"filename": {prefix}}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+
+	var records []storage.Record
+	if err := c.get(ctx, "/files/search", q, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// List returns a page of records, newest first
+func (c *Client) List(ctx context.Context, opts storage.ListOptions) (*storage.Page, error) {
+	q := url.Values{}
+	if opts.Status != "" {
+		q.Set("status", opts.Status)
+	}
+	if !opts.Since.IsZero() {
+		q.Set("since", opts.Since.Format(time.RFC3339Nano))
+	}
+	if !opts.Until.IsZero() {
+		q.Set("until", opts.Until.Format(time.RFC3339Nano))
+	}
+	if opts.Limit > 0 {
+		q.Set("limit", strconv.Itoa(min(opts.Limit, maxLimit)))
+	}
+	if opts.Cursor != "" {
+		q.Set("cursor", opts.Cursor)
+	}
+
+	var page storage.Page
+	if err := c.get(ctx, "/files", q, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// Count returns the number of records with a status (empty = all)
+func (c *Client) Count(ctx context.Context, status string) (int64, error) {
+	q := url.Values{}
+	if status != "" {
+		q.Set("status", status)
+	}
+
+	var resp CountResponse
+	if err := c.get(ctx, "/files/count", q, &resp); err != nil {
+		return 0, err
+	}
+	return resp.Count, nil
+}
+
+// get decodes the JSON response of a GET request into v, mapping error
+// responses back to the storage errors the server translated
+func (c *Client) get(ctx context.Context, path string, q url.Values, v any) error {
+	target := c.baseURL + path
+	if len(q) > 0 {
+		target += "?" + q.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr ErrorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&apiErr)
+
+		switch {
+		case resp.StatusCode == http.StatusNotFound && apiErr.Error == storage.ErrNotFound.Error():
+			return storage.ErrNotFound
+		case resp.StatusCode == http.StatusBadRequest && apiErr.Error == storage.ErrInvalidCursor.Error():
+			return storage.ErrInvalidCursor
+		default:
+			return fmt.Errorf("API returned %s: %s", resp.Status, apiErr.Error)
+		}
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("invalid API response: %w", err)
+	}
+	return nil
+}
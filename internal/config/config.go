@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -14,8 +15,10 @@ type Config struct {
 	Watcher   WatcherConfig   `mapstructure:"watcher"`
 	Queue     QueueConfig     `mapstructure:"queue"`
 	Redis     RedisConfig     `mapstructure:"redis"`
+	Storage   StorageConfig   `mapstructure:"storage"`
 	Metrics   MetricsConfig   `mapstructure:"metrics"`
 	Health    HealthConfig    `mapstructure:"health"`
+	API       APIConfig       `mapstructure:"api"`
 	Telemetry TelemetryConfig `mapstructure:"telemetry"`
 	Logger    LoggerConfig    `mapstructure:"logger"`
 	Cleanup   CleanupConfig   `mapstructure:"cleanup"`
@@ -36,13 +39,65 @@ type WatcherConfig struct {
 	MinFileSize       int64                `mapstructure:"min_file_size"`
 	MaxFileSize       int64                `mapstructure:"max_file_size"`
 	StableAttempts    int                  `mapstructure:"stable_attempts"`
-	StableDelay       int64                `mapstructure:"stable_delay"`
+	StableDelay       time.Duration        `mapstructure:"stable_delay"`
+	StabilityMode     string               `mapstructure:"stability_mode"` // poll (default), close_write, marker
+	Marker            MarkerConfig         `mapstructure:"marker"`
 	CleanupInterval   int64                `mapstructure:"cleanup_interval"`
 	MaxWorkers        int                  `mapstructure:"max_workers"`
 	MaxFilesPerSecond int                  `mapstructure:"max_files_per_second"`
 	WorkerQueueSize   int                  `mapstructure:"worker_queue_size"`
 	WorkingDir        string               `mapstructure:"working_dir"`
 	SubDirectories    SubDirectoriesConfig `mapstructure:"sub_directories"`
+	PathOptions       []PathOptionsConfig  `mapstructure:"path_options"`
+	Sources           []SourceConfig       `mapstructure:"sources"` // replaces paths when set
+}
+
+// SourceConfig gives one watch path a pipeline of its own. Fields left
+// empty inherit the watcher-wide settings.
+type SourceConfig struct {
+	Path              string             `mapstructure:"path"`
+	FilePatterns      []string           `mapstructure:"file_patterns"`
+	ExcludePatterns   []string           `mapstructure:"exclude_patterns"`
+	MinFileSize       int64              `mapstructure:"min_file_size"`
+	MaxFileSize       int64              `mapstructure:"max_file_size"`
+	StabilityMode     string             `mapstructure:"stability_mode"`
+	StableAttempts    int                `mapstructure:"stable_attempts"`
+	StableDelay       time.Duration      `mapstructure:"stable_delay"`
+	Marker            MarkerConfig       `mapstructure:"marker"`
+	Mode              string             `mapstructure:"mode"`
+	PollInterval      time.Duration      `mapstructure:"poll_interval"`
+	MaxFilesPerSecond int                `mapstructure:"max_files_per_second"` // own limit instead of the shared one
+	Target            SourceTargetConfig `mapstructure:"target"`
+}
+
+// SourceTargetConfig sends the files of a source to their own destination
+// on the configured queue type
+type SourceTargetConfig struct {
+	Exchange   string   `mapstructure:"exchange"`    // rabbitmq (default: queue.rabbitmq.exchange)
+	RoutingKey string   `mapstructure:"routing_key"` // rabbitmq
+	Queue      string   `mapstructure:"queue"`       // rabbitmq, declared and bound to routing_key
+	Topic      string   `mapstructure:"topic"`       // kafka
+	Stream     string   `mapstructure:"stream"`      // redis
+	Subject    string   `mapstructure:"subject"`     // nats, may contain {kind}
+	URLs       []string `mapstructure:"urls"`        // webhook
+	Format     string   `mapstructure:"format"`      // wire format of the target (default: queue.encoding)
+}
+
+// PathOptionsConfig sets how one watch path is monitored
+type PathOptionsConfig struct {
+	Path         string        `mapstructure:"path"`
+	Mode         string        `mapstructure:"mode"`          // fsnotify (default), poll, both
+	PollInterval time.Duration `mapstructure:"poll_interval"` // default: 10s
+}
+
+// MarkerConfig holds the done-marker completion settings.
+// Templates accept {name} (nfe.xml) and {stem} (nfe).
+type MarkerConfig struct {
+	Templates    []string      `mapstructure:"templates"`     // default: ["{name}.done"]
+	Timeout      time.Duration `mapstructure:"timeout"`       // default: 30m, then the file goes to failed/
+	PollInterval time.Duration `mapstructure:"poll_interval"` // default: 1s
+	Action       string        `mapstructure:"action"`        // delete (default), archive
+	ArchiveDir   string        `mapstructure:"archive_dir"`   // default: <working_dir>/markers
 }
 
 // SubDirectoriesConfig holds subdirectory names
@@ -56,26 +111,187 @@ type SubDirectoriesConfig struct {
 
 // QueueConfig holds queue settings
 type QueueConfig struct {
-	Enabled  bool           `mapstructure:"enabled"`
-	Type     string         `mapstructure:"type"`
-	RabbitMQ RabbitMQConfig `mapstructure:"rabbitmq"`
+	Enabled  bool              `mapstructure:"enabled"`
+	Type     string            `mapstructure:"type"`
+	RabbitMQ RabbitMQConfig    `mapstructure:"rabbitmq"`
+	Kafka    KafkaConfig       `mapstructure:"kafka"`
+	NATS     NATSConfig        `mapstructure:"nats"`
+	Redis    RedisStreamConfig `mapstructure:"redis"`
+	Webhook  WebhookConfig     `mapstructure:"webhook"`
+	Encoding EncodingConfig    `mapstructure:"encoding"`
+}
+
+// EncodingConfig selects the wire format shared by every queue backend
+type EncodingConfig struct {
+	Format             string                    `mapstructure:"format"` // json, cloudevents, protobuf
+	DestinationFormats []DestinationFormatConfig `mapstructure:"destination_formats"`
+	CloudEvents        CloudEventsConfig         `mapstructure:"cloudevents"`
+}
+
+// DestinationFormatConfig sets the wire format of one destination
+type DestinationFormatConfig struct {
+	Destination string `mapstructure:"destination"` // topic, stream, subject, url or routing key
+	Format      string `mapstructure:"format"`
+}
+
+// CloudEventsConfig holds CloudEvents 1.0 envelope settings.
+// Source, type and subject accept {id}, {hash}, {kind}, {filename}, {path} and {source}.
+type CloudEventsConfig struct {
+	Mode    string `mapstructure:"mode"` // structured, binary
+	Source  string `mapstructure:"source"`
+	Type    string `mapstructure:"type"`
+	Subject string `mapstructure:"subject"`
 }
 
 // RabbitMQConfig holds RabbitMQ settings
 type RabbitMQConfig struct {
-	URL        string `mapstructure:"url"`
+	URL            string        `mapstructure:"url"`
+	Exchange       string        `mapstructure:"exchange"`
+	QueueName      string        `mapstructure:"queue_name"`
+	RoutingKey     string        `mapstructure:"routing_key"`
+	Durable        bool          `mapstructure:"durable"`
+	ResultsQueue   string        `mapstructure:"results_queue"`
+	ConfirmTimeout time.Duration `mapstructure:"confirm_timeout"`
+
+	ReconnectInitialDelay time.Duration `mapstructure:"reconnect_initial_delay"`
+	ReconnectMaxDelay     time.Duration `mapstructure:"reconnect_max_delay"`
+
+	// Routing rules, evaluated in order (exchange/routing_key/queue_name are the fallback)
+	Routes []RouteConfig `mapstructure:"routes"`
+
+	// Dead-letter queue
+	DLQEnabled  bool   `mapstructure:"dlq_enabled"`
+	DLQExchange string `mapstructure:"dlq_exchange"`
+	DLQQueue    string `mapstructure:"dlq_queue"`
+
+	// Queue limits (0 = unlimited)
+	MessageTTL time.Duration `mapstructure:"message_ttl"`
+	MaxLength  int64         `mapstructure:"max_length"`
+	Overflow   string        `mapstructure:"overflow"` // drop-head, reject-publish, reject-publish-dlx
+}
+
+// RouteConfig maps files to a routing key or exchange.
+// Empty match fields match any file.
+type RouteConfig struct {
+	Kind       string `mapstructure:"kind"`
+	Pattern    string `mapstructure:"pattern"`
+	Source     string `mapstructure:"source"`
 	Exchange   string `mapstructure:"exchange"`
-	QueueName  string `mapstructure:"queue_name"`
 	RoutingKey string `mapstructure:"routing_key"`
-	Durable    bool   `mapstructure:"durable"`
+	Queue      string `mapstructure:"queue"`
+	Format     string `mapstructure:"format"` // default: queue.encoding
+}
+
+// KafkaConfig holds Kafka settings
+type KafkaConfig struct {
+	Brokers    []string          `mapstructure:"brokers"`
+	ClientID   string            `mapstructure:"client_id"`
+	Version    string            `mapstructure:"version"`
+	Topic      string            `mapstructure:"topic"`
+	Topics     map[string]string `mapstructure:"topics"` // kind -> topic
+	Idempotent bool              `mapstructure:"idempotent"`
+	MaxRetries int               `mapstructure:"max_retries"`
+}
+
+// NATSConfig holds NATS JetStream settings
+type NATSConfig struct {
+	URL              string        `mapstructure:"url"`
+	Stream           string        `mapstructure:"stream"`
+	SubjectTemplate  string        `mapstructure:"subject_template"`
+	AutoCreateStream bool          `mapstructure:"auto_create_stream"`
+	DuplicateWindow  time.Duration `mapstructure:"duplicate_window"`
+	AckTimeout       time.Duration `mapstructure:"ack_timeout"`
+}
+
+// RedisStreamConfig holds Redis Streams queue settings.
+// The connection comes from the top-level redis section.
+type RedisStreamConfig struct {
+	Stream          string            `mapstructure:"stream"`
+	Streams         map[string]string `mapstructure:"streams"` // kind -> stream
+	MaxLen          int64             `mapstructure:"max_len"`
+	ApproximateTrim bool              `mapstructure:"approximate_trim"`
+	Group           string            `mapstructure:"group"`
+}
+
+// WebhookConfig holds HTTP webhook settings
+type WebhookConfig struct {
+	URLs     []string            `mapstructure:"urls"`
+	KindURLs map[string][]string `mapstructure:"kind_urls"` // kind -> urls
+	Secret   string              `mapstructure:"secret"`
+	Timeout  time.Duration       `mapstructure:"timeout"`
+	Headers  map[string]string   `mapstructure:"headers"`
 }
 
 // RedisConfig holds Redis settings
 type RedisConfig struct {
 	Enabled  bool   `mapstructure:"enabled"`
+	Mode     string `mapstructure:"mode"` // standalone, sentinel, cluster
 	Addr     string `mapstructure:"addr"`
+	Username string `mapstructure:"username"` // Redis 6 ACL user
 	Password string `mapstructure:"password"`
 	DB       int    `mapstructure:"db"`
+
+	// Sentinel and cluster
+	Addrs            []string `mapstructure:"addrs"` // sentinels or cluster seed nodes
+	MasterName       string   `mapstructure:"master_name"`
+	SentinelUsername string   `mapstructure:"sentinel_username"`
+	SentinelPassword string   `mapstructure:"sentinel_password"`
+
+	TLS       RedisTLSConfig `mapstructure:"tls"`
+	KeyPrefix string         `mapstructure:"key_prefix"` // namespace for deployments sharing one Redis
+}
+
+// RedisTLSConfig holds Redis TLS settings
+type RedisTLSConfig struct {
+	Enabled            bool   `mapstructure:"enabled"`
+	CAFile             string `mapstructure:"ca_file"`
+	CertFile           string `mapstructure:"cert_file"`
+	KeyFile            string `mapstructure:"key_file"`
+	ServerName         string `mapstructure:"server_name"`
+	InsecureSkipVerify bool   `mapstructure:"insecure_skip_verify"`
+}
+
+// StorageConfig holds dedup state storage settings
+type StorageConfig struct {
+	Type     string         `mapstructure:"type"` // memory, redis, bolt, postgres, sqlite (default: redis if redis.enabled, else memory)
+	TTL      TTLConfig      `mapstructure:"ttl"`
+	Memory   MemoryConfig   `mapstructure:"memory"`
+	Bolt     BoltConfig     `mapstructure:"bolt"`
+	Postgres PostgresConfig `mapstructure:"postgres"`
+	SQLite   SQLiteConfig   `mapstructure:"sqlite"`
+}
+
+// TTLConfig holds how long each state is kept, for every storage type
+type TTLConfig struct {
+	Enqueued  time.Duration `mapstructure:"enqueued"`
+	Processed time.Duration `mapstructure:"processed"` // dedup window
+	Failed    time.Duration `mapstructure:"failed"`
+	Lock      time.Duration `mapstructure:"lock"` // lock lease
+}
+
+// MemoryConfig holds in-memory storage settings
+type MemoryConfig struct {
+	JanitorInterval time.Duration `mapstructure:"janitor_interval"`
+}
+
+// BoltConfig holds embedded bbolt storage settings
+type BoltConfig struct {
+	Path            string        `mapstructure:"path"` // default: <working_dir>/gordon-watcher.db
+	CompactInterval time.Duration `mapstructure:"compact_interval"`
+}
+
+// PostgresConfig holds Postgres storage settings
+type PostgresConfig struct {
+	DSN               string        `mapstructure:"dsn"`
+	MaxConns          int32         `mapstructure:"max_conns"` // each held lock pins a connection
+	RetentionInterval time.Duration `mapstructure:"retention_interval"`
+}
+
+// SQLiteConfig holds SQLite storage settings
+type SQLiteConfig struct {
+	Path              string        `mapstructure:"path"`      // default: <working_dir>/gordon-watcher.sqlite
+	Retention         time.Duration `mapstructure:"retention"` // history events (default: storage.ttl.processed)
+	RetentionInterval time.Duration `mapstructure:"retention_interval"`
 }
 
 // MetricsConfig holds metrics settings
@@ -88,6 +304,12 @@ type HealthConfig struct {
 	Addr string `mapstructure:"addr"`
 }
 
+// APIConfig holds the read-only file state API settings
+type APIConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Addr    string `mapstructure:"addr"`
+}
+
 // TelemetryConfig holds telemetry settings
 type TelemetryConfig struct {
 	Enabled     bool   `mapstructure:"enabled"`
@@ -141,16 +363,67 @@ func Load() (*Config, error) {
 	_ = viper.BindEnv("queue.rabbitmq.queue_name")
 	_ = viper.BindEnv("queue.rabbitmq.routing_key")
 	_ = viper.BindEnv("queue.rabbitmq.durable")
+	_ = viper.BindEnv("queue.rabbitmq.results_queue")
+	_ = viper.BindEnv("queue.rabbitmq.confirm_timeout")
+	_ = viper.BindEnv("queue.rabbitmq.dlq_enabled")
+	_ = viper.BindEnv("queue.rabbitmq.dlq_exchange")
+	_ = viper.BindEnv("queue.rabbitmq.dlq_queue")
+	_ = viper.BindEnv("queue.rabbitmq.message_ttl")
+	_ = viper.BindEnv("queue.rabbitmq.max_length")
+	_ = viper.BindEnv("queue.rabbitmq.overflow")
+
+	_ = viper.BindEnv("queue.kafka.brokers")
+	_ = viper.BindEnv("queue.kafka.client_id")
+	_ = viper.BindEnv("queue.kafka.version")
+	_ = viper.BindEnv("queue.kafka.topic")
+	_ = viper.BindEnv("queue.kafka.idempotent")
+
+	_ = viper.BindEnv("queue.nats.url")
+	_ = viper.BindEnv("queue.nats.stream")
+	_ = viper.BindEnv("queue.nats.subject_template")
+	_ = viper.BindEnv("queue.nats.auto_create_stream")
+
+	_ = viper.BindEnv("queue.redis.stream")
+	_ = viper.BindEnv("queue.redis.max_len")
+	_ = viper.BindEnv("queue.redis.group")
+
+	_ = viper.BindEnv("queue.webhook.urls")
+	_ = viper.BindEnv("queue.webhook.secret")
+	_ = viper.BindEnv("queue.webhook.timeout")
+	_ = viper.BindEnv("queue.encoding.format")
+	_ = viper.BindEnv("queue.encoding.cloudevents.mode")
+	_ = viper.BindEnv("queue.encoding.cloudevents.source")
+	_ = viper.BindEnv("queue.encoding.cloudevents.type")
 
 	_ = viper.BindEnv("redis.enabled")
+	_ = viper.BindEnv("redis.mode")
 	_ = viper.BindEnv("redis.addr")
+	_ = viper.BindEnv("redis.addrs")
+	_ = viper.BindEnv("redis.master_name")
+	_ = viper.BindEnv("redis.username")
 	_ = viper.BindEnv("redis.password")
+	_ = viper.BindEnv("redis.sentinel_password")
 	_ = viper.BindEnv("redis.db")
+	_ = viper.BindEnv("redis.tls.enabled")
+	_ = viper.BindEnv("redis.tls.ca_file")
+	_ = viper.BindEnv("redis.key_prefix")
+	_ = viper.BindEnv("storage.type")
+	_ = viper.BindEnv("storage.ttl.enqueued")
+	_ = viper.BindEnv("storage.ttl.processed")
+	_ = viper.BindEnv("storage.ttl.failed")
+	_ = viper.BindEnv("storage.ttl.lock")
+	_ = viper.BindEnv("storage.bolt.path")
+	_ = viper.BindEnv("storage.postgres.dsn")
+	_ = viper.BindEnv("storage.sqlite.path")
 
 	_ = viper.BindEnv("watcher.paths")
 	_ = viper.BindEnv("watcher.working_dir")
 	_ = viper.BindEnv("watcher.max_workers")
 	_ = viper.BindEnv("watcher.max_files_per_second")
+	_ = viper.BindEnv("watcher.stability_mode")
+
+	_ = viper.BindEnv("api.enabled")
+	_ = viper.BindEnv("api.addr")
 
 	_ = viper.BindEnv("telemetry.enabled")
 	_ = viper.BindEnv("telemetry.service_name")
@@ -181,13 +454,15 @@ func Load() (*Config, error) {
 	fmt.Printf("DEBUG - Viper Get queue.enabled: %v\n", viper.GetBool("queue.enabled"))
 	fmt.Printf("DEBUG - ENV GORDON_WATCHER_QUEUE_ENABLED: %v\n", os.Getenv("GORDON_WATCHER_QUEUE_ENABLED"))
 
-	// Set defaults
-	SetDefaults(&cfg)
-
-	// Override with environment variables for critical settings
+	// Override with environment variables for critical settings. The
+	// working dir comes first: defaults such as storage paths derive from it.
 	if envWorkingDir := os.Getenv("GORDON_WATCHER_WORKING_DIR"); envWorkingDir != "" {
 		cfg.Watcher.WorkingDir = envWorkingDir
 	}
+
+	// Set defaults
+	SetDefaults(&cfg)
+
 	if envPaths := os.Getenv("GORDON_WATCHER_PATHS"); envPaths != "" {
 		cfg.Watcher.Paths = strings.Split(envPaths, ",")
 	}
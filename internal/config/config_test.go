@@ -0,0 +1,64 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoad_WorkingDirEnvDrivesDerivedPaths(t *testing.T) {
+	workingDir := t.TempDir()
+	t.Setenv("HOME", workingDir)
+	t.Setenv("GORDON_WATCHER_WORKING_DIR", workingDir)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if want := filepath.Join(workingDir, "gordon-watcher.db"); cfg.Storage.Bolt.Path != want {
+		t.Errorf("Bolt.Path = %q, want %q", cfg.Storage.Bolt.Path, want)
+	}
+	if want := filepath.Join(workingDir, "gordon-watcher.sqlite"); cfg.Storage.SQLite.Path != want {
+		t.Errorf("SQLite.Path = %q, want %q", cfg.Storage.SQLite.Path, want)
+	}
+	if want := filepath.Join(workingDir, "markers"); cfg.Watcher.Marker.ArchiveDir != want {
+		t.Errorf("Marker.ArchiveDir = %q, want %q", cfg.Watcher.Marker.ArchiveDir, want)
+	}
+}
+
+func TestLoad_StableDelaySameEverywhere(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	dir := filepath.Join(home, ".gordon", "watcher")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	yaml := `watcher:
+  stable_delay: 5s
+  sources:
+    - path: /data/a
+      stable_delay: 5s
+    - path: /data/b
+      stable_delay: 5000000000
+`
+	if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(yaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if cfg.Watcher.StableDelay != 5*time.Second {
+		t.Errorf("StableDelay = %v, want 5s", cfg.Watcher.StableDelay)
+	}
+	for _, src := range cfg.Watcher.Sources {
+		if src.StableDelay != 5*time.Second {
+			t.Errorf("source %s StableDelay = %v, want 5s", src.Path, src.StableDelay)
+		}
+	}
+}
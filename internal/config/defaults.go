@@ -1,6 +1,7 @@
 package config
 
 import (
+	"path/filepath"
 	"time"
 )
 
@@ -18,6 +19,13 @@ func SetDefaults(cfg *Config) {
 	}
 
 	// Watcher defaults
+	if len(cfg.Watcher.Sources) > 0 {
+		// Sources define the watch paths
+		cfg.Watcher.Paths = make([]string, 0, len(cfg.Watcher.Sources))
+		for _, src := range cfg.Watcher.Sources {
+			cfg.Watcher.Paths = append(cfg.Watcher.Paths, src.Path)
+		}
+	}
 	if len(cfg.Watcher.Paths) == 0 {
 		cfg.Watcher.Paths = []string{"/opt/gordon-watcher/data/incoming"}
 	}
@@ -37,7 +45,22 @@ func SetDefaults(cfg *Config) {
 		cfg.Watcher.StableAttempts = 5
 	}
 	if cfg.Watcher.StableDelay == 0 {
-		cfg.Watcher.StableDelay = int64(1 * time.Second)
+		cfg.Watcher.StableDelay = 1 * time.Second
+	}
+	if cfg.Watcher.StabilityMode == "" {
+		cfg.Watcher.StabilityMode = "poll"
+	}
+	if len(cfg.Watcher.Marker.Templates) == 0 {
+		cfg.Watcher.Marker.Templates = []string{"{name}.done"}
+	}
+	if cfg.Watcher.Marker.Timeout == 0 {
+		cfg.Watcher.Marker.Timeout = 30 * time.Minute
+	}
+	if cfg.Watcher.Marker.PollInterval == 0 {
+		cfg.Watcher.Marker.PollInterval = time.Second
+	}
+	if cfg.Watcher.Marker.Action == "" {
+		cfg.Watcher.Marker.Action = "delete"
 	}
 	if cfg.Watcher.CleanupInterval == 0 {
 		cfg.Watcher.CleanupInterval = int64(5 * time.Minute)
@@ -54,6 +77,19 @@ func SetDefaults(cfg *Config) {
 	if cfg.Watcher.WorkingDir == "" {
 		cfg.Watcher.WorkingDir = "/opt/gordon-watcher/data"
 	}
+	if cfg.Watcher.Marker.ArchiveDir == "" {
+		cfg.Watcher.Marker.ArchiveDir = filepath.Join(cfg.Watcher.WorkingDir, "markers")
+	}
+
+	for i := range cfg.Watcher.PathOptions {
+		opts := &cfg.Watcher.PathOptions[i]
+		if opts.Mode == "" {
+			opts.Mode = "fsnotify"
+		}
+		if opts.PollInterval == 0 {
+			opts.PollInterval = 10 * time.Second
+		}
+	}
 
 	// SubDirectories defaults
 	if cfg.Watcher.SubDirectories.Processing == "" {
@@ -85,11 +121,113 @@ func SetDefaults(cfg *Config) {
 	if cfg.Queue.RabbitMQ.RoutingKey == "" {
 		cfg.Queue.RabbitMQ.RoutingKey = "gordon.file"
 	}
+	if cfg.Queue.RabbitMQ.ConfirmTimeout == 0 {
+		cfg.Queue.RabbitMQ.ConfirmTimeout = 5 * time.Second
+	}
+	if cfg.Queue.RabbitMQ.DLQEnabled {
+		if cfg.Queue.RabbitMQ.DLQExchange == "" {
+			cfg.Queue.RabbitMQ.DLQExchange = cfg.Queue.RabbitMQ.Exchange + ".dlx"
+		}
+		if cfg.Queue.RabbitMQ.DLQQueue == "" {
+			cfg.Queue.RabbitMQ.DLQQueue = cfg.Queue.RabbitMQ.QueueName + ".dlq"
+		}
+	}
+	if cfg.Queue.Kafka.ClientID == "" {
+		cfg.Queue.Kafka.ClientID = "gordon-watcher"
+	}
+	if cfg.Queue.Kafka.Topic == "" {
+		cfg.Queue.Kafka.Topic = "gordon.files"
+	}
+	if cfg.Queue.NATS.Stream == "" {
+		cfg.Queue.NATS.Stream = "GORDON_FILES"
+	}
+	if cfg.Queue.NATS.SubjectTemplate == "" {
+		cfg.Queue.NATS.SubjectTemplate = "gordon.files.{kind}"
+	}
+	if cfg.Queue.NATS.DuplicateWindow == 0 {
+		cfg.Queue.NATS.DuplicateWindow = 2 * time.Minute
+	}
+	if cfg.Queue.NATS.AckTimeout == 0 {
+		cfg.Queue.NATS.AckTimeout = 5 * time.Second
+	}
+	if cfg.Queue.Redis.Stream == "" {
+		cfg.Queue.Redis.Stream = "gordon:files"
+	}
+	if cfg.Queue.Webhook.Timeout == 0 {
+		cfg.Queue.Webhook.Timeout = 10 * time.Second
+	}
+	if cfg.Queue.Encoding.Format == "" {
+		cfg.Queue.Encoding.Format = "json"
+	}
+	if cfg.Queue.Encoding.CloudEvents.Mode == "" {
+		cfg.Queue.Encoding.CloudEvents.Mode = "structured"
+	}
+	if cfg.Queue.Encoding.CloudEvents.Source == "" {
+		cfg.Queue.Encoding.CloudEvents.Source = "gordon-watcher"
+	}
+	if cfg.Queue.Encoding.CloudEvents.Type == "" {
+		cfg.Queue.Encoding.CloudEvents.Type = "com.gordon.file.detected"
+	}
+	if cfg.Queue.Encoding.CloudEvents.Subject == "" {
+		cfg.Queue.Encoding.CloudEvents.Subject = "{filename}"
+	}
 
 	// Redis defaults
+	if cfg.Redis.Mode == "" {
+		cfg.Redis.Mode = "standalone"
+	}
 	if cfg.Redis.Addr == "" {
 		cfg.Redis.Addr = "localhost:6379"
 	}
+	if cfg.Redis.KeyPrefix == "" {
+		cfg.Redis.KeyPrefix = "gordon:watcher:"
+	}
+
+	// Storage defaults
+	if cfg.Storage.Type == "" {
+		if cfg.Redis.Enabled {
+			cfg.Storage.Type = "redis"
+		} else {
+			cfg.Storage.Type = "memory"
+		}
+	}
+	if cfg.Storage.TTL.Enqueued == 0 {
+		cfg.Storage.TTL.Enqueued = 24 * time.Hour
+	}
+	if cfg.Storage.TTL.Processed == 0 {
+		cfg.Storage.TTL.Processed = 7 * 24 * time.Hour
+	}
+	if cfg.Storage.TTL.Failed == 0 {
+		cfg.Storage.TTL.Failed = 7 * 24 * time.Hour
+	}
+	if cfg.Storage.TTL.Lock == 0 {
+		cfg.Storage.TTL.Lock = 30 * time.Second
+	}
+	if cfg.Storage.Memory.JanitorInterval == 0 {
+		cfg.Storage.Memory.JanitorInterval = time.Minute
+	}
+	if cfg.Storage.Bolt.Path == "" {
+		cfg.Storage.Bolt.Path = filepath.Join(cfg.Watcher.WorkingDir, "gordon-watcher.db")
+	}
+	if cfg.Storage.Bolt.CompactInterval == 0 {
+		cfg.Storage.Bolt.CompactInterval = 10 * time.Minute
+	}
+	if cfg.Storage.Postgres.MaxConns == 0 {
+		// One connection per locked file plus headroom for state queries
+		cfg.Storage.Postgres.MaxConns = int32(cfg.Watcher.MaxWorkers) + 4
+	}
+	if cfg.Storage.Postgres.RetentionInterval == 0 {
+		cfg.Storage.Postgres.RetentionInterval = 10 * time.Minute
+	}
+	if cfg.Storage.SQLite.Path == "" {
+		cfg.Storage.SQLite.Path = filepath.Join(cfg.Watcher.WorkingDir, "gordon-watcher.sqlite")
+	}
+	if cfg.Storage.SQLite.Retention == 0 {
+		cfg.Storage.SQLite.Retention = cfg.Storage.TTL.Processed
+	}
+	if cfg.Storage.SQLite.RetentionInterval == 0 {
+		cfg.Storage.SQLite.RetentionInterval = 10 * time.Minute
+	}
 
 	// Metrics defaults
 	if cfg.Metrics.Addr == "" {
@@ -101,6 +239,11 @@ func SetDefaults(cfg *Config) {
 		cfg.Health.Addr = ":8081"
 	}
 
+	// API defaults
+	if cfg.API.Addr == "" {
+		cfg.API.Addr = ":8082"
+	}
+
 	// Telemetry defaults
 	if cfg.Telemetry.ServiceName == "" {
 		cfg.Telemetry.ServiceName = "gordon-watcher"
@@ -0,0 +1,18 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSetDefaults_MarkerArchiveDirUnderWorkingDir(t *testing.T) {
+	var cfg Config
+	SetDefaults(&cfg)
+
+	if !filepath.IsAbs(cfg.Watcher.Marker.ArchiveDir) {
+		t.Errorf("ArchiveDir = %q, want an absolute path", cfg.Watcher.Marker.ArchiveDir)
+	}
+	if want := filepath.Join(cfg.Watcher.WorkingDir, "markers"); cfg.Watcher.Marker.ArchiveDir != want {
+		t.Errorf("ArchiveDir = %q, want %q", cfg.Watcher.Marker.ArchiveDir, want)
+	}
+}
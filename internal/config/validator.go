@@ -2,6 +2,9 @@ package config
 
 import (
 	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
 // Validate validates the configuration
@@ -28,13 +31,32 @@ func Validate(cfg *Config) error {
 		return fmt.Errorf("watcher.working_dir is required")
 	}
 
+	switch cfg.Watcher.StabilityMode {
+	case "poll", "close_write":
+	case "marker":
+		if err := validateMarker("watcher.marker", &cfg.Watcher.Marker); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("watcher.stability_mode must be poll, close_write or marker")
+	}
+
+	if err := validatePathOptions(&cfg.Watcher); err != nil {
+		return err
+	}
+
+	if err := validateSources(cfg); err != nil {
+		return err
+	}
+
 	// Queue validation
 	if cfg.Queue.Enabled {
 		if cfg.Queue.Type == "" {
 			return fmt.Errorf("queue.type is required when queue is enabled")
 		}
 
-		if cfg.Queue.Type == "rabbitmq" {
+		switch cfg.Queue.Type {
+		case "rabbitmq":
 			if cfg.Queue.RabbitMQ.URL == "" {
 				return fmt.Errorf("queue.rabbitmq.url is required")
 			}
@@ -44,15 +66,507 @@ func Validate(cfg *Config) error {
 			if cfg.Queue.RabbitMQ.QueueName == "" {
 				return fmt.Errorf("queue.rabbitmq.queue_name is required")
 			}
+			if err := validateRabbitMQLimits(&cfg.Queue.RabbitMQ); err != nil {
+				return err
+			}
+			if err := validateRoutes(cfg.Queue.RabbitMQ.Routes); err != nil {
+				return err
+			}
+		case "kafka":
+			if len(cfg.Queue.Kafka.Brokers) == 0 {
+				return fmt.Errorf("queue.kafka.brokers must have at least one broker")
+			}
+			if cfg.Queue.Kafka.Topic == "" {
+				return fmt.Errorf("queue.kafka.topic is required")
+			}
+		case "nats":
+			if cfg.Queue.NATS.URL == "" {
+				return fmt.Errorf("queue.nats.url is required")
+			}
+			if cfg.Queue.NATS.Stream == "" {
+				return fmt.Errorf("queue.nats.stream is required")
+			}
+			if strings.ContainsAny(cfg.Queue.NATS.SubjectTemplate, " *>") {
+				return fmt.Errorf("queue.nats.subject_template must not contain spaces or wildcards")
+			}
+		case "redis":
+			if err := validateRedis(&cfg.Redis); err != nil {
+				return err
+			}
+			if cfg.Queue.Redis.Stream == "" {
+				return fmt.Errorf("queue.redis.stream is required")
+			}
+			if cfg.Queue.Redis.MaxLen < 0 {
+				return fmt.Errorf("queue.redis.max_len must not be negative")
+			}
+		case "webhook":
+			if len(cfg.Queue.Webhook.URLs) == 0 && len(cfg.Queue.Webhook.KindURLs) == 0 && !hasSourceURLs(&cfg.Watcher) {
+				return fmt.Errorf("queue.webhook.urls, queue.webhook.kind_urls or a source target is required")
+			}
+		default:
+			return fmt.Errorf("unsupported queue.type: %s", cfg.Queue.Type)
+		}
+
+		if err := validateEncoding(cfg); err != nil {
+			return err
 		}
 	}
 
 	// Redis validation
 	if cfg.Redis.Enabled {
-		if cfg.Redis.Addr == "" {
-			return fmt.Errorf("redis.addr is required when redis is enabled")
+		if err := validateRedis(&cfg.Redis); err != nil {
+			return err
 		}
 	}
 
+	// Storage validation
+	if err := validateTTL(&cfg.Storage.TTL); err != nil {
+		return err
+	}
+
+	switch cfg.Storage.Type {
+	case "memory":
+	case "redis":
+		if err := validateRedis(&cfg.Redis); err != nil {
+			return err
+		}
+	case "bolt":
+		if cfg.Storage.Bolt.Path == "" {
+			return fmt.Errorf("storage.bolt.path is required")
+		}
+	case "sqlite":
+		if cfg.Storage.SQLite.Path == "" {
+			return fmt.Errorf("storage.sqlite.path is required")
+		}
+		if cfg.Storage.SQLite.Retention < 0 {
+			return fmt.Errorf("storage.sqlite.retention must not be negative")
+		}
+	case "postgres":
+		if cfg.Storage.Postgres.DSN == "" {
+			return fmt.Errorf("storage.postgres.dsn is required")
+		}
+		if int(cfg.Storage.Postgres.MaxConns) <= cfg.Watcher.MaxWorkers {
+			return fmt.Errorf("storage.postgres.max_conns must be greater than watcher.max_workers")
+		}
+	default:
+		return fmt.Errorf("unsupported storage.type: %s", cfg.Storage.Type)
+	}
+
+	// API validation
+	if cfg.API.Enabled && cfg.API.Addr == "" {
+		return fmt.Errorf("api.addr is required when api is enabled")
+	}
+
 	return nil
 }
+
+// validateTTL validates the storage TTLs
+func validateTTL(cfg *TTLConfig) error {
+	ttls := []struct {
+		name string
+		ttl  time.Duration
+	}{
+		{"enqueued", cfg.Enqueued},
+		{"processed", cfg.Processed},
+		{"failed", cfg.Failed},
+		{"lock", cfg.Lock},
+	}
+	for _, t := range ttls {
+		if t.ttl < 0 {
+			return fmt.Errorf("storage.ttl.%s must not be negative", t.name)
+		}
+	}
+
+	// The lease is renewed every third of the TTL
+	if cfg.Lock > 0 && cfg.Lock < 3*time.Second {
+		return fmt.Errorf("storage.ttl.lock must be at least 3s")
+	}
+
+	return nil
+}
+
+// validateRedis validates the connection settings for the Redis mode
+func validateRedis(cfg *RedisConfig) error {
+	switch cfg.Mode {
+	case "standalone":
+		if cfg.Addr == "" {
+			return fmt.Errorf("redis.addr is required in standalone mode")
+		}
+	case "sentinel":
+		if cfg.MasterName == "" {
+			return fmt.Errorf("redis.master_name is required in sentinel mode")
+		}
+		if len(cfg.Addrs) == 0 {
+			return fmt.Errorf("redis.addrs is required in sentinel mode")
+		}
+	case "cluster":
+		if len(cfg.Addrs) == 0 {
+			return fmt.Errorf("redis.addrs is required in cluster mode")
+		}
+		if cfg.DB != 0 {
+			return fmt.Errorf("redis.db is not supported in cluster mode")
+		}
+	default:
+		return fmt.Errorf("unsupported redis.mode: %s", cfg.Mode)
+	}
+
+	if (cfg.TLS.CertFile == "") != (cfg.TLS.KeyFile == "") {
+		return fmt.Errorf("redis.tls.cert_file and redis.tls.key_file must be set together")
+	}
+	if strings.ContainsAny(cfg.KeyPrefix, "{}") {
+		return fmt.Errorf("redis.key_prefix must not contain braces (reserved for cluster hash tags)")
+	}
+
+	return nil
+}
+
+// validateRabbitMQLimits validates DLQ and queue argument settings
+func validateRabbitMQLimits(cfg *RabbitMQConfig) error {
+	if cfg.DLQEnabled {
+		if cfg.DLQExchange == "" {
+			return fmt.Errorf("queue.rabbitmq.dlq_exchange is required when dlq is enabled")
+		}
+		if cfg.DLQQueue == "" {
+			return fmt.Errorf("queue.rabbitmq.dlq_queue is required when dlq is enabled")
+		}
+		if cfg.DLQExchange == cfg.Exchange {
+			return fmt.Errorf("queue.rabbitmq.dlq_exchange must differ from queue.rabbitmq.exchange")
+		}
+		if cfg.DLQQueue == cfg.QueueName {
+			return fmt.Errorf("queue.rabbitmq.dlq_queue must differ from queue.rabbitmq.queue_name")
+		}
+	}
+
+	if cfg.MessageTTL < 0 {
+		return fmt.Errorf("queue.rabbitmq.message_ttl must not be negative")
+	}
+	if cfg.MessageTTL > 0 && cfg.MessageTTL < time.Millisecond {
+		return fmt.Errorf("queue.rabbitmq.message_ttl must be at least 1ms")
+	}
+
+	if cfg.MaxLength < 0 {
+		return fmt.Errorf("queue.rabbitmq.max_length must not be negative")
+	}
+
+	switch cfg.Overflow {
+	case "", "drop-head", "reject-publish":
+	case "reject-publish-dlx":
+		if !cfg.DLQEnabled {
+			return fmt.Errorf("queue.rabbitmq.overflow reject-publish-dlx requires dlq_enabled")
+		}
+	default:
+		return fmt.Errorf("queue.rabbitmq.overflow must be drop-head, reject-publish or reject-publish-dlx")
+	}
+
+	if cfg.Overflow != "" && cfg.MaxLength == 0 {
+		return fmt.Errorf("queue.rabbitmq.overflow requires queue.rabbitmq.max_length")
+	}
+
+	return nil
+}
+
+// validateRoutes validates RabbitMQ routing rules
+func validateRoutes(routes []RouteConfig) error {
+	for i, route := range routes {
+		if route.RoutingKey == "" {
+			return fmt.Errorf("queue.rabbitmq.routes[%d].routing_key is required", i)
+		}
+		if route.Kind == "" && route.Pattern == "" && route.Source == "" {
+			return fmt.Errorf("queue.rabbitmq.routes[%d] must match on kind, pattern or source", i)
+		}
+		if route.Pattern != "" {
+			if _, err := filepath.Match(route.Pattern, ""); err != nil {
+				return fmt.Errorf("queue.rabbitmq.routes[%d].pattern is invalid: %w", i, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateEncoding validates the message encoding settings, including the
+// formats chosen per destination, route and source target
+func validateEncoding(cfg *Config) error {
+	enc := &cfg.Queue.Encoding
+
+	type destinationFormat struct{ key, destination, format string }
+	formats := []destinationFormat{{"queue.encoding.format", "", enc.Format}}
+
+	for i, d := range enc.DestinationFormats {
+		if d.Destination == "" {
+			return fmt.Errorf("queue.encoding.destination_formats[%d].destination is required", i)
+		}
+		formats = append(formats, destinationFormat{
+			fmt.Sprintf("queue.encoding.destination_formats[%d].format", i), d.Destination, d.Format,
+		})
+	}
+	if cfg.Queue.Type == "rabbitmq" {
+		for i, route := range cfg.Queue.RabbitMQ.Routes {
+			if route.Format != "" {
+				formats = append(formats, destinationFormat{
+					fmt.Sprintf("queue.rabbitmq.routes[%d].format", i), route.RoutingKey, route.Format,
+				})
+			}
+		}
+	}
+	for i, src := range cfg.Watcher.Sources {
+		if src.Target.Format == "" {
+			continue
+		}
+		for _, dest := range targetDestinations(src.Target) {
+			formats = append(formats, destinationFormat{
+				fmt.Sprintf("watcher.sources[%d].target.format", i), dest, src.Target.Format,
+			})
+		}
+	}
+
+	// One destination carries one wire format, so consumers never have to
+	// tell formats apart within a queue
+	usesCloudEvents := false
+	byDestination := make(map[string]string)
+	for _, f := range formats {
+		if err := validateEncodingFormat(f.key, f.format); err != nil {
+			return err
+		}
+		usesCloudEvents = usesCloudEvents || f.format == "cloudevents"
+
+		if f.destination == "" {
+			continue
+		}
+		if prev, ok := byDestination[f.destination]; ok && prev != f.format {
+			return fmt.Errorf("%s: destination %s is already encoded as %s", f.key, f.destination, prev)
+		}
+		byDestination[f.destination] = f.format
+	}
+
+	if usesCloudEvents {
+		switch enc.CloudEvents.Mode {
+		case "structured", "binary":
+		default:
+			return fmt.Errorf("queue.encoding.cloudevents.mode must be structured or binary")
+		}
+		if enc.CloudEvents.Source == "" || enc.CloudEvents.Type == "" {
+			return fmt.Errorf("queue.encoding.cloudevents.source and type are required")
+		}
+	}
+
+	return nil
+}
+
+// targetDestinations returns the topic, stream, subject, URLs or routing
+// key a source target publishes to
+func targetDestinations(t SourceTargetConfig) []string {
+	switch {
+	case t.RoutingKey != "":
+		return []string{t.RoutingKey}
+	case t.Topic != "":
+		return []string{t.Topic}
+	case t.Stream != "":
+		return []string{t.Stream}
+	case t.Subject != "":
+		return []string{t.Subject}
+	default:
+		return t.URLs
+	}
+}
+
+// validateEncodingFormat checks a single encoding format name
+func validateEncodingFormat(key, format string) error {
+	switch format {
+	case "json", "cloudevents", "protobuf":
+		return nil
+	default:
+		return fmt.Errorf("unsupported %s: %s", key, format)
+	}
+}
+
+// validateMarker checks the done-marker settings under key
+func validateMarker(key string, cfg *MarkerConfig) error {
+	if len(cfg.Templates) == 0 {
+		return fmt.Errorf("%s.templates must have at least one template", key)
+	}
+	for _, tmpl := range cfg.Templates {
+		if !strings.Contains(tmpl, "{name}") && !strings.Contains(tmpl, "{stem}") {
+			return fmt.Errorf("%s.templates: %q must contain {name} or {stem}", key, tmpl)
+		}
+		if tmpl == "{name}" || strings.ContainsAny(tmpl, `/\`) {
+			return fmt.Errorf("%s.templates: %q must name a file next to the data file", key, tmpl)
+		}
+	}
+
+	if cfg.Timeout <= 0 {
+		return fmt.Errorf("%s.timeout must be greater than 0", key)
+	}
+	if cfg.PollInterval <= 0 {
+		return fmt.Errorf("%s.poll_interval must be greater than 0", key)
+	}
+
+	switch cfg.Action {
+	case "delete":
+	case "archive":
+		if cfg.ArchiveDir == "" {
+			return fmt.Errorf("%s.archive_dir is required when action is archive", key)
+		}
+	default:
+		return fmt.Errorf("%s.action must be delete or archive", key)
+	}
+
+	return nil
+}
+
+// validatePathOptions checks the per-path watch modes
+func validatePathOptions(w *WatcherConfig) error {
+	watched := make(map[string]bool, len(w.Paths))
+	for _, path := range w.Paths {
+		watched[filepath.Clean(path)] = true
+	}
+
+	seen := make(map[string]bool, len(w.PathOptions))
+	for i, opts := range w.PathOptions {
+		if opts.Path == "" {
+			return fmt.Errorf("watcher.path_options[%d].path is required", i)
+		}
+
+		path := filepath.Clean(opts.Path)
+		if !watched[path] {
+			return fmt.Errorf("watcher.path_options[%d].path %s is not in watcher.paths", i, opts.Path)
+		}
+		if seen[path] {
+			return fmt.Errorf("watcher.path_options[%d].path %s is configured twice", i, opts.Path)
+		}
+		seen[path] = true
+
+		switch opts.Mode {
+		case "fsnotify", "poll", "both":
+		default:
+			return fmt.Errorf("watcher.path_options[%d].mode must be fsnotify, poll or both", i)
+		}
+
+		if opts.PollInterval <= 0 {
+			return fmt.Errorf("watcher.path_options[%d].poll_interval must be greater than 0", i)
+		}
+	}
+
+	return nil
+}
+
+// validateSources checks the per-path pipelines of watcher.sources
+func validateSources(cfg *Config) error {
+	seen := make(map[string]bool, len(cfg.Watcher.Sources))
+	for i, src := range cfg.Watcher.Sources {
+		if src.Path == "" {
+			return fmt.Errorf("watcher.sources[%d].path is required", i)
+		}
+
+		path := filepath.Clean(src.Path)
+		if seen[path] {
+			return fmt.Errorf("watcher.sources[%d].path %s is configured twice", i, src.Path)
+		}
+		seen[path] = true
+
+		if src.MinFileSize < 0 || src.MaxFileSize < 0 {
+			return fmt.Errorf("watcher.sources[%d] file sizes must not be negative", i)
+		}
+		if src.StableAttempts < 0 || src.StableDelay < 0 {
+			return fmt.Errorf("watcher.sources[%d] stability settings must not be negative", i)
+		}
+		if src.MaxFilesPerSecond < 0 {
+			return fmt.Errorf("watcher.sources[%d].max_files_per_second must not be negative", i)
+		}
+
+		switch src.Mode {
+		case "", "fsnotify", "poll", "both":
+		default:
+			return fmt.Errorf("watcher.sources[%d].mode must be fsnotify, poll or both", i)
+		}
+		if src.PollInterval < 0 {
+			return fmt.Errorf("watcher.sources[%d].poll_interval must not be negative", i)
+		}
+
+		mode := src.StabilityMode
+		if mode == "" {
+			mode = cfg.Watcher.StabilityMode
+		}
+		switch mode {
+		case "poll", "close_write":
+		case "marker":
+			marker := inheritMarker(src.Marker, cfg.Watcher.Marker)
+			if err := validateMarker(fmt.Sprintf("watcher.sources[%d].marker", i), &marker); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("watcher.sources[%d].stability_mode must be poll, close_write or marker", i)
+		}
+
+		if cfg.Queue.Enabled {
+			if err := validateSourceTarget(i, src.Target, cfg.Queue.Type); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateSourceTarget checks that a source target only sets fields of
+// the configured queue type
+func validateSourceTarget(i int, t SourceTargetConfig, queueType string) error {
+	fields := []struct {
+		queueType string
+		set       bool
+	}{
+		{"rabbitmq", t.Exchange != "" || t.RoutingKey != "" || t.Queue != ""},
+		{"kafka", t.Topic != ""},
+		{"redis", t.Stream != ""},
+		{"nats", t.Subject != ""},
+		{"webhook", len(t.URLs) > 0},
+	}
+	for _, f := range fields {
+		if f.set && f.queueType != queueType {
+			return fmt.Errorf("watcher.sources[%d].target sets %s fields but queue.type is %s", i, f.queueType, queueType)
+		}
+	}
+
+	if (t.Exchange != "" || t.Queue != "") && t.RoutingKey == "" {
+		return fmt.Errorf("watcher.sources[%d].target.routing_key is required with exchange or queue", i)
+	}
+	if t.Format != "" && len(targetDestinations(t)) == 0 {
+		return fmt.Errorf("watcher.sources[%d].target.format requires a destination", i)
+	}
+	if strings.ContainsAny(t.Subject, " *>") {
+		return fmt.Errorf("watcher.sources[%d].target.subject must not contain spaces or wildcards", i)
+	}
+
+	return nil
+}
+
+// inheritMarker fills the unset fields of a source's marker settings from
+// the watcher-wide ones
+func inheritMarker(m, parent MarkerConfig) MarkerConfig {
+	if len(m.Templates) == 0 {
+		m.Templates = parent.Templates
+	}
+	if m.Timeout == 0 {
+		m.Timeout = parent.Timeout
+	}
+	if m.PollInterval == 0 {
+		m.PollInterval = parent.PollInterval
+	}
+	if m.Action == "" {
+		m.Action = parent.Action
+	}
+	if m.ArchiveDir == "" {
+		m.ArchiveDir = parent.ArchiveDir
+	}
+	return m
+}
+
+// hasSourceURLs reports whether any source sends to webhook URLs of its own
+func hasSourceURLs(w *WatcherConfig) bool {
+	for _, src := range w.Sources {
+		if len(src.Target.URLs) > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -34,19 +34,21 @@ type Server struct {
 	startTime time.Time
 	logger    *logger.Logger
 
-	mu     sync.RWMutex
-	ready  bool
-	checks map[string]func() error
+	mu          sync.RWMutex
+	ready       bool
+	checks      map[string]func() error
+	readyChecks map[string]func() error
 }
 
 // NewServer creates a new health check server
 func NewServer(addr string, log *logger.Logger) *Server {
 	s := &Server{
-		addr:      addr,
-		startTime: time.Now(),
-		logger:    log,
-		ready:     false,
-		checks:    make(map[string]func() error),
+		addr:        addr,
+		startTime:   time.Now(),
+		logger:      log,
+		ready:       false,
+		checks:      make(map[string]func() error),
+		readyChecks: make(map[string]func() error),
 	}
 
 	mux := http.NewServeMux()
@@ -89,10 +91,17 @@ func (s *Server) AddCheck(name string, check func() error) {
 	s.checks[name] = check
 }
 
+// AddReadinessCheck adds a check that must pass for /ready to report ready
+func (s *Server) AddReadinessCheck(name string, check func() error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.readyChecks[name] = check
+}
+
 // healthHandler handles /health endpoint
 func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
 	s.mu.RLock()
-	checks := s.checks
+	checks := copyChecks(s.checks)
 	s.mu.RUnlock()
 
 	// Run all checks
@@ -134,8 +143,16 @@ func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
 func (s *Server) readyHandler(w http.ResponseWriter, r *http.Request) {
 	s.mu.RLock()
 	ready := s.ready
+	readyChecks := copyChecks(s.readyChecks)
 	s.mu.RUnlock()
 
+	for name, check := range readyChecks {
+		if err := check(); err != nil {
+			s.logger.Debug("Readiness check failed", "check", name, "error", err)
+			ready = false
+		}
+	}
+
 	if ready {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("ready"))
@@ -145,6 +162,16 @@ func (s *Server) readyHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// copyChecks copies a check map so it can be read after the lock is released,
+// while checks are still being added
+func copyChecks(checks map[string]func() error) map[string]func() error {
+	copied := make(map[string]func() error, len(checks))
+	for name, check := range checks {
+		copied[name] = check
+	}
+	return copied
+}
+
 // liveHandler handles /live endpoint (Kubernetes liveness probe)
 func (s *Server) liveHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
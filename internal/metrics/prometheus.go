@@ -37,6 +37,17 @@ var (
 		Help: "Total number of ignored files",
 	}, []string{})
 
+	// Consumer Results (Vectors)
+	resultsSucceededVec = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gordon_watcher_results_succeeded_total",
+		Help: "Total number of files reported as processed by consumers",
+	}, []string{})
+
+	resultsFailedVec = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gordon_watcher_results_failed_total",
+		Help: "Total number of files reported as failed by consumers",
+	}, []string{})
+
 	// Errors (Vectors)
 	watcherErrorsVec = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "gordon_watcher_errors_total",
@@ -53,6 +64,16 @@ var (
 		Help: "Total number of storage errors",
 	}, []string{})
 
+	locksLostVec = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gordon_watcher_locks_lost_total",
+		Help: "Total number of file locks lost while processing",
+	}, []string{})
+
+	markerTimeoutsVec = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gordon_watcher_marker_timeouts_total",
+		Help: "Total number of files moved to failed because their marker never appeared",
+	}, []string{})
+
 	// Rate Limiting (Vectors)
 	rateLimitWaitsVec = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "gordon_watcher_rate_limit_waits_total",
@@ -77,9 +98,13 @@ var (
 	FilesDuplicated         = filesDuplicatedVec.WithLabelValues()
 	FilesRejected           = filesRejectedVec.WithLabelValues()
 	FilesIgnored            = filesIgnoredVec.WithLabelValues()
+	ResultsSucceeded        = resultsSucceededVec.WithLabelValues()
+	ResultsFailed           = resultsFailedVec.WithLabelValues()
 	WatcherErrors           = watcherErrorsVec.WithLabelValues()
 	QueueErrors             = queueErrorsVec.WithLabelValues()
 	StorageErrors           = storageErrorsVec.WithLabelValues()
+	LocksLost               = locksLostVec.WithLabelValues()
+	MarkerTimeouts          = markerTimeoutsVec.WithLabelValues()
 	RateLimitWaits          = rateLimitWaitsVec.WithLabelValues()
 	RateLimitDropped        = rateLimitDroppedVec.WithLabelValues()
 	EmptyDirectoriesRemoved = emptyDirectoriesRemovedVec.WithLabelValues()
@@ -120,6 +145,12 @@ var (
 		Name: "gordon_watcher_goroutines",
 		Help: "Current number of goroutines",
 	})
+
+	// Storage
+	StorageEntries = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gordon_watcher_storage_entries",
+		Help: "Number of entries in the state storage by status",
+	}, []string{"status"})
 )
 
 // Init initializes metrics (sets to zero)
@@ -131,9 +162,13 @@ func Init() {
 	FilesDuplicated.Add(0)
 	FilesRejected.Add(0)
 	FilesIgnored.Add(0)
+	ResultsSucceeded.Add(0)
+	ResultsFailed.Add(0)
 	WatcherErrors.Add(0)
 	QueueErrors.Add(0)
 	StorageErrors.Add(0)
+	LocksLost.Add(0)
+	MarkerTimeouts.Add(0)
 	RateLimitWaits.Add(0)
 	RateLimitDropped.Add(0)
 	EmptyDirectoriesRemoved.Add(0)
@@ -154,9 +189,13 @@ func Reset() {
 	filesDuplicatedVec.Reset()
 	filesRejectedVec.Reset()
 	filesIgnoredVec.Reset()
+	resultsSucceededVec.Reset()
+	resultsFailedVec.Reset()
 	watcherErrorsVec.Reset()
 	queueErrorsVec.Reset()
 	storageErrorsVec.Reset()
+	locksLostVec.Reset()
+	markerTimeoutsVec.Reset()
 	rateLimitWaitsVec.Reset()
 	rateLimitDroppedVec.Reset()
 	emptyDirectoriesRemovedVec.Reset()
@@ -168,9 +207,13 @@ func Reset() {
 	FilesDuplicated = filesDuplicatedVec.WithLabelValues()
 	FilesRejected = filesRejectedVec.WithLabelValues()
 	FilesIgnored = filesIgnoredVec.WithLabelValues()
+	ResultsSucceeded = resultsSucceededVec.WithLabelValues()
+	ResultsFailed = resultsFailedVec.WithLabelValues()
 	WatcherErrors = watcherErrorsVec.WithLabelValues()
 	QueueErrors = queueErrorsVec.WithLabelValues()
 	StorageErrors = storageErrorsVec.WithLabelValues()
+	LocksLost = locksLostVec.WithLabelValues()
+	MarkerTimeouts = markerTimeoutsVec.WithLabelValues()
 	RateLimitWaits = rateLimitWaitsVec.WithLabelValues()
 	RateLimitDropped = rateLimitDroppedVec.WithLabelValues()
 	EmptyDirectoriesRemoved = emptyDirectoriesRemovedVec.WithLabelValues()
@@ -179,4 +222,5 @@ func Reset() {
 	WorkerPoolQueueSize.Set(0)
 	WorkerPoolActiveWorkers.Set(0)
 	GoroutineCount.Set(0)
+	StorageEntries.Reset()
 }
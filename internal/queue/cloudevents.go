@@ -0,0 +1,108 @@
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// CloudEvents content modes
+const (
+	CloudEventsStructured = "structured"
+	CloudEventsBinary     = "binary"
+)
+
+// CloudEventsHeaderPrefix prefixes attributes sent as headers in binary mode
+const CloudEventsHeaderPrefix = "ce-"
+
+// CloudEventsConfig configures the CloudEvents 1.0 envelope.
+// Source, Type and Subject accept the placeholders of expandTemplate.
+type CloudEventsConfig struct {
+	Mode    string // structured or binary
+	Source  string
+	Type    string
+	Subject string
+}
+
+// CloudEventsEncoder wraps messages as CloudEvents 1.0
+type CloudEventsEncoder struct {
+	cfg CloudEventsConfig
+}
+
+// cloudEvent is the structured-mode JSON representation
+type cloudEvent struct {
+	SpecVersion     string    `json:"specversion"`
+	ID              string    `json:"id"`
+	Source          string    `json:"source"`
+	Type            string    `json:"type"`
+	Subject         string    `json:"subject,omitempty"`
+	Time            time.Time `json:"time"`
+	DataContentType string    `json:"datacontenttype"`
+	Data            *Message  `json:"data"`
+}
+
+// NewCloudEventsEncoder creates a CloudEvents encoder
+func NewCloudEventsEncoder(cfg CloudEventsConfig) (*CloudEventsEncoder, error) {
+	switch cfg.Mode {
+	case "":
+		cfg.Mode = CloudEventsStructured
+	case CloudEventsStructured, CloudEventsBinary:
+	default:
+		return nil, fmt.Errorf("invalid CloudEvents mode %q", cfg.Mode)
+	}
+
+	if cfg.Source == "" || cfg.Type == "" {
+		return nil, fmt.Errorf("CloudEvents source and type are required")
+	}
+
+	return &CloudEventsEncoder{cfg: cfg}, nil
+}
+
+// Encode wraps msg as a CloudEvent in the configured content mode
+func (e *CloudEventsEncoder) Encode(msg *Message) (*Envelope, error) {
+	event := cloudEvent{
+		SpecVersion:     "1.0",
+		ID:              msg.ID,
+		Source:          expandTemplate(e.cfg.Source, msg),
+		Type:            expandTemplate(e.cfg.Type, msg),
+		Subject:         expandTemplate(e.cfg.Subject, msg),
+		Time:            msg.Timestamp.UTC(),
+		DataContentType: "application/json",
+		Data:            msg,
+	}
+
+	if e.cfg.Mode == CloudEventsStructured {
+		body, err := json.Marshal(event)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal CloudEvent: %w", err)
+		}
+
+		return &Envelope{
+			Body:        body,
+			ContentType: "application/cloudevents+json",
+		}, nil
+	}
+
+	// Binary mode: data is the body, attributes travel as headers
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	headers := map[string]string{
+		CloudEventsHeaderPrefix + "specversion": event.SpecVersion,
+		CloudEventsHeaderPrefix + "id":          event.ID,
+		CloudEventsHeaderPrefix + "source":      event.Source,
+		CloudEventsHeaderPrefix + "type":        event.Type,
+		CloudEventsHeaderPrefix + "time":        event.Time.Format(time.RFC3339Nano),
+	}
+	if event.Subject != "" {
+		headers[CloudEventsHeaderPrefix+"subject"] = event.Subject
+	}
+
+	return &Envelope{
+		Body:        body,
+		ContentType: event.DataContentType,
+		Headers:     headers,
+	}, nil
+}
@@ -0,0 +1,115 @@
+package queue
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func testCloudEventsMessage() *Message {
+	return &Message{
+		ID:        "abc",
+		Filename:  "nota.xml",
+		Kind:      "xml",
+		Hash:      "deadbeef",
+		Source:    "/data/incoming",
+		Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+}
+
+func TestCloudEventsEncoder_Structured(t *testing.T) {
+	enc, err := NewCloudEventsEncoder(CloudEventsConfig{
+		Source:  "gordon-watcher",
+		Type:    "com.gordon.file.{kind}",
+		Subject: "{filename}",
+	})
+	if err != nil {
+		t.Fatalf("NewCloudEventsEncoder() failed: %v", err)
+	}
+
+	env, err := enc.Encode(testCloudEventsMessage())
+	if err != nil {
+		t.Fatalf("Encode() failed: %v", err)
+	}
+
+	if env.ContentType != "application/cloudevents+json" {
+		t.Errorf("content type = %q", env.ContentType)
+	}
+	if len(env.Headers) != 0 {
+		t.Errorf("structured mode should not set headers, got %v", env.Headers)
+	}
+
+	var event struct {
+		SpecVersion string  `json:"specversion"`
+		ID          string  `json:"id"`
+		Type        string  `json:"type"`
+		Subject     string  `json:"subject"`
+		Data        Message `json:"data"`
+	}
+	if err := json.Unmarshal(env.Body, &event); err != nil {
+		t.Fatalf("body is not JSON: %v", err)
+	}
+
+	if event.SpecVersion != "1.0" || event.ID != "abc" {
+		t.Errorf("specversion/id = %q/%q", event.SpecVersion, event.ID)
+	}
+	if event.Type != "com.gordon.file.xml" {
+		t.Errorf("type = %q, want %q", event.Type, "com.gordon.file.xml")
+	}
+	if event.Subject != "nota.xml" {
+		t.Errorf("subject = %q, want %q", event.Subject, "nota.xml")
+	}
+	if event.Data.Hash != "deadbeef" {
+		t.Errorf("data.hash = %q, want %q", event.Data.Hash, "deadbeef")
+	}
+}
+
+func TestCloudEventsEncoder_Binary(t *testing.T) {
+	enc, err := NewCloudEventsEncoder(CloudEventsConfig{
+		Mode:   CloudEventsBinary,
+		Source: "{source}",
+		Type:   "com.gordon.file.detected",
+	})
+	if err != nil {
+		t.Fatalf("NewCloudEventsEncoder() failed: %v", err)
+	}
+
+	env, err := enc.Encode(testCloudEventsMessage())
+	if err != nil {
+		t.Fatalf("Encode() failed: %v", err)
+	}
+
+	if env.ContentType != "application/json" {
+		t.Errorf("content type = %q", env.ContentType)
+	}
+
+	want := map[string]string{
+		"ce-specversion": "1.0",
+		"ce-id":          "abc",
+		"ce-source":      "/data/incoming",
+		"ce-type":        "com.gordon.file.detected",
+		"ce-time":        "2024-01-02T03:04:05Z",
+	}
+	for k, v := range want {
+		if env.Headers[k] != v {
+			t.Errorf("header %s = %q, want %q", k, env.Headers[k], v)
+		}
+	}
+	if _, ok := env.Headers["ce-subject"]; ok {
+		t.Error("empty subject should not be sent")
+	}
+
+	var msg Message
+	if err := json.Unmarshal(env.Body, &msg); err != nil || msg.ID != "abc" {
+		t.Errorf("body should be the plain message, got %s (%v)", env.Body, err)
+	}
+}
+
+func TestNewCloudEventsEncoder_Invalid(t *testing.T) {
+	if _, err := NewCloudEventsEncoder(CloudEventsConfig{Mode: "batched", Source: "s", Type: "t"}); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+	if _, err := NewCloudEventsEncoder(CloudEventsConfig{Source: "s"}); err == nil {
+		t.Error("expected error for missing type")
+	}
+}
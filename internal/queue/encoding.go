@@ -0,0 +1,72 @@
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Envelope is an encoded message ready to be sent by a backend
+type Envelope struct {
+	Body        []byte
+	ContentType string
+
+	// Headers carry protocol-independent metadata, e.g. CloudEvents
+	// attributes in binary mode as "ce-<attribute>"
+	Headers map[string]string
+}
+
+// Encoder turns a Message into the wire format sent by every backend
+type Encoder interface {
+	Encode(msg *Message) (*Envelope, error)
+}
+
+// JSONEncoder encodes messages as plain JSON (the default)
+type JSONEncoder struct{}
+
+// Encode marshals msg as JSON
+func (JSONEncoder) Encode(msg *Message) (*Envelope, error) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	return &Envelope{
+		Body:        body,
+		ContentType: "application/json",
+	}, nil
+}
+
+// encoderOrDefault returns enc, or the JSON encoder when enc is nil
+func encoderOrDefault(enc Encoder) Encoder {
+	if enc == nil {
+		return JSONEncoder{}
+	}
+	return enc
+}
+
+// expandTemplate replaces message placeholders in a template:
+// {id}, {hash}, {kind}, {filename}, {path} and {source}
+func expandTemplate(tmpl string, msg *Message) string {
+	if !strings.Contains(tmpl, "{") {
+		return tmpl
+	}
+
+	return strings.NewReplacer(
+		"{id}", msg.ID,
+		"{hash}", msg.Hash,
+		"{kind}", msg.Kind,
+		"{filename}", msg.Filename,
+		"{path}", msg.Path,
+		"{source}", msg.Source,
+	).Replace(tmpl)
+}
+
+// encoderFor returns the encoder configured for a destination (topic,
+// stream, subject or URL), or def
+func encoderFor(def Encoder, destinations map[string]Encoder, destination string) Encoder {
+	if enc, ok := destinations[destination]; ok {
+		return enc
+	}
+	return def
+}
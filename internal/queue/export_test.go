@@ -0,0 +1,4 @@
+package queue
+
+// RunNATSServer lets the conformance suite use the embedded JetStream server
+var RunNATSServer = runNATSServer
@@ -0,0 +1,221 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/IBM/sarama"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/fabyo/gordon-watcher/internal/logger"
+)
+
+// KafkaConfig holds Kafka configuration
+type KafkaConfig struct {
+	Brokers  []string
+	ClientID string
+	Version  string // Kafka protocol version (e.g. "2.8.0")
+
+	// Topic is the default topic; Topics overrides it per Message.Kind
+	// and SourceTopics per watch path (Message.Source), before the kind
+	Topic        string
+	Topics       map[string]string
+	SourceTopics map[string]string
+
+	// Producer settings
+	Idempotent bool
+	MaxRetries int
+
+	// Encoder builds the record value and headers (nil = plain JSON);
+	// TopicEncoders overrides it per topic
+	Encoder       Encoder
+	TopicEncoders map[string]Encoder
+}
+
+// KafkaQueue implements Queue interface for Kafka
+type KafkaQueue struct {
+	cfg      KafkaConfig
+	producer sarama.SyncProducer
+	encoder  Encoder
+	logger   *logger.Logger
+
+	// Sending on a closed sarama producer panics, so Close waits for
+	// in-flight publishes
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewKafkaQueue creates a new Kafka queue
+func NewKafkaQueue(cfg KafkaConfig, log *logger.Logger) (*KafkaQueue, error) {
+	sc := sarama.NewConfig()
+	sc.ClientID = cfg.ClientID
+	sc.Producer.Return.Successes = true // Required by SyncProducer
+	sc.Producer.RequiredAcks = sarama.WaitForAll
+
+	if cfg.MaxRetries > 0 {
+		sc.Producer.Retry.Max = cfg.MaxRetries
+	}
+
+	if cfg.Version != "" {
+		version, err := sarama.ParseKafkaVersion(cfg.Version)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Kafka version: %w", err)
+		}
+		sc.Version = version
+	}
+
+	if cfg.Idempotent {
+		// Idempotence needs acks=all, a single in-flight request and Kafka >= 0.11
+		sc.Producer.Idempotent = true
+		sc.Net.MaxOpenRequests = 1
+		if !sc.Version.IsAtLeast(sarama.V0_11_0_0) {
+			sc.Version = sarama.V0_11_0_0
+		}
+	}
+
+	producer, err := sarama.NewSyncProducer(cfg.Brokers, sc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kafka producer: %w", err)
+	}
+
+	log.Info("Connected to Kafka",
+		"brokers", cfg.Brokers,
+		"topic", cfg.Topic,
+		"idempotent", cfg.Idempotent,
+	)
+
+	return &KafkaQueue{
+		cfg:      cfg,
+		producer: producer,
+		encoder:  encoderOrDefault(cfg.Encoder),
+		logger:   log,
+	}, nil
+}
+
+// Publish publishes a message to Kafka
+func (q *KafkaQueue) Publish(ctx context.Context, msg *Message) error {
+	tracer := otel.Tracer("gordon-watcher")
+	ctx, span := tracer.Start(ctx, "kafka.publish")
+	defer span.End()
+
+	topic := q.topicFor(msg)
+
+	span.SetAttributes(
+		attribute.String("message.id", msg.ID),
+		attribute.String("message.filename", msg.Filename),
+		attribute.String("message.kind", msg.Kind),
+		attribute.String("kafka.topic", topic),
+	)
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return ErrClosed
+	}
+
+	// Encode message
+	env, err := encoderFor(q.encoder, q.cfg.TopicEncoders, topic).Encode(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	// Propagate trace context through record headers
+	headers := kafkaHeaderCarrier{
+		{Key: []byte("content-type"), Value: []byte(env.ContentType)},
+		{Key: []byte("message-id"), Value: []byte(msg.ID)},
+	}
+	for k, v := range env.Headers {
+		// The Kafka binding of CloudEvents uses "ce_" instead of "ce-"
+		k = strings.Replace(k, CloudEventsHeaderPrefix, "ce_", 1)
+		headers.Set(k, v)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, &headers)
+
+	partition, offset, err := q.producer.SendMessage(&sarama.ProducerMessage{
+		Topic:   topic,
+		Key:     sarama.StringEncoder(msg.Hash),
+		Value:   sarama.ByteEncoder(env.Body),
+		Headers: headers,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish message: %w", err)
+	}
+
+	q.logger.Debug("Message published to Kafka",
+		"messageId", msg.ID,
+		"filename", msg.Filename,
+		"topic", topic,
+		"partition", partition,
+		"offset", offset,
+	)
+
+	return nil
+}
+
+// Close closes the Kafka producer
+func (q *KafkaQueue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return nil
+	}
+	q.closed = true
+
+	if err := q.producer.Close(); err != nil {
+		return fmt.Errorf("failed to close Kafka producer: %w", err)
+	}
+	q.logger.Info("Kafka producer closed")
+	return nil
+}
+
+// topicFor returns the topic for a message source or kind
+func (q *KafkaQueue) topicFor(msg *Message) string {
+	if topic, ok := forSource(q.cfg.SourceTopics, msg.Source); ok && topic != "" {
+		return topic
+	}
+	if topic, ok := q.cfg.Topics[msg.Kind]; ok && topic != "" {
+		return topic
+	}
+	return q.cfg.Topic
+}
+
+// kafkaHeaderCarrier adapts Kafka record headers to propagation.TextMapCarrier
+type kafkaHeaderCarrier []sarama.RecordHeader
+
+// Get returns the value for a header key
+func (c *kafkaHeaderCarrier) Get(key string) string {
+	for _, h := range *c {
+		if string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// Set sets a header, replacing an existing one with the same key
+func (c *kafkaHeaderCarrier) Set(key, value string) {
+	for i, h := range *c {
+		if string(h.Key) == key {
+			(*c)[i].Value = []byte(value)
+			return
+		}
+	}
+	*c = append(*c, sarama.RecordHeader{Key: []byte(key), Value: []byte(value)})
+}
+
+// Keys lists the header keys
+func (c *kafkaHeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(*c))
+	for _, h := range *c {
+		keys = append(keys, string(h.Key))
+	}
+	return keys
+}
@@ -0,0 +1,147 @@
+package queue
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// testTraceID is the trace of the context returned by tracedContext
+const testTraceID = "0af7651916cd43dd8448eb211c80319c"
+
+// tracedContext installs the W3C propagator for the test and returns a
+// context carrying a sampled remote span
+func tracedContext(t *testing.T) context.Context {
+	t.Helper()
+
+	prev := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(prev) })
+
+	traceID, _ := trace.TraceIDFromHex(testTraceID)
+	spanID, _ := trace.SpanIDFromHex("b7ad6b7169203331")
+	return trace.ContextWithRemoteSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+}
+
+// newMockKafkaQueue returns a queue publishing to a mock producer
+func newMockKafkaQueue(t *testing.T, cfg KafkaConfig) (*KafkaQueue, *mocks.SyncProducer) {
+	t.Helper()
+
+	producer := mocks.NewSyncProducer(t, nil)
+	q := &KafkaQueue{
+		cfg:      cfg,
+		producer: producer,
+		encoder:  encoderOrDefault(cfg.Encoder),
+		logger:   newTestLogger(),
+	}
+	t.Cleanup(func() { _ = q.Close() })
+	return q, producer
+}
+
+func TestKafkaQueue_TopicFor(t *testing.T) {
+	q, producer := newMockKafkaQueue(t, KafkaConfig{
+		Topic:        "files",
+		Topics:       map[string]string{"xml": "files.xml", "pdf": ""},
+		SourceTopics: map[string]string{"/data/partner-a": "partner-a"},
+	})
+
+	tests := []struct {
+		msg  Message
+		want string
+	}{
+		{Message{ID: "1", Kind: "xml"}, "files.xml"},
+		{Message{ID: "2", Kind: "csv"}, "files"},
+		{Message{ID: "3", Kind: "pdf"}, "files"}, // empty override falls back
+		{Message{ID: "4", Kind: "xml", Source: "/data/partner-a/"}, "partner-a"},
+		{Message{ID: "5", Kind: "xml", Source: "/data/partner-b"}, "files.xml"},
+	}
+
+	for _, tt := range tests {
+		want := tt.want
+		producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(pm *sarama.ProducerMessage) error {
+			if pm.Topic != want {
+				t.Errorf("topic = %s, want %s", pm.Topic, want)
+			}
+			return nil
+		})
+
+		msg := tt.msg
+		if err := q.Publish(context.Background(), &msg); err != nil {
+			t.Fatalf("Publish(%s) failed: %v", msg.ID, err)
+		}
+	}
+}
+
+func TestKafkaQueue_TopicEncoders(t *testing.T) {
+	q, producer := newMockKafkaQueue(t, KafkaConfig{
+		Topic:         "files",
+		Topics:        map[string]string{"zip": "files.zip"},
+		TopicEncoders: map[string]Encoder{"files.zip": ProtobufEncoder{}},
+	})
+
+	// The format follows the destination topic, not the kind
+	want := map[string]string{"files.zip": ProtobufContentType, "files": "application/json"}
+	for _, kind := range []string{"zip", "xml"} {
+		producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(pm *sarama.ProducerMessage) error {
+			headers := kafkaHeaderCarrier(pm.Headers)
+			if got := headers.Get("content-type"); got != want[pm.Topic] {
+				t.Errorf("%s content type = %q, want %q", pm.Topic, got, want[pm.Topic])
+			}
+			return nil
+		})
+		if err := q.Publish(context.Background(), &Message{ID: kind, Kind: kind}); err != nil {
+			t.Fatalf("Publish(%s) failed: %v", kind, err)
+		}
+	}
+}
+
+func TestKafkaQueue_KeyAndHeaders(t *testing.T) {
+	q, producer := newMockKafkaQueue(t, KafkaConfig{Topic: "files"})
+
+	var sent *sarama.ProducerMessage
+	producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(pm *sarama.ProducerMessage) error {
+		sent = pm
+		return nil
+	})
+
+	msg := &Message{ID: "id-1", Hash: "abc123", Filename: "a.xml"}
+	if err := q.Publish(tracedContext(t), msg); err != nil {
+		t.Fatalf("Publish() failed: %v", err)
+	}
+
+	// Records of the same file land on the same partition
+	key, _ := sent.Key.Encode()
+	if string(key) != "abc123" {
+		t.Errorf("key = %q, want the file hash", key)
+	}
+
+	headers := kafkaHeaderCarrier(sent.Headers)
+	if got := headers.Get("message-id"); got != "id-1" {
+		t.Errorf("message-id header = %q, want id-1", got)
+	}
+	if got := headers.Get("content-type"); got != "application/json" {
+		t.Errorf("content-type header = %q, want application/json", got)
+	}
+	if got := headers.Get("traceparent"); !strings.Contains(got, testTraceID) {
+		t.Errorf("traceparent header = %q, want trace %s", got, testTraceID)
+	}
+}
+
+func TestKafkaQueue_PublishAfterClose(t *testing.T) {
+	q, _ := newMockKafkaQueue(t, KafkaConfig{Topic: "files"})
+	_ = q.Close()
+
+	if err := q.Publish(context.Background(), &Message{ID: "1"}); err != ErrClosed {
+		t.Errorf("Publish() after Close = %v, want ErrClosed", err)
+	}
+}
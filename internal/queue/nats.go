@@ -0,0 +1,223 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+
+	"github.com/fabyo/gordon-watcher/internal/logger"
+)
+
+// NATSConfig holds NATS JetStream configuration
+type NATSConfig struct {
+	URL    string
+	Stream string
+
+	// SubjectTemplate builds the subject per message, e.g. "gordon.files.{kind}";
+	// SourceSubjects overrides it per watch path (Message.Source)
+	SubjectTemplate string
+	SourceSubjects  map[string]string
+
+	// Stream auto-creation
+	AutoCreateStream bool
+	DuplicateWindow  time.Duration
+
+	// AckTimeout bounds the wait for the JetStream publish ack
+	AckTimeout time.Duration
+
+	// Encoder builds the message data and headers (nil = plain JSON);
+	// SubjectEncoders overrides it per subject or subject template
+	Encoder         Encoder
+	SubjectEncoders map[string]Encoder
+}
+
+// NATSQueue implements Queue interface for NATS JetStream
+type NATSQueue struct {
+	cfg     NATSConfig
+	nc      *nats.Conn
+	js      jetstream.JetStream
+	encoder Encoder
+	logger  *logger.Logger
+	closed  atomic.Bool
+}
+
+// NewNATSQueue creates a new NATS JetStream queue
+func NewNATSQueue(cfg NATSConfig, log *logger.Logger) (*NATSQueue, error) {
+	// Connect to NATS
+	nc, err := nats.Connect(cfg.URL, nats.Name("gordon-watcher"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if cfg.AutoCreateStream {
+		_, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+			Name:       cfg.Stream,
+			Subjects:   streamSubjects(cfg),
+			Storage:    jetstream.FileStorage,
+			Duplicates: cfg.DuplicateWindow,
+		})
+		if err != nil {
+			nc.Close()
+			return nil, fmt.Errorf("failed to create stream: %w", err)
+		}
+	} else if _, err := js.Stream(ctx, cfg.Stream); err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("failed to look up stream %s: %w", cfg.Stream, err)
+	}
+
+	log.Info("Connected to NATS JetStream",
+		"url", cfg.URL,
+		"stream", cfg.Stream,
+		"subject", cfg.SubjectTemplate,
+	)
+
+	return &NATSQueue{
+		cfg:     cfg,
+		nc:      nc,
+		js:      js,
+		encoder: encoderOrDefault(cfg.Encoder),
+		logger:  log,
+	}, nil
+}
+
+// Publish publishes a message to JetStream and waits for the publish ack
+func (q *NATSQueue) Publish(ctx context.Context, msg *Message) error {
+	tracer := otel.Tracer("gordon-watcher")
+	ctx, span := tracer.Start(ctx, "nats.publish")
+	defer span.End()
+
+	if q.closed.Load() {
+		return ErrClosed
+	}
+
+	subject, tmpl := q.subjectFor(msg)
+
+	span.SetAttributes(
+		attribute.String("message.id", msg.ID),
+		attribute.String("message.filename", msg.Filename),
+		attribute.String("message.kind", msg.Kind),
+		attribute.String("nats.subject", subject),
+	)
+
+	// Encode message
+	encoder := encoderFor(nil, q.cfg.SubjectEncoders, subject)
+	if encoder == nil {
+		encoder = encoderFor(q.encoder, q.cfg.SubjectEncoders, tmpl)
+	}
+	env, err := encoder.Encode(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	natsMsg := nats.NewMsg(subject)
+	natsMsg.Data = env.Body
+	natsMsg.Header.Set("Content-Type", env.ContentType)
+	for k, v := range env.Headers {
+		natsMsg.Header.Set(k, v)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(http.Header(natsMsg.Header)))
+
+	if q.cfg.AckTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, q.cfg.AckTimeout)
+		defer cancel()
+	}
+
+	// Nats-Msg-Id lets JetStream drop duplicates within the stream's window
+	ack, err := q.js.PublishMsg(ctx, natsMsg, jetstream.WithMsgID(msg.ID))
+	if err != nil {
+		return fmt.Errorf("failed to publish message: %w", err)
+	}
+
+	if ack.Duplicate {
+		q.logger.Info("Duplicate message dropped by JetStream",
+			"messageId", msg.ID,
+			"stream", ack.Stream,
+			"sequence", ack.Sequence,
+		)
+		return nil
+	}
+
+	q.logger.Debug("Message published to NATS JetStream",
+		"messageId", msg.ID,
+		"filename", msg.Filename,
+		"subject", subject,
+		"sequence", ack.Sequence,
+	)
+
+	return nil
+}
+
+// Close drains and closes the NATS connection
+func (q *NATSQueue) Close() error {
+	if !q.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	if err := q.nc.Drain(); err != nil {
+		q.nc.Close()
+		return fmt.Errorf("failed to drain NATS connection: %w", err)
+	}
+	q.logger.Info("NATS connection closed")
+	return nil
+}
+
+// streamSubjects derives the stream subjects from every subject template,
+// with {kind} as a wildcard token
+func streamSubjects(cfg NATSConfig) []string {
+	seen := make(map[string]bool)
+	var subjects []string
+
+	templates := []string{cfg.SubjectTemplate}
+	for _, tmpl := range cfg.SourceSubjects {
+		templates = append(templates, tmpl)
+	}
+
+	for _, tmpl := range templates {
+		subject := strings.ReplaceAll(tmpl, "{kind}", "*")
+		if tmpl != "" && !seen[subject] {
+			seen[subject] = true
+			subjects = append(subjects, subject)
+		}
+	}
+
+	// Map order must not reorder the stream config on every start
+	sort.Strings(subjects)
+	return subjects
+}
+
+// subjectFor renders the subject template for a message and returns it
+// with the template it came from
+func (q *NATSQueue) subjectFor(msg *Message) (string, string) {
+	kind := msg.Kind
+	if kind == "" {
+		kind = "unknown"
+	}
+	// Subject tokens cannot contain separators or wildcards
+	kind = strings.NewReplacer(".", "_", "*", "_", ">", "_", " ", "_").Replace(kind)
+
+	tmpl := q.cfg.SubjectTemplate
+	if t, ok := forSource(q.cfg.SourceSubjects, msg.Source); ok && t != "" {
+		tmpl = t
+	}
+
+	return strings.ReplaceAll(tmpl, "{kind}", kind), tmpl
+}
@@ -0,0 +1,140 @@
+package queue
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// runNATSServer starts an embedded JetStream server and returns its URL
+func runNATSServer(t *testing.T) string {
+	t.Helper()
+
+	ns, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	if err != nil {
+		t.Fatalf("NewServer() failed: %v", err)
+	}
+	go ns.Start()
+	t.Cleanup(ns.Shutdown)
+
+	if !ns.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server not ready")
+	}
+	return ns.ClientURL()
+}
+
+func TestNATSQueue_SubjectFor(t *testing.T) {
+	q := &NATSQueue{cfg: NATSConfig{
+		SubjectTemplate: "gordon.files.{kind}",
+		SourceSubjects:  map[string]string{"/data/partner-a": "partner-a.{kind}"},
+	}}
+
+	tests := []struct {
+		msg  Message
+		want string
+	}{
+		{Message{Kind: "xml"}, "gordon.files.xml"},
+		{Message{}, "gordon.files.unknown"},
+		// Separators and wildcards would split or widen the subject
+		{Message{Kind: "nfe.v4"}, "gordon.files.nfe_v4"},
+		{Message{Kind: "a*b>c d"}, "gordon.files.a_b_c_d"},
+		{Message{Kind: "xml", Source: "/data/partner-a/"}, "partner-a.xml"},
+		{Message{Kind: "xml", Source: "/data/partner-b"}, "gordon.files.xml"},
+	}
+
+	for _, tt := range tests {
+		if got, _ := q.subjectFor(&tt.msg); got != tt.want {
+			t.Errorf("subjectFor(%+v) = %s, want %s", tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestStreamSubjects(t *testing.T) {
+	got := streamSubjects(NATSConfig{
+		SubjectTemplate: "gordon.files.{kind}",
+		SourceSubjects: map[string]string{
+			"/data/partner-a": "partner-a.{kind}.in",
+			"/data/partner-b": "gordon.files.{kind}",
+			"/data/partner-c": "",
+			"/data/archive":   "archive.files",
+		},
+	})
+
+	// Sorted, deduplicated, with {kind} as a single-token wildcard
+	want := []string{"archive.files", "gordon.files.*", "partner-a.*.in"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("streamSubjects() = %v, want %v", got, want)
+	}
+}
+
+func TestNATSQueue_PublishHeaders(t *testing.T) {
+	url := runNATSServer(t)
+
+	q, err := NewNATSQueue(NATSConfig{
+		URL:              url,
+		Stream:           "GORDON",
+		SubjectTemplate:  "gordon.files.{kind}",
+		AutoCreateStream: true,
+		DuplicateWindow:  time.Minute,
+		AckTimeout:       5 * time.Second,
+	}, newTestLogger())
+	if err != nil {
+		t.Fatalf("NewNATSQueue() failed: %v", err)
+	}
+	defer q.Close()
+
+	ctx := tracedContext(t)
+	msg := &Message{ID: "id-1", Kind: "xml", Filename: "a.xml"}
+	if err := q.Publish(ctx, msg); err != nil {
+		t.Fatalf("Publish() failed: %v", err)
+	}
+	// A retry with the same ID is dropped by JetStream
+	if err := q.Publish(ctx, msg); err != nil {
+		t.Fatalf("second Publish() failed: %v", err)
+	}
+
+	stream, err := q.js.Stream(context.Background(), "GORDON")
+	if err != nil {
+		t.Fatalf("Stream() failed: %v", err)
+	}
+	info, err := stream.Info(context.Background())
+	if err != nil {
+		t.Fatalf("Info() failed: %v", err)
+	}
+	if info.State.Msgs != 1 {
+		t.Errorf("stream has %d messages, want 1 after a duplicate publish", info.State.Msgs)
+	}
+
+	stored, err := stream.GetMsg(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("GetMsg() failed: %v", err)
+	}
+	if stored.Subject != "gordon.files.xml" {
+		t.Errorf("subject = %s, want gordon.files.xml", stored.Subject)
+	}
+	if got := stored.Header.Get(jetstream.MsgIDHeader); got != "id-1" {
+		t.Errorf("%s = %q, want id-1", jetstream.MsgIDHeader, got)
+	}
+
+	// Consumers extract with the same carrier the queue injects with
+	carrier := propagation.HeaderCarrier(http.Header(stored.Header))
+	remote := trace.SpanContextFromContext(otel.GetTextMapPropagator().Extract(context.Background(), carrier))
+	if got := remote.TraceID().String(); got != testTraceID {
+		t.Errorf("extracted trace = %s, want %s", got, testTraceID)
+	}
+}
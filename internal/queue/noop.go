@@ -2,6 +2,7 @@ package queue
 
 import (
 	"context"
+	"sync/atomic"
 
 	"github.com/fabyo/gordon-watcher/internal/logger"
 )
@@ -9,6 +10,7 @@ import (
 // NoOpQueue implements Queue interface but does nothing
 type NoOpQueue struct {
 	logger *logger.Logger
+	closed atomic.Bool
 }
 
 // NewNoOpQueue creates a new NoOp queue
@@ -18,8 +20,16 @@ func NewNoOpQueue(log *logger.Logger) *NoOpQueue {
 	}
 }
 
-// Publish does nothing (no-op)
+// Publish does nothing (no-op). It still fails like a real queue after
+// Close or with a cancelled context.
 func (q *NoOpQueue) Publish(ctx context.Context, msg *Message) error {
+	if q.closed.Load() {
+		return ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	q.logger.Debug("NoOp queue: message would be published",
 		"messageId", msg.ID,
 		"filename", msg.Filename,
@@ -28,8 +38,10 @@ func (q *NoOpQueue) Publish(ctx context.Context, msg *Message) error {
 	return nil
 }
 
-// Close does nothing
+// Close marks the queue closed
 func (q *NoOpQueue) Close() error {
-	q.logger.Info("NoOp queue closed")
+	if q.closed.CompareAndSwap(false, true) {
+		q.logger.Info("NoOp queue closed")
+	}
 	return nil
 }
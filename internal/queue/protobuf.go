@@ -0,0 +1,185 @@
+package queue
+
+import (
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// ProtobufContentType identifies messages encoded by ProtobufEncoder
+const ProtobufContentType = "application/x-protobuf; messageType=gordon.v1.FileMessage"
+
+// gordon.v1.FileMessage field numbers (see schema/message.proto)
+const (
+	pbSchemaVersion protowire.Number = 1
+	pbID            protowire.Number = 2
+	pbPath          protowire.Number = 3
+	pbFilename      protowire.Number = 4
+	pbKind          protowire.Number = 5
+	pbSize          protowire.Number = 6
+	pbHash          protowire.Number = 7
+	pbSource        protowire.Number = 8
+	pbTimestamp     protowire.Number = 9
+	pbFencingToken  protowire.Number = 10
+
+	// google.protobuf.Timestamp
+	pbSeconds protowire.Number = 1
+	pbNanos   protowire.Number = 2
+)
+
+// ProtobufEncoder encodes messages as gordon.v1.FileMessage
+type ProtobufEncoder struct{}
+
+// Encode marshals msg in the Protobuf wire format
+func (ProtobufEncoder) Encode(msg *Message) (*Envelope, error) {
+	var b []byte
+
+	// proto3: fields holding the zero value are not written
+	if msg.SchemaVersion != 0 {
+		b = protowire.AppendTag(b, pbSchemaVersion, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(msg.SchemaVersion))
+	}
+	b = appendString(b, pbID, msg.ID)
+	b = appendString(b, pbPath, msg.Path)
+	b = appendString(b, pbFilename, msg.Filename)
+	b = appendString(b, pbKind, msg.Kind)
+	if msg.Size != 0 {
+		b = protowire.AppendTag(b, pbSize, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(msg.Size))
+	}
+	b = appendString(b, pbHash, msg.Hash)
+	b = appendString(b, pbSource, msg.Source)
+	if !msg.Timestamp.IsZero() {
+		var ts []byte
+		if secs := msg.Timestamp.Unix(); secs != 0 {
+			ts = protowire.AppendTag(ts, pbSeconds, protowire.VarintType)
+			ts = protowire.AppendVarint(ts, uint64(secs))
+		}
+		if nanos := msg.Timestamp.Nanosecond(); nanos != 0 {
+			ts = protowire.AppendTag(ts, pbNanos, protowire.VarintType)
+			ts = protowire.AppendVarint(ts, uint64(nanos))
+		}
+		b = protowire.AppendTag(b, pbTimestamp, protowire.BytesType)
+		b = protowire.AppendBytes(b, ts)
+	}
+	if msg.FencingToken != 0 {
+		b = protowire.AppendTag(b, pbFencingToken, protowire.VarintType)
+		b = protowire.AppendVarint(b, msg.FencingToken)
+	}
+
+	return &Envelope{
+		Body:        b,
+		ContentType: ProtobufContentType,
+	}, nil
+}
+
+// DecodeProtobuf parses a gordon.v1.FileMessage. Unknown fields are skipped
+// so newer producers stay readable.
+func DecodeProtobuf(b []byte) (*Message, error) {
+	msg := &Message{}
+
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, fmt.Errorf("invalid protobuf tag: %w", protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		switch {
+		case num == pbSchemaVersion && typ == protowire.VarintType:
+			var v uint64
+			v, n = protowire.ConsumeVarint(b)
+			msg.SchemaVersion = int(v)
+		case num == pbSize && typ == protowire.VarintType:
+			var v uint64
+			v, n = protowire.ConsumeVarint(b)
+			msg.Size = int64(v)
+		case num == pbFencingToken && typ == protowire.VarintType:
+			msg.FencingToken, n = protowire.ConsumeVarint(b)
+		case num == pbTimestamp && typ == protowire.BytesType:
+			var v []byte
+			v, n = protowire.ConsumeBytes(b)
+			if n >= 0 {
+				ts, err := decodeTimestamp(v)
+				if err != nil {
+					return nil, err
+				}
+				msg.Timestamp = ts
+			}
+		case typ == protowire.BytesType && stringField(msg, num) != nil:
+			var v string
+			v, n = protowire.ConsumeString(b)
+			*stringField(msg, num) = v
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+
+		if n < 0 {
+			return nil, fmt.Errorf("invalid protobuf field %d: %w", num, protowire.ParseError(n))
+		}
+		b = b[n:]
+	}
+
+	return msg, nil
+}
+
+// appendString appends a non-empty string field
+func appendString(b []byte, num protowire.Number, v string) []byte {
+	if v == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+// stringField returns the Message field for a string field number
+func stringField(msg *Message, num protowire.Number) *string {
+	switch num {
+	case pbID:
+		return &msg.ID
+	case pbPath:
+		return &msg.Path
+	case pbFilename:
+		return &msg.Filename
+	case pbKind:
+		return &msg.Kind
+	case pbHash:
+		return &msg.Hash
+	case pbSource:
+		return &msg.Source
+	}
+	return nil
+}
+
+// decodeTimestamp parses a google.protobuf.Timestamp
+func decodeTimestamp(b []byte) (time.Time, error) {
+	var secs, nanos int64
+
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return time.Time{}, fmt.Errorf("invalid timestamp tag: %w", protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		if typ == protowire.VarintType && (num == pbSeconds || num == pbNanos) {
+			var v uint64
+			v, n = protowire.ConsumeVarint(b)
+			if num == pbSeconds {
+				secs = int64(v)
+			} else {
+				nanos = int64(int32(v))
+			}
+		} else {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+
+		if n < 0 {
+			return time.Time{}, fmt.Errorf("invalid timestamp field %d: %w", num, protowire.ParseError(n))
+		}
+		b = b[n:]
+	}
+
+	return time.Unix(secs, nanos).UTC(), nil
+}
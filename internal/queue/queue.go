@@ -2,9 +2,17 @@ package queue
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
+// ErrResultsDisabled is returned by Results when the queue has no results
+// channel configured
+var ErrResultsDisabled = errors.New("results channel not configured")
+
+// ErrClosed is returned by Publish after Close
+var ErrClosed = errors.New("queue closed")
+
 // Queue is the interface for message queue
 type Queue interface {
 	// Publish publishes a message to the queue
@@ -14,13 +22,72 @@ type Queue interface {
 	Close() error
 }
 
-// Message represents a file event message
+// ResultSource is implemented by queues that can report the outcome of
+// consumer processing back to the watcher
+type ResultSource interface {
+	// Results returns a channel of consumer results. The channel is closed
+	// when ctx is cancelled or the queue is closed.
+	Results(ctx context.Context) (<-chan Result, error)
+}
+
+// HealthChecker is implemented by queues that can report broker connectivity
+type HealthChecker interface {
+	// Check returns an error while the queue cannot publish
+	Check() error
+}
+
+// StateRecorder is implemented by queues that write the enqueued state
+// atomically with the publish; the watcher then skips its own MarkEnqueued
+type StateRecorder interface {
+	RecordsEnqueued() bool
+}
+
+// SchemaVersion is the current version of the Message schema. Adding an
+// optional field keeps the version; renaming, removing or changing the
+// meaning of a field bumps it.
+const SchemaVersion = 1
+
+// Message represents a file event message.
+// Keep schema/message.schema.json and schema/message.proto in sync.
 type Message struct {
-	ID        string    `json:"id"`
-	Path      string    `json:"path"`
-	Filename  string    `json:"filename"`
-	Kind      string    `json:"kind"`
-	Size      int64     `json:"size"`
-	Hash      string    `json:"hash"`
-	Timestamp time.Time `json:"timestamp"`
+	SchemaVersion int       `json:"schema_version"`
+	ID            string    `json:"id"`
+	Path          string    `json:"path"`
+	Filename      string    `json:"filename"`
+	Kind          string    `json:"kind"`
+	Size          int64     `json:"size"`
+	Hash          string    `json:"hash"`
+	Source        string    `json:"source,omitempty"` // watch path the file was detected in
+	Timestamp     time.Time `json:"timestamp"`
+
+	// FencingToken comes from the storage lock held while publishing.
+	// Consumers should reject a message whose token is lower than the
+	// highest one already seen for the same hash.
+	FencingToken uint64 `json:"fencing_token,omitempty"`
+}
+
+// ResultStatus is the outcome reported by a consumer
+type ResultStatus string
+
+const (
+	ResultSuccess ResultStatus = "success"
+	ResultFailure ResultStatus = "failure"
+)
+
+// Result reports the outcome of processing a published message
+type Result struct {
+	ID        string       `json:"id"`
+	Path      string       `json:"path,omitempty"`
+	Status    ResultStatus `json:"status"`
+	Reason    string       `json:"reason,omitempty"`
+	Timestamp time.Time    `json:"timestamp"`
+
+	// Ack settles the result with the broker once the watcher has applied
+	// it (nil when the source needs no acknowledgement). A result that is
+	// never acked is redelivered.
+	Ack func() `json:"-"`
+
+	// Nack returns the result to the broker for redelivery (nil when the
+	// source cannot redeliver)
+	Nack func() `json:"-"`
 }
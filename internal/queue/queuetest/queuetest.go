@@ -0,0 +1,217 @@
+// Package queuetest is a conformance suite for queue.Queue implementations.
+// A backend passes it by running
+//
+//	func TestConformance(t *testing.T) {
+//		queuetest.Run(t, func(t *testing.T) (queue.Queue, queuetest.Receiver) {
+//			q := newMyQueue(t)
+//			return q, func(ctx context.Context, n int) ([]*queue.Message, error) {
+//				return readFromBroker(ctx, n)
+//			}
+//		})
+//	}
+//
+// The queue must be configured with the default JSON encoding.
+package queuetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fabyo/gordon-watcher/internal/queue"
+)
+
+// Receiver waits until n messages were delivered by the queue under test
+// and returns them in delivery order
+type Receiver func(ctx context.Context, n int) ([]*queue.Message, error)
+
+// Factory returns an empty queue and a receiver for what it delivers. The
+// receiver is nil for queues that drop messages, such as NoOpQueue; the
+// delivery checks are skipped for them. The factory registers its own
+// cleanup; the suite closes the queue itself only in the Close test.
+type Factory func(t *testing.T) (queue.Queue, Receiver)
+
+// receiveTimeout bounds every wait for delivered messages
+const receiveTimeout = 10 * time.Second
+
+// Run runs every conformance check against queues made by factory
+func Run(t *testing.T, factory Factory) {
+	t.Run("Delivery", func(t *testing.T) { testDelivery(t, factory) })
+	t.Run("Ordering", func(t *testing.T) { testOrdering(t, factory) })
+	t.Run("Concurrent", func(t *testing.T) { testConcurrent(t, factory) })
+	t.Run("Redelivery", func(t *testing.T) { testRedelivery(t, factory) })
+	t.Run("CancelledContext", func(t *testing.T) { testCancelledContext(t, factory) })
+	t.Run("Close", func(t *testing.T) { testClose(t, factory) })
+}
+
+// NewMessage returns a message with every field set
+func NewMessage(id, hash string) *queue.Message {
+	return &queue.Message{
+		SchemaVersion: queue.SchemaVersion,
+		ID:            id,
+		Path:          "/processing/" + id + ".xml",
+		Filename:      id + ".xml",
+		Kind:          "xml",
+		Size:          2048,
+		Hash:          hash,
+		Source:        "/incoming",
+		Timestamp:     time.Now().UTC().Truncate(time.Millisecond),
+		FencingToken:  42,
+	}
+}
+
+// receive waits for n messages
+func receive(t *testing.T, r Receiver, n int) []*queue.Message {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), receiveTimeout)
+	defer cancel()
+
+	msgs, err := r(ctx, n)
+	if err != nil {
+		t.Fatalf("receive %d messages: %v", n, err)
+	}
+	if len(msgs) < n {
+		t.Fatalf("received %d messages, want %d", len(msgs), n)
+	}
+	return msgs
+}
+
+func testDelivery(t *testing.T, factory Factory) {
+	q, r := factory(t)
+	msg := NewMessage("delivery", "hash-delivery")
+
+	if err := q.Publish(context.Background(), msg); err != nil {
+		t.Fatalf("Publish() failed: %v", err)
+	}
+	if r == nil {
+		t.Skip("queue does not deliver messages")
+	}
+
+	got := receive(t, r, 1)[0]
+	if !got.Timestamp.Equal(msg.Timestamp) {
+		t.Errorf("Timestamp = %v, want %v", got.Timestamp, msg.Timestamp)
+	}
+	got.Timestamp = msg.Timestamp
+	if !reflect.DeepEqual(got, msg) {
+		t.Errorf("received %+v, want %+v", got, msg)
+	}
+}
+
+// testOrdering checks that sequential publishes for one hash arrive in
+// order, which fencing-token checks in consumers rely on
+func testOrdering(t *testing.T, factory Factory) {
+	q, r := factory(t)
+	ctx := context.Background()
+
+	const n = 20
+	want := make([]string, n)
+	for i := range want {
+		want[i] = fmt.Sprintf("ordered-%02d", i)
+		if err := q.Publish(ctx, NewMessage(want[i], "hash-ordered")); err != nil {
+			t.Fatalf("Publish(%s) failed: %v", want[i], err)
+		}
+	}
+	if r == nil {
+		t.Skip("queue does not deliver messages")
+	}
+
+	if got := ids(receive(t, r, n)); !reflect.DeepEqual(got, want) {
+		t.Errorf("delivery order = %v, want %v", got, want)
+	}
+}
+
+func testConcurrent(t *testing.T, factory Factory) {
+	q, r := factory(t)
+	ctx := context.Background()
+
+	const n = 20
+	want := make([]string, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range want {
+		want[i] = fmt.Sprintf("concurrent-%02d", i)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = q.Publish(ctx, NewMessage(want[i], want[i]))
+		}(i)
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		t.Fatalf("concurrent Publish() failed: %v", err)
+	}
+	if r == nil {
+		t.Skip("queue does not deliver messages")
+	}
+
+	got := ids(receive(t, r, n))
+	sort.Strings(got)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("delivered %v, want %v", got, want)
+	}
+}
+
+// testRedelivery publishes a message twice, as the watcher does when a
+// publish is retried. Delivery is at least once: backends may deliver both
+// copies or drop the duplicate, but both publishes must succeed.
+func testRedelivery(t *testing.T, factory Factory) {
+	q, r := factory(t)
+	ctx := context.Background()
+	msg := NewMessage("retried", "hash-retried")
+
+	for i := 0; i < 2; i++ {
+		if err := q.Publish(ctx, msg); err != nil {
+			t.Fatalf("Publish() #%d failed: %v", i+1, err)
+		}
+	}
+	if r == nil {
+		t.Skip("queue does not deliver messages")
+	}
+
+	if got := receive(t, r, 1)[0]; got.ID != msg.ID {
+		t.Errorf("received %s, want %s", got.ID, msg.ID)
+	}
+}
+
+// testCancelledContext checks that a publish with a cancelled context is
+// not reported as successful; the watcher cancels publishes when it loses
+// the file lock
+func testCancelledContext(t *testing.T, factory Factory) {
+	q, _ := factory(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := q.Publish(ctx, NewMessage("cancelled", "hash-cancelled")); err == nil {
+		t.Error("Publish() with a cancelled context succeeded")
+	}
+}
+
+func testClose(t *testing.T, factory Factory) {
+	q, _ := factory(t)
+
+	if err := q.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	if err := q.Close(); err != nil {
+		t.Errorf("second Close() failed: %v", err)
+	}
+	if err := q.Publish(context.Background(), NewMessage("closed", "hash-closed")); err == nil {
+		t.Error("Publish() after Close succeeded")
+	}
+}
+
+func ids(msgs []*queue.Message) []string {
+	out := make([]string, 0, len(msgs))
+	for _, m := range msgs {
+		out = append(out, m.ID)
+	}
+	return out
+}
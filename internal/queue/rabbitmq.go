@@ -3,7 +3,9 @@ package queue
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
@@ -21,24 +23,132 @@ type RabbitMQConfig struct {
 	RoutingKey string
 	Durable    bool
 
+	// ResultsQueue receives consumer results. Empty disables the results loop.
+	ResultsQueue string
+
+	// ConfirmTimeout bounds the wait for a publisher confirm when ctx has no deadline
+	ConfirmTimeout time.Duration
+
+	// Reconnect backoff
+	ReconnectInitialDelay time.Duration
+	ReconnectMaxDelay     time.Duration
+
 	// DLQ Configuration
 	DLQEnabled  bool
 	DLQExchange string
 	DLQQueue    string
+
+	// Routes send messages to other routing keys or exchanges; the first
+	// match wins and Exchange/RoutingKey/QueueName act as the fallback
+	Routes []Route
+
+	// Queue arguments (zero values are not sent)
+	MessageTTL time.Duration // x-message-ttl
+	MaxLength  int64         // x-max-length
+	Overflow   string        // x-overflow
+
+	// Encoder builds the body and headers of each publishing (nil = plain JSON)
+	Encoder Encoder
+}
+
+// ErrNotConnected is returned while the queue is reconnecting to the broker
+var ErrNotConnected = errors.New("not connected to RabbitMQ")
+
+// ErrNacked is returned when the broker negatively acknowledges a message
+var ErrNacked = errors.New("message nacked by broker")
+
+// ReturnError is returned when the broker could not route a mandatory message
+type ReturnError struct {
+	Exchange   string
+	RoutingKey string
+	ReplyCode  uint16
+	ReplyText  string
+}
+
+func (e *ReturnError) Error() string {
+	return fmt.Sprintf("message returned by broker (exchange=%q, routingKey=%q): %d %s",
+		e.Exchange, e.RoutingKey, e.ReplyCode, e.ReplyText)
 }
 
-// RabbitMQQueue implements Queue interface for RabbitMQ
+// Permanent reports that publishing again cannot help: nothing is bound to
+// the routing key until the topology changes
+func (e *ReturnError) Permanent() bool { return true }
+
+// RabbitMQQueue implements Queue interface for RabbitMQ.
+// A supervisor goroutine reconnects and re-declares the topology whenever
+// the connection or channel closes.
 type RabbitMQQueue struct {
-	cfg    RabbitMQConfig
-	conn   *amqp.Connection
-	ch     *amqp.Channel
-	logger *logger.Logger
+	cfg     RabbitMQConfig
+	router  *Router
+	encoder Encoder
+	logger  *logger.Logger
+
+	// dial opens broker connections (amqp.Dial; replaced in tests)
+	dial func(url string) (*amqp.Connection, error)
+
+	// Current connection state, swapped by the supervisor
+	mu        sync.RWMutex
+	conn      *amqp.Connection
+	ch        *amqp.Channel
+	returns   chan amqp.Return
+	connected chan struct{} // closed while connected, replaced on disconnect
+
+	// Returned (unroutable) messages, keyed by message ID, until the
+	// publish they belong to collects them or they age out
+	returnsMu sync.Mutex
+	returned  map[string]pendingReturn
+
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
-// NewRabbitMQQueue creates a new RabbitMQ queue
+// NewRabbitMQQueue creates a new RabbitMQ queue. If the broker is not
+// reachable yet the queue starts disconnected and keeps retrying in the
+// background; Publish returns ErrNotConnected until it succeeds.
 func NewRabbitMQQueue(cfg RabbitMQConfig, log *logger.Logger) (*RabbitMQQueue, error) {
+	if cfg.ReconnectInitialDelay <= 0 {
+		cfg.ReconnectInitialDelay = 1 * time.Second
+	}
+	if cfg.ReconnectMaxDelay <= 0 {
+		cfg.ReconnectMaxDelay = 30 * time.Second
+	}
+
+	router, err := NewRouter(cfg.Routes, Route{
+		Exchange:   cfg.Exchange,
+		RoutingKey: cfg.RoutingKey,
+		Queue:      cfg.QueueName,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid routes: %w", err)
+	}
+
+	q := &RabbitMQQueue{
+		cfg:       cfg,
+		router:    router,
+		encoder:   encoderOrDefault(cfg.Encoder),
+		logger:    log,
+		dial:      amqp.Dial,
+		connected: make(chan struct{}),
+		returned:  make(map[string]pendingReturn),
+		done:      make(chan struct{}),
+	}
+
+	closed, err := q.connect()
+	if err != nil {
+		log.Warn("RabbitMQ not available, retrying in background", "error", err)
+	}
+
+	go q.supervise(closed)
+
+	return q, nil
+}
+
+// connect dials the broker, declares the topology and swaps in the new
+// connection. It returns a channel that receives when either the
+// connection or the channel closes.
+func (q *RabbitMQQueue) connect() (<-chan *amqp.Error, error) {
 	// Connect to RabbitMQ
-	conn, err := amqp.Dial(cfg.URL)
+	conn, err := q.dial(q.cfg.URL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
 	}
@@ -50,8 +160,143 @@ func NewRabbitMQQueue(cfg RabbitMQConfig, log *logger.Logger) (*RabbitMQQueue, e
 		return nil, fmt.Errorf("failed to open channel: %w", err)
 	}
 
+	if err := q.declareTopology(ch); err != nil {
+		ch.Close()
+		conn.Close()
+		return nil, err
+	}
+
+	// Enable publisher confirms
+	if err := ch.Confirm(false); err != nil {
+		ch.Close()
+		conn.Close()
+		return nil, fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+
+	// basic.return is delivered before the matching basic.ack, so buffered
+	// returns are always visible once the confirm arrives
+	returns := ch.NotifyReturn(make(chan amqp.Return, 128))
+
+	// Closing the connection also closes the channel, so one signal is enough
+	// for both; the buffered channels never block the amqp reader
+	closed := make(chan *amqp.Error, 2)
+	conn.NotifyClose(forward(closed))
+	ch.NotifyClose(forward(closed))
+
+	// Close may have run while dialing; it holds mu, so checking done under
+	// mu guarantees it either sees this connection or we see it closed
+	q.mu.Lock()
+	select {
+	case <-q.done:
+		q.mu.Unlock()
+		ch.Close()
+		conn.Close()
+		return nil, ErrClosed
+	default:
+	}
+	q.conn = conn
+	q.ch = ch
+	q.returns = returns
+	close(q.connected)
+	q.mu.Unlock()
+
+	q.logger.Info("Connected to RabbitMQ",
+		"exchange", q.cfg.Exchange,
+		"queue", q.cfg.QueueName,
+		"routingKey", q.cfg.RoutingKey,
+	)
+
+	return closed, nil
+}
+
+// forward returns a close-notification channel that relays into dst
+func forward(dst chan<- *amqp.Error) chan *amqp.Error {
+	src := make(chan *amqp.Error, 1)
+	go func() {
+		if err, ok := <-src; ok {
+			select {
+			case dst <- err:
+			default:
+			}
+		}
+	}()
+	return src
+}
+
+// supervise waits for the connection to drop and reconnects with backoff
+func (q *RabbitMQQueue) supervise(closed <-chan *amqp.Error) {
+	for {
+		if closed != nil {
+			select {
+			case <-q.done:
+				return
+			case err := <-closed:
+				q.logger.Error("RabbitMQ connection lost", "error", err)
+				q.markDisconnected()
+			}
+		}
+
+		closed = q.reconnect()
+		if closed == nil {
+			return // Queue closed while reconnecting
+		}
+	}
+}
+
+// reconnect retries connect with exponential backoff until it succeeds or
+// the queue is closed
+func (q *RabbitMQQueue) reconnect() <-chan *amqp.Error {
+	delay := q.cfg.ReconnectInitialDelay
+
+	for {
+		select {
+		case <-q.done:
+			return nil
+		case <-time.After(delay):
+		}
+
+		closed, err := q.connect()
+		if err == nil {
+			q.logger.Info("RabbitMQ connection recovered")
+			return closed
+		}
+		if errors.Is(err, ErrClosed) {
+			return nil
+		}
+
+		q.logger.Warn("RabbitMQ reconnect failed", "error", err, "retryIn", delay)
+
+		delay *= 2
+		if delay > q.cfg.ReconnectMaxDelay {
+			delay = q.cfg.ReconnectMaxDelay
+		}
+	}
+}
+
+// markDisconnected drops the current connection so Publish fails fast
+func (q *RabbitMQQueue) markDisconnected() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.ch != nil {
+		q.ch.Close()
+	}
+	if q.conn != nil {
+		q.conn.Close()
+	}
+
+	q.ch = nil
+	q.conn = nil
+	q.returns = nil
+	q.connected = make(chan struct{})
+}
+
+// declareTopology declares exchanges, queues and bindings on ch
+func (q *RabbitMQQueue) declareTopology(ch *amqp.Channel) error {
+	cfg := q.cfg
+
 	// Declare exchange
-	err = ch.ExchangeDeclare(
+	err := ch.ExchangeDeclare(
 		cfg.Exchange, // name
 		"topic",      // type
 		cfg.Durable,  // durable
@@ -61,9 +306,7 @@ func NewRabbitMQQueue(cfg RabbitMQConfig, log *logger.Logger) (*RabbitMQQueue, e
 		nil,          // arguments
 	)
 	if err != nil {
-		ch.Close()
-		conn.Close()
-		return nil, fmt.Errorf("failed to declare exchange: %w", err)
+		return fmt.Errorf("failed to declare exchange: %w", err)
 	}
 
 	// Setup DLQ if enabled
@@ -79,9 +322,7 @@ func NewRabbitMQQueue(cfg RabbitMQConfig, log *logger.Logger) (*RabbitMQQueue, e
 			nil,             // arguments
 		)
 		if err != nil {
-			ch.Close()
-			conn.Close()
-			return nil, fmt.Errorf("failed to declare DLQ exchange: %w", err)
+			return fmt.Errorf("failed to declare DLQ exchange: %w", err)
 		}
 
 		// Declare DLQ queue
@@ -94,9 +335,7 @@ func NewRabbitMQQueue(cfg RabbitMQConfig, log *logger.Logger) (*RabbitMQQueue, e
 			nil,          // arguments
 		)
 		if err != nil {
-			ch.Close()
-			conn.Close()
-			return nil, fmt.Errorf("failed to declare DLQ queue: %w", err)
+			return fmt.Errorf("failed to declare DLQ queue: %w", err)
 		}
 
 		// Bind DLQ queue to DLQ exchange
@@ -108,12 +347,10 @@ func NewRabbitMQQueue(cfg RabbitMQConfig, log *logger.Logger) (*RabbitMQQueue, e
 			nil,             // arguments
 		)
 		if err != nil {
-			ch.Close()
-			conn.Close()
-			return nil, fmt.Errorf("failed to bind DLQ queue: %w", err)
+			return fmt.Errorf("failed to bind DLQ queue: %w", err)
 		}
 
-		log.Info("DLQ configured",
+		q.logger.Info("DLQ configured",
 			"dlqExchange", cfg.DLQExchange,
 			"dlqQueue", cfg.DLQQueue,
 		)
@@ -125,48 +362,89 @@ func NewRabbitMQQueue(cfg RabbitMQConfig, log *logger.Logger) (*RabbitMQQueue, e
 		// Route failed messages to DLQ
 		queueArgs["x-dead-letter-exchange"] = cfg.DLQExchange
 	}
-
-	// Declare main queue
-	_, err = ch.QueueDeclare(
-		cfg.QueueName, // name
-		cfg.Durable,   // durable
-		false,         // delete when unused
-		false,         // exclusive
-		false,         // no-wait
-		queueArgs,     // arguments
-	)
-	if err != nil {
-		ch.Close()
-		conn.Close()
-		return nil, fmt.Errorf("failed to declare queue: %w", err)
+	if cfg.MessageTTL > 0 {
+		queueArgs["x-message-ttl"] = cfg.MessageTTL.Milliseconds()
+	}
+	if cfg.MaxLength > 0 {
+		queueArgs["x-max-length"] = cfg.MaxLength
+	}
+	if cfg.Overflow != "" {
+		queueArgs["x-overflow"] = cfg.Overflow
 	}
 
-	// Bind queue to exchange
-	err = ch.QueueBind(
-		cfg.QueueName,  // queue name
-		cfg.RoutingKey, // routing key
-		cfg.Exchange,   // exchange
-		false,          // no-wait
-		nil,            // arguments
-	)
-	if err != nil {
-		ch.Close()
-		conn.Close()
-		return nil, fmt.Errorf("failed to bind queue: %w", err)
+	// Declare and bind the queue of every route (fallback included)
+	declaredExchanges := map[string]bool{cfg.Exchange: true}
+	declaredQueues := make(map[string]bool)
+
+	for _, route := range q.router.Routes() {
+		exchange := route.Exchange
+		if exchange == "" {
+			exchange = cfg.Exchange
+		}
+
+		if !declaredExchanges[exchange] {
+			err = ch.ExchangeDeclare(
+				exchange,    // name
+				"topic",     // type
+				cfg.Durable, // durable
+				false,       // auto-deleted
+				false,       // internal
+				false,       // no-wait
+				nil,         // arguments
+			)
+			if err != nil {
+				return fmt.Errorf("failed to declare exchange %s: %w", exchange, err)
+			}
+			declaredExchanges[exchange] = true
+		}
+
+		if route.Queue == "" {
+			continue
+		}
+
+		if !declaredQueues[route.Queue] {
+			_, err = ch.QueueDeclare(
+				route.Queue, // name
+				cfg.Durable, // durable
+				false,       // delete when unused
+				false,       // exclusive
+				false,       // no-wait
+				queueArgs,   // arguments
+			)
+			if err != nil {
+				return fmt.Errorf("failed to declare queue %s: %w", route.Queue, err)
+			}
+			declaredQueues[route.Queue] = true
+		}
+
+		err = ch.QueueBind(
+			route.Queue,      // queue name
+			route.RoutingKey, // routing key
+			exchange,         // exchange
+			false,            // no-wait
+			nil,              // arguments
+		)
+		if err != nil {
+			return fmt.Errorf("failed to bind queue %s: %w", route.Queue, err)
+		}
 	}
 
-	log.Info("Connected to RabbitMQ",
-		"exchange", cfg.Exchange,
-		"queue", cfg.QueueName,
-		"routingKey", cfg.RoutingKey,
-	)
+	// Declare results queue (consumers reply through the default exchange)
+	if cfg.ResultsQueue != "" {
+		_, err = ch.QueueDeclare(
+			cfg.ResultsQueue, // name
+			cfg.Durable,      // durable
+			false,            // delete when unused
+			false,            // exclusive
+			false,            // no-wait
+			nil,              // arguments
+		)
+		if err != nil {
+			return fmt.Errorf("failed to declare results queue: %w", err)
+		}
+	}
 
-	return &RabbitMQQueue{
-		cfg:    cfg,
-		conn:   conn,
-		ch:     ch,
-		logger: log,
-	}, nil
+	return nil
 }
 
 // Publish publishes a message to RabbitMQ
@@ -175,54 +453,312 @@ func (q *RabbitMQQueue) Publish(ctx context.Context, msg *Message) error {
 	ctx, span := tracer.Start(ctx, "rabbitmq.publish")
 	defer span.End()
 
+	route := q.router.Match(msg)
+	exchange := route.Exchange
+	if exchange == "" {
+		exchange = q.cfg.Exchange
+	}
+
 	span.SetAttributes(
 		attribute.String("message.id", msg.ID),
 		attribute.String("message.filename", msg.Filename),
 		attribute.String("message.kind", msg.Kind),
+		attribute.String("rabbitmq.exchange", exchange),
+		attribute.String("rabbitmq.routing_key", route.RoutingKey),
 	)
 
-	// Marshal message
-	body, err := json.Marshal(msg)
+	select {
+	case <-q.done:
+		return ErrClosed
+	default:
+	}
+
+	// Snapshot the current channel; the supervisor may swap it at any time
+	q.mu.RLock()
+	ch, returns := q.ch, q.returns
+	q.mu.RUnlock()
+
+	if ch == nil {
+		return ErrNotConnected
+	}
+
+	// Encode message
+	encoder := route.Encoder
+	if encoder == nil {
+		encoder = q.encoder
+	}
+	env, err := encoder.Encode(msg)
 	if err != nil {
-		return fmt.Errorf("failed to marshal message: %w", err)
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	// Bound the confirm wait when the caller did not set a deadline
+	if _, ok := ctx.Deadline(); !ok && q.cfg.ConfirmTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, q.cfg.ConfirmTimeout)
+		defer cancel()
 	}
 
-	// Publish
-	err = q.ch.PublishWithContext(
+	// Drop a basic.return left by an earlier attempt whose confirm wait
+	// timed out, so it cannot fail this one
+	q.takeReturn(returns, msg.ID)
+
+	// Publish (mandatory so unroutable messages come back as basic.return)
+	confirm, err := ch.PublishWithDeferredConfirmWithContext(
 		ctx,
-		q.cfg.Exchange,   // exchange
-		q.cfg.RoutingKey, // routing key
-		false,            // mandatory
+		exchange,         // exchange
+		route.RoutingKey, // routing key
+		true,             // mandatory
 		false,            // immediate
 		amqp.Publishing{
 			DeliveryMode: amqp.Persistent,
-			ContentType:  "application/json",
-			Body:         body,
+			ContentType:  env.ContentType,
+			Headers:      amqpHeaders(env.Headers),
+			Body:         env.Body,
 			Timestamp:    time.Now(),
 			MessageId:    msg.ID,
+			ReplyTo:      q.cfg.ResultsQueue,
 		},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to publish message: %w", err)
 	}
 
+	// Wait for the broker to confirm
+	acked, err := confirm.WaitContext(ctx)
+	ret, wasReturned := q.takeReturn(returns, msg.ID)
+	if err != nil {
+		return fmt.Errorf("failed waiting for publisher confirm: %w", err)
+	}
+
+	if wasReturned {
+		return &ReturnError{
+			Exchange:   ret.Exchange,
+			RoutingKey: ret.RoutingKey,
+			ReplyCode:  ret.ReplyCode,
+			ReplyText:  ret.ReplyText,
+		}
+	}
+
+	if !acked {
+		return ErrNacked
+	}
+
 	q.logger.Debug("Message published to RabbitMQ",
 		"messageId", msg.ID,
 		"filename", msg.Filename,
 		"path", msg.Path,
+		"exchange", exchange,
+		"routingKey", route.RoutingKey,
 	)
 
 	return nil
 }
 
-// Close closes the RabbitMQ connection
-func (q *RabbitMQQueue) Close() error {
-	if q.ch != nil {
-		q.ch.Close()
+// returnRetention bounds how long a basic.return waits for its publish to
+// collect it; publishes that are never retried would otherwise leak it
+const returnRetention = 5 * time.Minute
+
+// pendingReturn is a basic.return not yet collected by its publish
+type pendingReturn struct {
+	ret amqp.Return
+	at  time.Time
+}
+
+// takeReturn collects pending basic.return frames and removes the one for id
+func (q *RabbitMQQueue) takeReturn(returns <-chan amqp.Return, id string) (amqp.Return, bool) {
+	q.returnsMu.Lock()
+	defer q.returnsMu.Unlock()
+
+	now := time.Now()
+
+drain:
+	for {
+		select {
+		case ret, ok := <-returns:
+			if !ok {
+				break drain
+			}
+			q.returned[ret.MessageId] = pendingReturn{ret: ret, at: now}
+		default:
+			break drain
+		}
 	}
-	if q.conn != nil {
-		q.conn.Close()
+
+	for msgID, p := range q.returned {
+		if now.Sub(p.at) > returnRetention {
+			delete(q.returned, msgID)
+		}
+	}
+
+	p, ok := q.returned[id]
+	if ok {
+		delete(q.returned, id)
+	}
+	return p.ret, ok
+}
+
+// amqpHeaders converts envelope headers into an AMQP table (nil when empty)
+func amqpHeaders(headers map[string]string) amqp.Table {
+	if len(headers) == 0 {
+		return nil
 	}
+
+	table := make(amqp.Table, len(headers))
+	for k, v := range headers {
+		table[k] = v
+	}
+	return table
+}
+
+// Check reports whether the queue is currently connected to the broker
+func (q *RabbitMQQueue) Check() error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.ch == nil {
+		return ErrNotConnected
+	}
+	return nil
+}
+
+// waitConnected blocks until a connection is available
+func (q *RabbitMQQueue) waitConnected(ctx context.Context) (*amqp.Connection, error) {
+	for {
+		q.mu.RLock()
+		conn, connected := q.conn, q.connected
+		q.mu.RUnlock()
+
+		if conn != nil {
+			return conn, nil
+		}
+
+		select {
+		case <-connected:
+		case <-q.done:
+			return nil, ErrNotConnected
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Results consumes consumer results from the results queue, re-subscribing
+// after every reconnect
+func (q *RabbitMQQueue) Results(ctx context.Context) (<-chan Result, error) {
+	if q.cfg.ResultsQueue == "" {
+		return nil, ErrResultsDisabled
+	}
+
+	results := make(chan Result)
+
+	go func() {
+		defer close(results)
+
+		for {
+			conn, err := q.waitConnected(ctx)
+			if err != nil {
+				return
+			}
+
+			if err := q.consumeResults(ctx, conn, results); err != nil {
+				q.logger.Warn("Results consumer interrupted", "error", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-q.done:
+				return
+			case <-time.After(q.cfg.ReconnectInitialDelay):
+			}
+		}
+	}()
+
+	q.logger.Info("Consuming results", "queue", q.cfg.ResultsQueue)
+
+	return results, nil
+}
+
+// consumeResults forwards results until ctx is cancelled or the channel closes
+func (q *RabbitMQQueue) consumeResults(ctx context.Context, conn *amqp.Connection, results chan<- Result) error {
+	// Use a dedicated channel so consuming never interferes with publishing
+	ch, err := conn.Channel()
+	if err != nil {
+		return fmt.Errorf("failed to open results channel: %w", err)
+	}
+	defer ch.Close()
+
+	deliveries, err := ch.Consume(
+		q.cfg.ResultsQueue, // queue
+		"",                 // consumer
+		false,              // auto-ack (acked via Result.Ack)
+		false,              // exclusive
+		false,              // no-local
+		false,              // no-wait
+		nil,                // args
+	)
+	if err != nil {
+		return fmt.Errorf("failed to consume results queue: %w", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case d, ok := <-deliveries:
+			if !ok {
+				return fmt.Errorf("results channel closed")
+			}
+
+			var res Result
+			if err := json.Unmarshal(d.Body, &res); err != nil {
+				q.logger.Error("Invalid result message",
+					"messageId", d.MessageId,
+					"error", err)
+				_ = d.Nack(false, false)
+				continue
+			}
+
+			// Fall back to the correlation id when the body omits it
+			if res.ID == "" {
+				res.ID = d.CorrelationId
+			}
+
+			// Acked by the watcher once the file has moved, so a crash
+			// in between leaves the result on the queue
+			res.Ack = func() { _ = d.Ack(false) }
+			res.Nack = func() { _ = d.Nack(false, true) }
+
+			select {
+			case results <- res:
+			case <-ctx.Done():
+				_ = d.Nack(false, true)
+				return nil
+			}
+		}
+	}
+}
+
+// Close stops the supervisor and closes the RabbitMQ connection
+func (q *RabbitMQQueue) Close() error {
+	q.closeOnce.Do(func() {
+		close(q.done)
+
+		q.mu.Lock()
+		defer q.mu.Unlock()
+
+		if q.ch != nil {
+			q.ch.Close()
+		}
+		if q.conn != nil {
+			q.conn.Close()
+		}
+		q.ch = nil
+		q.conn = nil
+	})
+
 	q.logger.Info("RabbitMQ connection closed")
 	return nil
 }
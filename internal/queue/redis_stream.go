@@ -0,0 +1,232 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+
+	"github.com/fabyo/gordon-watcher/internal/logger"
+)
+
+// EnqueuedRecorder writes the enqueued state inside a Redis transaction,
+// so publishing and state tracking succeed or fail together
+type EnqueuedRecorder interface {
+	MarkEnqueuedTx(ctx context.Context, pipe redis.Pipeliner, hash, path string)
+}
+
+// RedisStreamConfig holds Redis Streams configuration
+type RedisStreamConfig struct {
+	// Stream is the default stream; Streams overrides it per Message.Kind
+	// and SourceStreams per watch path (Message.Source), before the kind
+	Stream        string
+	Streams       map[string]string
+	SourceStreams map[string]string
+
+	// MaxLen trims each stream on XADD (0 = no trimming)
+	MaxLen          int64
+	ApproximateTrim bool
+
+	// Group is created on every stream at startup (empty = skip)
+	Group string
+
+	// KeyPrefix is prepended to every stream name, so deployments sharing
+	// one Redis do not publish to each other's streams (empty = none)
+	KeyPrefix string
+
+	// Encoder builds the payload and header fields of each entry (nil =
+	// plain JSON); StreamEncoders overrides it per stream
+	Encoder        Encoder
+	StreamEncoders map[string]Encoder
+}
+
+// RedisStreamQueue implements Queue interface for Redis Streams
+type RedisStreamQueue struct {
+	cfg        RedisStreamConfig
+	client     redis.UniversalClient
+	state      EnqueuedRecorder
+	ownsClient bool
+	encoder    Encoder
+	logger     *logger.Logger
+	closed     atomic.Bool
+}
+
+// NewRedisStreamQueue creates a new Redis Streams queue on an existing client.
+// When state is not nil the enqueued state is written atomically with XADD.
+// The client is not closed by Close; the caller keeps ownership.
+func NewRedisStreamQueue(cfg RedisStreamConfig, client redis.UniversalClient, state EnqueuedRecorder, log *logger.Logger) (*RedisStreamQueue, error) {
+	q := &RedisStreamQueue{
+		cfg:     cfg,
+		client:  client,
+		state:   state,
+		encoder: encoderOrDefault(cfg.Encoder),
+		logger:  log,
+	}
+
+	if err := q.bootstrapGroups(); err != nil {
+		return nil, err
+	}
+
+	log.Info("Redis Streams queue initialized",
+		"stream", cfg.KeyPrefix+cfg.Stream,
+		"maxLen", cfg.MaxLen,
+		"group", cfg.Group,
+		"atomicState", state != nil,
+	)
+
+	return q, nil
+}
+
+// NewRedisStreamQueueWithClient creates a Redis Streams queue that takes
+// ownership of client and closes it on Close
+func NewRedisStreamQueueWithClient(cfg RedisStreamConfig, client redis.UniversalClient, log *logger.Logger) (*RedisStreamQueue, error) {
+	q, err := NewRedisStreamQueue(cfg, client, nil, log)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	q.ownsClient = true
+
+	return q, nil
+}
+
+// bootstrapGroups creates the consumer group on every configured stream
+func (q *RedisStreamQueue) bootstrapGroups() error {
+	if q.cfg.Group == "" {
+		return nil
+	}
+
+	ctx := context.Background()
+
+	for _, stream := range q.streams() {
+		err := q.client.XGroupCreateMkStream(ctx, q.cfg.KeyPrefix+stream, q.cfg.Group, "$").Err()
+		if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+			return fmt.Errorf("failed to create consumer group on %s: %w", stream, err)
+		}
+	}
+
+	return nil
+}
+
+// Publish appends a message to the stream
+func (q *RedisStreamQueue) Publish(ctx context.Context, msg *Message) error {
+	tracer := otel.Tracer("gordon-watcher")
+	ctx, span := tracer.Start(ctx, "redis.xadd")
+	defer span.End()
+
+	// A shared client stays open after Close, so check explicitly
+	if q.closed.Load() {
+		return ErrClosed
+	}
+
+	stream := q.streamFor(msg)
+
+	span.SetAttributes(
+		attribute.String("message.id", msg.ID),
+		attribute.String("message.filename", msg.Filename),
+		attribute.String("message.kind", msg.Kind),
+		attribute.String("redis.stream", q.cfg.KeyPrefix+stream),
+	)
+
+	// Encode message
+	env, err := encoderFor(q.encoder, q.cfg.StreamEncoders, stream).Encode(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	values := map[string]interface{}{
+		"id":           msg.ID,
+		"kind":         msg.Kind,
+		"content_type": env.ContentType,
+		"payload":      env.Body,
+	}
+	for k, v := range env.Headers {
+		values[k] = v
+	}
+
+	// Propagate trace context as stream fields
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	for k, v := range carrier {
+		values[k] = v
+	}
+
+	// MULTI/EXEC so the entry and the enqueued state are written together
+	_, err = q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: q.cfg.KeyPrefix + stream,
+			MaxLen: q.cfg.MaxLen,
+			Approx: q.cfg.ApproximateTrim,
+			Values: values,
+		})
+
+		if q.state != nil {
+			q.state.MarkEnqueuedTx(ctx, pipe, msg.Hash, msg.Path)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish message: %w", err)
+	}
+
+	q.logger.Debug("Message published to Redis stream",
+		"messageId", msg.ID,
+		"filename", msg.Filename,
+		"stream", q.cfg.KeyPrefix+stream,
+	)
+
+	return nil
+}
+
+// RecordsEnqueued reports whether Publish also writes the enqueued state
+func (q *RedisStreamQueue) RecordsEnqueued() bool {
+	return q.state != nil
+}
+
+// Close closes the Redis connection if the queue owns it
+func (q *RedisStreamQueue) Close() error {
+	if !q.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	if q.ownsClient {
+		if err := q.client.Close(); err != nil {
+			return fmt.Errorf("failed to close Redis connection: %w", err)
+		}
+	}
+	q.logger.Info("Redis Streams queue closed")
+	return nil
+}
+
+// streamFor returns the stream for a message source or kind
+func (q *RedisStreamQueue) streamFor(msg *Message) string {
+	if stream, ok := forSource(q.cfg.SourceStreams, msg.Source); ok && stream != "" {
+		return stream
+	}
+	if stream, ok := q.cfg.Streams[msg.Kind]; ok && stream != "" {
+		return stream
+	}
+	return q.cfg.Stream
+}
+
+// streams lists every distinct configured stream
+func (q *RedisStreamQueue) streams() []string {
+	seen := map[string]bool{q.cfg.Stream: true}
+	streams := []string{q.cfg.Stream}
+
+	for _, overrides := range []map[string]string{q.cfg.Streams, q.cfg.SourceStreams} {
+		for _, stream := range overrides {
+			if stream != "" && !seen[stream] {
+				seen[stream] = true
+				streams = append(streams, stream)
+			}
+		}
+	}
+
+	return streams
+}
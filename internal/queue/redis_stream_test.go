@@ -0,0 +1,134 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/fabyo/gordon-watcher/internal/storage"
+)
+
+// abortingRecorder records the enqueued state, then queues an invalid
+// command so Redis aborts the whole transaction on EXEC
+type abortingRecorder struct {
+	EnqueuedRecorder
+}
+
+func (r abortingRecorder) MarkEnqueuedTx(ctx context.Context, pipe redis.Pipeliner, hash, path string) {
+	r.EnqueuedRecorder.MarkEnqueuedTx(ctx, pipe, hash, path)
+	pipe.Do(ctx, "set", "too-few-args")
+}
+
+// newAtomicStreamQueue returns a Redis Streams queue sharing the client of
+// a RedisStorage used as its state store
+func newAtomicStreamQueue(t *testing.T, wrap func(EnqueuedRecorder) EnqueuedRecorder) (*RedisStreamQueue, *storage.RedisStorage) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	store, err := storage.NewRedisStorage(storage.RedisConfig{Addr: mr.Addr()})
+	if err != nil {
+		t.Fatalf("NewRedisStorage() failed: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	var state EnqueuedRecorder = store
+	if wrap != nil {
+		state = wrap(state)
+	}
+
+	q, err := NewRedisStreamQueue(RedisStreamConfig{Stream: "files"}, store.Client(), state, newTestLogger())
+	if err != nil {
+		t.Fatalf("NewRedisStreamQueue() failed: %v", err)
+	}
+	t.Cleanup(func() { _ = q.Close() })
+
+	if !q.RecordsEnqueued() {
+		t.Fatal("RecordsEnqueued() = false with a state store")
+	}
+	return q, store
+}
+
+func TestRedisStreamQueue_PublishRecordsEnqueued(t *testing.T) {
+	ctx := context.Background()
+	q, store := newAtomicStreamQueue(t, nil)
+
+	msg := &Message{ID: "h1", Hash: "h1", Filename: "a.xml", Path: "/data/processing/a.xml"}
+	if err := q.Publish(ctx, msg); err != nil {
+		t.Fatalf("Publish() failed: %v", err)
+	}
+
+	entries, err := store.Client().XRange(ctx, "files", "-", "+").Result()
+	if err != nil {
+		t.Fatalf("XRange() failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Values["id"] != "h1" {
+		t.Errorf("stream entries = %v, want one entry for h1", entries)
+	}
+
+	rec, err := store.Get(ctx, "h1")
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if rec.Status != storage.StatusEnqueued || rec.Path != msg.Path {
+		t.Errorf("record = %+v, want enqueued at %s", rec, msg.Path)
+	}
+}
+
+func TestRedisStreamQueue_FailedPublishWritesNothing(t *testing.T) {
+	ctx := context.Background()
+	q, store := newAtomicStreamQueue(t, func(state EnqueuedRecorder) EnqueuedRecorder {
+		return abortingRecorder{state}
+	})
+
+	msg := &Message{ID: "h1", Hash: "h1", Filename: "a.xml", Path: "/data/processing/a.xml"}
+	if err := q.Publish(ctx, msg); err == nil {
+		t.Fatal("Publish() succeeded although the transaction was aborted")
+	}
+
+	if n, err := store.Client().XLen(ctx, "files").Result(); err != nil || n != 0 {
+		t.Errorf("XLen() = %d, %v; want an empty stream", n, err)
+	}
+	if _, err := store.Get(ctx, "h1"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Get() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestRedisStreamQueue_KeyPrefix(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	q, err := NewRedisStreamQueueWithClient(RedisStreamConfig{
+		Stream:    "files",
+		Streams:   map[string]string{"xml": "files:xml"},
+		Group:     "consumers",
+		KeyPrefix: "site-a:",
+	}, client, newTestLogger())
+	if err != nil {
+		t.Fatalf("NewRedisStreamQueueWithClient() failed: %v", err)
+	}
+	t.Cleanup(func() { _ = q.Close() })
+
+	if err := q.Publish(ctx, &Message{ID: "h1", Kind: "xml"}); err != nil {
+		t.Fatalf("Publish() failed: %v", err)
+	}
+
+	reader := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = reader.Close() })
+
+	if n, _ := reader.XLen(ctx, "site-a:files:xml").Result(); n != 1 {
+		t.Errorf("XLen(site-a:files:xml) = %d, want 1", n)
+	}
+	for _, stream := range []string{"site-a:files", "site-a:files:xml"} {
+		groups, err := reader.XInfoGroups(ctx, stream).Result()
+		if err != nil || len(groups) != 1 || groups[0].Name != "consumers" {
+			t.Errorf("XInfoGroups(%s) = %v, %v; want the consumer group", stream, groups, err)
+		}
+	}
+	if mr.Exists("files") || mr.Exists("files:xml") {
+		t.Error("unprefixed stream was created")
+	}
+}
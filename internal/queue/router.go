@@ -0,0 +1,109 @@
+package queue
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Route maps messages to an exchange and routing key. Empty match fields
+// match any message.
+type Route struct {
+	// Match criteria
+	Kind    string // Message.Kind
+	Pattern string // glob on Message.Filename
+	Source  string // watch path the file was detected in (Message.Source)
+
+	// Target
+	Exchange   string // empty = default exchange of the queue
+	RoutingKey string
+	Queue      string // queue declared and bound to RoutingKey (empty = none)
+
+	// Encoder builds the messages sent through this route (nil = the
+	// queue's Encoder)
+	Encoder Encoder
+}
+
+// matches reports whether the route applies to msg
+func (r Route) matches(msg *Message) bool {
+	if r.Kind != "" && !strings.EqualFold(r.Kind, msg.Kind) {
+		return false
+	}
+
+	if r.Source != "" && filepath.Clean(r.Source) != filepath.Clean(msg.Source) {
+		return false
+	}
+
+	if r.Pattern != "" {
+		matched, _ := filepath.Match(r.Pattern, msg.Filename)
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// forSource returns the value configured for the watch path a message was
+// detected in (Message.Source)
+func forSource[T any](values map[string]T, source string) (T, bool) {
+	var zero T
+	if source == "" || len(values) == 0 {
+		return zero, false
+	}
+
+	if v, ok := values[source]; ok {
+		return v, true
+	}
+
+	source = filepath.Clean(source)
+	for path, v := range values {
+		if filepath.Clean(path) == source {
+			return v, true
+		}
+	}
+	return zero, false
+}
+
+// Router selects the route for a message. Rules are evaluated in order and
+// the first match wins; the fallback applies when nothing matches.
+type Router struct {
+	routes   []Route
+	fallback Route
+}
+
+// NewRouter creates a router and validates rule patterns
+func NewRouter(routes []Route, fallback Route) (*Router, error) {
+	for i, route := range routes {
+		if route.RoutingKey == "" {
+			return nil, fmt.Errorf("route %d: routing key is required", i)
+		}
+		if route.Pattern != "" {
+			if _, err := filepath.Match(route.Pattern, ""); err != nil {
+				return nil, fmt.Errorf("route %d: invalid pattern %q: %w", i, route.Pattern, err)
+			}
+		}
+	}
+
+	return &Router{
+		routes:   routes,
+		fallback: fallback,
+	}, nil
+}
+
+// Match returns the route for msg
+func (r *Router) Match(msg *Message) Route {
+	for _, route := range r.routes {
+		if route.matches(msg) {
+			return route
+		}
+	}
+	return r.fallback
+}
+
+// Routes returns every rule followed by the fallback
+func (r *Router) Routes() []Route {
+	routes := make([]Route, 0, len(r.routes)+1)
+	routes = append(routes, r.routes...)
+	return append(routes, r.fallback)
+}
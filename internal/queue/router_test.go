@@ -0,0 +1,51 @@
+package queue
+
+import "testing"
+
+func TestRouter_Match(t *testing.T) {
+	fallback := Route{RoutingKey: "gordon.file", Queue: "gordon_queue"}
+
+	router, err := NewRouter([]Route{
+		{Source: "/data/partner-a", Pattern: "NFe*.xml", Exchange: "partner_a", RoutingKey: "partner_a.nfe"},
+		{Kind: "xml", RoutingKey: "gordon.file.xml", Queue: "xml"},
+		{Pattern: "*.csv", RoutingKey: "gordon.file.csv"},
+	}, fallback)
+	if err != nil {
+		t.Fatalf("NewRouter() failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		msg     Message
+		wantKey string
+	}{
+		{"source and pattern", Message{Source: "/data/partner-a/", Filename: "NFe123.xml", Kind: "xml"}, "partner_a.nfe"},
+		{"same pattern other source", Message{Source: "/data/partner-b", Filename: "NFe123.xml", Kind: "xml"}, "gordon.file.xml"},
+		{"kind is case insensitive", Message{Filename: "a.XML", Kind: "XML"}, "gordon.file.xml"},
+		{"pattern", Message{Filename: "report.csv", Kind: "csv"}, "gordon.file.csv"},
+		{"fallback", Message{Filename: "a.json", Kind: "json"}, "gordon.file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := router.Match(&tt.msg)
+			if got.RoutingKey != tt.wantKey {
+				t.Errorf("Match() routing key = %q, want %q", got.RoutingKey, tt.wantKey)
+			}
+		})
+	}
+
+	if routes := router.Routes(); len(routes) != 4 || routes[3] != fallback {
+		t.Errorf("Routes() = %v, want 3 rules plus fallback", routes)
+	}
+}
+
+func TestNewRouter_Invalid(t *testing.T) {
+	if _, err := NewRouter([]Route{{Kind: "xml"}}, Route{}); err == nil {
+		t.Error("expected error for route without routing key")
+	}
+
+	if _, err := NewRouter([]Route{{Pattern: "[", RoutingKey: "x"}}, Route{}); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
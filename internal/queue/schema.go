@@ -0,0 +1,13 @@
+package queue
+
+import _ "embed"
+
+// MessageJSONSchema is the JSON Schema (draft 2020-12) of the JSON-encoded Message
+//
+//go:embed schema/message.schema.json
+var MessageJSONSchema []byte
+
+// MessageProto is the Protobuf definition used by ProtobufEncoder
+//
+//go:embed schema/message.proto
+var MessageProto []byte
@@ -0,0 +1,129 @@
+package queue
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// jsonFields returns the JSON names of a struct's fields and the ones
+// that are always present (no omitempty)
+func jsonFields(t reflect.Type) (all, required []string) {
+	for i := 0; i < t.NumField(); i++ {
+		name, opts, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		all = append(all, name)
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	sort.Strings(all)
+	sort.Strings(required)
+	return all, required
+}
+
+func TestMessageJSONSchema_MatchesStruct(t *testing.T) {
+	var schema struct {
+		Required   []string                   `json:"required"`
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	if err := json.Unmarshal(MessageJSONSchema, &schema); err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+
+	var properties []string
+	for name := range schema.Properties {
+		properties = append(properties, name)
+	}
+	sort.Strings(properties)
+	sort.Strings(schema.Required)
+
+	all, required := jsonFields(reflect.TypeOf(Message{}))
+	if !reflect.DeepEqual(properties, all) {
+		t.Errorf("schema properties = %v, Message fields = %v", properties, all)
+	}
+	if !reflect.DeepEqual(schema.Required, required) {
+		t.Errorf("schema required = %v, Message non-omitempty fields = %v", schema.Required, required)
+	}
+
+	var version struct {
+		Const int `json:"const"`
+	}
+	_ = json.Unmarshal(schema.Properties["schema_version"], &version)
+	if version.Const != SchemaVersion {
+		t.Errorf("schema_version const = %d, want %d", version.Const, SchemaVersion)
+	}
+}
+
+func TestMessageProto_MatchesStruct(t *testing.T) {
+	fieldRe := regexp.MustCompile(`(?m)^\s+(?:[\w.]+)\s+(\w+)\s*=\s*\d+;`)
+
+	var protoFields []string
+	for _, m := range fieldRe.FindAllSubmatch(MessageProto, -1) {
+		protoFields = append(protoFields, string(m[1]))
+	}
+	sort.Strings(protoFields)
+
+	all, _ := jsonFields(reflect.TypeOf(Message{}))
+	if !reflect.DeepEqual(protoFields, all) {
+		t.Errorf("proto fields = %v, Message fields = %v", protoFields, all)
+	}
+}
+
+func TestProtobufEncoder_RoundTrip(t *testing.T) {
+	want := &Message{
+		SchemaVersion: SchemaVersion,
+		ID:            "abc",
+		Path:          "/data/processing/nota.xml",
+		Filename:      "nota.xml",
+		Kind:          "xml",
+		Size:          1234,
+		Hash:          "abc",
+		Source:        "/data/incoming",
+		Timestamp:     time.Date(2024, 1, 2, 3, 4, 5, 678, time.UTC),
+		FencingToken:  7,
+	}
+
+	env, err := ProtobufEncoder{}.Encode(want)
+	if err != nil {
+		t.Fatalf("Encode() failed: %v", err)
+	}
+	if env.ContentType != ProtobufContentType {
+		t.Errorf("content type = %q", env.ContentType)
+	}
+
+	got, err := DecodeProtobuf(env.Body)
+	if err != nil {
+		t.Fatalf("DecodeProtobuf() failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip = %+v, want %+v", got, want)
+	}
+}
+
+func TestDecodeProtobuf_Truncated(t *testing.T) {
+	env, _ := ProtobufEncoder{}.Encode(&Message{ID: "abc", Kind: "xml"})
+	if _, err := DecodeProtobuf(env.Body[:len(env.Body)-1]); err == nil {
+		t.Error("expected error for truncated message")
+	}
+}
+
+func TestEncoderFor(t *testing.T) {
+	destinations := map[string]Encoder{"files.zip": ProtobufEncoder{}}
+
+	env, _ := encoderFor(JSONEncoder{}, destinations, "files.zip").Encode(&Message{Kind: "xml"})
+	if env.ContentType != ProtobufContentType {
+		t.Errorf("files.zip content type = %q, want protobuf", env.ContentType)
+	}
+
+	env, _ = encoderFor(JSONEncoder{}, destinations, "files.xml").Encode(&Message{Kind: "zip"})
+	if env.ContentType != "application/json" {
+		t.Errorf("files.xml content type = %q, want the JSON default", env.ContentType)
+	}
+}
@@ -0,0 +1,215 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+
+	"github.com/fabyo/gordon-watcher/internal/logger"
+)
+
+// Webhook request headers
+const (
+	HeaderWebhookSignature = "X-Gordon-Signature"
+	HeaderWebhookTimestamp = "X-Gordon-Timestamp"
+	HeaderWebhookMessageID = "X-Gordon-Message-Id"
+)
+
+// WebhookConfig holds HTTP webhook configuration
+type WebhookConfig struct {
+	// URLs receive every message; KindURLs overrides them per Message.Kind
+	// and SourceURLs per watch path (Message.Source), before the kind
+	URLs       []string
+	KindURLs   map[string][]string
+	SourceURLs map[string][]string
+
+	// Secret signs each request with HMAC-SHA256 (empty = unsigned)
+	Secret string
+
+	Timeout time.Duration
+	Headers map[string]string
+
+	// Encoder builds the request body and headers (nil = plain JSON);
+	// URLEncoders overrides it per URL
+	Encoder     Encoder
+	URLEncoders map[string]Encoder
+}
+
+// HTTPStatusError is returned when a webhook answers with a non-2xx status.
+// It is treated as retryable by the watcher's retry and circuit breaker.
+type HTTPStatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("webhook %s returned status %d", e.URL, e.StatusCode)
+}
+
+// WebhookQueue implements Queue interface by POSTing messages over HTTP
+type WebhookQueue struct {
+	cfg     WebhookConfig
+	client  *http.Client
+	encoder Encoder
+	logger  *logger.Logger
+	closed  atomic.Bool
+}
+
+// NewWebhookQueue creates a new webhook queue
+func NewWebhookQueue(cfg WebhookConfig, log *logger.Logger) (*WebhookQueue, error) {
+	if len(cfg.URLs) == 0 && len(cfg.KindURLs) == 0 && len(cfg.SourceURLs) == 0 {
+		return nil, fmt.Errorf("at least one webhook URL is required")
+	}
+
+	log.Info("Webhook queue initialized",
+		"urls", cfg.URLs,
+		"signed", cfg.Secret != "",
+		"timeout", cfg.Timeout,
+	)
+
+	return &WebhookQueue{
+		cfg:     cfg,
+		client:  &http.Client{Timeout: cfg.Timeout},
+		encoder: encoderOrDefault(cfg.Encoder),
+		logger:  log,
+	}, nil
+}
+
+// Publish POSTs the message to every URL configured for its kind. Any
+// failure fails the whole publish, so a retry re-delivers to all URLs;
+// receivers should deduplicate on the message ID header.
+func (q *WebhookQueue) Publish(ctx context.Context, msg *Message) error {
+	tracer := otel.Tracer("gordon-watcher")
+	ctx, span := tracer.Start(ctx, "webhook.publish")
+	defer span.End()
+
+	if q.closed.Load() {
+		return ErrClosed
+	}
+
+	urls := q.urlsFor(msg)
+
+	span.SetAttributes(
+		attribute.String("message.id", msg.ID),
+		attribute.String("message.filename", msg.Filename),
+		attribute.String("message.kind", msg.Kind),
+		attribute.Int("webhook.targets", len(urls)),
+	)
+
+	if len(urls) == 0 {
+		return fmt.Errorf("no webhook URL configured for kind %q", msg.Kind)
+	}
+
+	// Encode for every URL first, each receiver may take its own format
+	envs := make([]*Envelope, len(urls))
+	for i, url := range urls {
+		env, err := encoderFor(q.encoder, q.cfg.URLEncoders, url).Encode(msg)
+		if err != nil {
+			return fmt.Errorf("failed to encode message: %w", err)
+		}
+		envs[i] = env
+	}
+
+	var errs []error
+	for i, url := range urls {
+		if err := q.post(ctx, url, msg, envs[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to publish message: %w", errors.Join(errs...))
+	}
+
+	q.logger.Debug("Message posted to webhooks",
+		"messageId", msg.ID,
+		"filename", msg.Filename,
+		"targets", len(urls),
+	)
+
+	return nil
+}
+
+// post sends a single signed request
+func (q *WebhookQueue) post(ctx context.Context, url string, msg *Message, env *Envelope) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(env.Body))
+	if err != nil {
+		return fmt.Errorf("failed to create request for %s: %w", url, err)
+	}
+
+	for k, v := range q.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	for k, v := range env.Headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", env.ContentType)
+	req.Header.Set(HeaderWebhookMessageID, msg.ID)
+
+	if q.cfg.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(HeaderWebhookTimestamp, timestamp)
+		req.Header.Set(HeaderWebhookSignature, "sha256="+SignWebhook(q.cfg.Secret, timestamp, env.Body))
+	}
+
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := q.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post to %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	// Drain so the connection can be reused
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &HTTPStatusError{URL: url, StatusCode: resp.StatusCode}
+	}
+
+	return nil
+}
+
+// Close releases idle HTTP connections
+func (q *WebhookQueue) Close() error {
+	if !q.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	q.client.CloseIdleConnections()
+	q.logger.Info("Webhook queue closed")
+	return nil
+}
+
+// urlsFor returns the target URLs for a message source or kind
+func (q *WebhookQueue) urlsFor(msg *Message) []string {
+	if urls, ok := forSource(q.cfg.SourceURLs, msg.Source); ok && len(urls) > 0 {
+		return urls
+	}
+	if urls, ok := q.cfg.KindURLs[msg.Kind]; ok && len(urls) > 0 {
+		return urls
+	}
+	return q.cfg.URLs
+}
+
+// SignWebhook computes the hex HMAC-SHA256 of "<timestamp>.<body>".
+// Receivers recompute it to authenticate requests and reject replays.
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
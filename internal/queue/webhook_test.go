@@ -0,0 +1,141 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fabyo/gordon-watcher/internal/logger"
+)
+
+func newTestLogger() *logger.Logger {
+	return logger.New(logger.Config{Level: "error", Format: "text", Output: "stdout"})
+}
+
+func TestWebhookQueue_PublishSigned(t *testing.T) {
+	var gotSignature, gotTimestamp, gotID string
+	var gotBody []byte
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSignature = r.Header.Get(HeaderWebhookSignature)
+		gotTimestamp = r.Header.Get(HeaderWebhookTimestamp)
+		gotID = r.Header.Get(HeaderWebhookMessageID)
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	q, err := NewWebhookQueue(WebhookConfig{
+		URLs:    []string{server.URL},
+		Secret:  "s3cret",
+		Timeout: time.Second,
+	}, newTestLogger())
+	if err != nil {
+		t.Fatalf("NewWebhookQueue() failed: %v", err)
+	}
+	defer q.Close()
+
+	msg := &Message{ID: "abc", Filename: "a.xml", Kind: "xml"}
+	if err := q.Publish(context.Background(), msg); err != nil {
+		t.Fatalf("Publish() failed: %v", err)
+	}
+
+	if gotID != "abc" {
+		t.Errorf("message id header = %q, want %q", gotID, "abc")
+	}
+
+	want := "sha256=" + SignWebhook("s3cret", gotTimestamp, gotBody)
+	if gotSignature != want {
+		t.Errorf("signature = %q, want %q", gotSignature, want)
+	}
+}
+
+func TestWebhookQueue_Non2xxIsError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	q, err := NewWebhookQueue(WebhookConfig{URLs: []string{server.URL}, Timeout: time.Second}, newTestLogger())
+	if err != nil {
+		t.Fatalf("NewWebhookQueue() failed: %v", err)
+	}
+	defer q.Close()
+
+	err = q.Publish(context.Background(), &Message{ID: "abc", Kind: "xml"})
+
+	var statusErr *HTTPStatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("Publish() error = %v, want HTTPStatusError", err)
+	}
+	if statusErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", statusErr.StatusCode, http.StatusServiceUnavailable)
+	}
+}
+
+func TestWebhookQueue_KindURLs(t *testing.T) {
+	var defaultHits, xmlHits int
+
+	defaultServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defaultHits++
+	}))
+	defer defaultServer.Close()
+
+	xmlServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		xmlHits++
+	}))
+	defer xmlServer.Close()
+
+	q, err := NewWebhookQueue(WebhookConfig{
+		URLs:     []string{defaultServer.URL},
+		KindURLs: map[string][]string{"xml": {xmlServer.URL}},
+		Timeout:  time.Second,
+	}, newTestLogger())
+	if err != nil {
+		t.Fatalf("NewWebhookQueue() failed: %v", err)
+	}
+	defer q.Close()
+
+	_ = q.Publish(context.Background(), &Message{ID: "1", Kind: "xml"})
+	_ = q.Publish(context.Background(), &Message{ID: "2", Kind: "json"})
+
+	if xmlHits != 1 || defaultHits != 1 {
+		t.Errorf("hits xml=%d default=%d, want 1 and 1", xmlHits, defaultHits)
+	}
+}
+
+func TestWebhookQueue_SourceURLs(t *testing.T) {
+	var kindHits, partnerHits int
+
+	kindServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		kindHits++
+	}))
+	defer kindServer.Close()
+
+	partnerServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		partnerHits++
+	}))
+	defer partnerServer.Close()
+
+	q, err := NewWebhookQueue(WebhookConfig{
+		KindURLs:   map[string][]string{"xml": {kindServer.URL}},
+		SourceURLs: map[string][]string{"/data/partner-a": {partnerServer.URL}},
+		Timeout:    time.Second,
+	}, newTestLogger())
+	if err != nil {
+		t.Fatalf("NewWebhookQueue() failed: %v", err)
+	}
+	defer q.Close()
+
+	// The source wins over the kind, with or without a trailing slash
+	_ = q.Publish(context.Background(), &Message{ID: "1", Kind: "xml", Source: "/data/partner-a/"})
+	_ = q.Publish(context.Background(), &Message{ID: "2", Kind: "xml", Source: "/data/partner-b"})
+
+	if partnerHits != 1 || kindHits != 1 {
+		t.Errorf("hits partner=%d kind=%d, want 1 and 1", partnerHits, kindHits)
+	}
+}
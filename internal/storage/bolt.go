@@ -0,0 +1,548 @@
+package storage
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	bucketProcessed = []byte("processed")
+	bucketEnqueued  = []byte("enqueued")
+	bucketFailed    = []byte("failed")
+	bucketFencing   = []byte("fencing")
+)
+
+// BoltConfig configures the embedded bbolt storage
+type BoltConfig struct {
+	Path string
+
+	// CompactInterval is how often expired records are purged (default 10m)
+	CompactInterval time.Duration
+
+	TTL TTLConfig
+}
+
+// BoltStorage implements Storage on an embedded bbolt file. It is meant for
+// single-node installs: state survives restarts, locks are in-process only.
+type BoltStorage struct {
+	db  *bolt.DB
+	ttl TTLConfig
+
+	// In-process locks, same semantics as MemoryStorage
+	mu    sync.Mutex
+	locks map[string]*boltLock
+
+	done      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// NewBoltStorage opens (or creates) the bbolt file and starts the
+// background compaction job
+func NewBoltStorage(cfg BoltConfig) (*BoltStorage, error) {
+	if cfg.CompactInterval <= 0 {
+		cfg.CompactInterval = 10 * time.Minute
+	}
+
+	// Storage is initialized before the watcher creates its directories
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create bolt directory: %w", err)
+	}
+
+	// The timeout fails fast when another process holds the file lock
+	db, err := bolt.Open(cfg.Path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bolt database %s: %w", cfg.Path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketProcessed, bucketEnqueued, bucketFailed, bucketFencing} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create bolt buckets: %w", err)
+	}
+
+	s := &BoltStorage{
+		db:    db,
+		ttl:   cfg.TTL.withDefaults(),
+		locks: make(map[string]*boltLock),
+		done:  make(chan struct{}),
+	}
+
+	s.wg.Add(1)
+	go s.compactLoop(cfg.CompactInterval)
+
+	return s, nil
+}
+
+// IsProcessed checks if a file hash has been processed
+func (s *BoltStorage) IsProcessed(ctx context.Context, hash string) (bool, error) {
+	var processed bool
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		_, processed = getRecord(tx.Bucket(bucketProcessed), hash, time.Now())
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to check if processed: %w", err)
+	}
+
+	return processed, nil
+}
+
+// MarkEnqueued marks a file as enqueued
+func (s *BoltStorage) MarkEnqueued(ctx context.Context, hash, path string) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return putState(tx.Bucket(bucketEnqueued), hash, boltState{Path: path}, s.ttl.Enqueued)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mark as enqueued: %w", err)
+	}
+
+	return nil
+}
+
+// MarkProcessed marks a file as processed
+func (s *BoltStorage) MarkProcessed(ctx context.Context, hash string) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		state := boltState{Path: lastPath(tx, hash)}
+		if err := putState(tx.Bucket(bucketProcessed), hash, state, s.ttl.Processed); err != nil {
+			return err
+		}
+		return tx.Bucket(bucketEnqueued).Delete([]byte(hash))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mark as processed: %w", err)
+	}
+
+	return nil
+}
+
+// MarkFailed marks a file as failed
+func (s *BoltStorage) MarkFailed(ctx context.Context, hash, reason string) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		state := boltState{Path: lastPath(tx, hash), Reason: reason}
+		if err := putState(tx.Bucket(bucketFailed), hash, state, s.ttl.Failed); err != nil {
+			return err
+		}
+		return tx.Bucket(bucketEnqueued).Delete([]byte(hash))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mark as failed: %w", err)
+	}
+
+	return nil
+}
+
+// Get returns the current record for a hash
+func (s *BoltStorage) Get(ctx context.Context, hash string) (*Record, error) {
+	var record *Record
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		record = currentRecord(tx, hash, time.Now())
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get record: %w", err)
+	}
+	if record == nil {
+		return nil, ErrNotFound
+	}
+
+	return record, nil
+}
+
+// SearchByFilename returns records whose filename starts with prefix
+func (s *BoltStorage) SearchByFilename(ctx context.Context, prefix string, limit int) ([]Record, error) {
+	records, err := s.records()
+	if err != nil {
+		return nil, fmt.Errorf("failed to search by filename: %w", err)
+	}
+	return searchFilename(records, prefix, limit), nil
+}
+
+// List returns a page of records
+func (s *BoltStorage) List(ctx context.Context, opts ListOptions) (*Page, error) {
+	records, err := s.records()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list records: %w", err)
+	}
+	return paginate(records, opts)
+}
+
+// Count returns the number of records with a status (empty = all)
+func (s *BoltStorage) Count(ctx context.Context, status string) (int64, error) {
+	records, err := s.records()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count records: %w", err)
+	}
+
+	var n int64
+	for _, r := range records {
+		if status == "" || r.Status == status {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// records returns the current record of every unexpired hash. Single-node
+// installs are small enough to scan.
+func (s *BoltStorage) records() ([]Record, error) {
+	var records []Record
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		now := time.Now()
+		seen := make(map[string]bool)
+
+		for _, name := range [][]byte{bucketEnqueued, bucketProcessed, bucketFailed} {
+			err := tx.Bucket(name).ForEach(func(k, _ []byte) error {
+				hash := string(k)
+				if seen[hash] {
+					return nil
+				}
+				seen[hash] = true
+				if record := currentRecord(tx, hash, now); record != nil {
+					records = append(records, *record)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return records, err
+}
+
+// CountEntries returns the number of records per bucket, including expired
+// ones not yet compacted, and the locks held by this process
+func (s *BoltStorage) CountEntries(ctx context.Context) (map[string]int64, error) {
+	counts := make(map[string]int64)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		for status, name := range map[string][]byte{
+			StatusEnqueued:  bucketEnqueued,
+			StatusProcessed: bucketProcessed,
+			StatusFailed:    bucketFailed,
+		} {
+			counts[status] = int64(tx.Bucket(name).Stats().KeyN)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count entries: %w", err)
+	}
+
+	s.mu.Lock()
+	counts["locks"] = int64(len(s.locks))
+	s.mu.Unlock()
+
+	return counts, nil
+}
+
+// Import writes records keeping their timestamps and expiry
+func (s *BoltStorage) Import(ctx context.Context, records []Record) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		for _, r := range records {
+			var bucket []byte
+			switch r.Status {
+			case StatusEnqueued:
+				bucket = bucketEnqueued
+			case StatusProcessed:
+				bucket = bucketProcessed
+			case StatusFailed:
+				bucket = bucketFailed
+			default:
+				return fmt.Errorf("unknown status %q for %s", r.Status, r.Hash)
+			}
+
+			state := boltState{Path: r.Path, Reason: r.Reason, UpdatedAt: r.UpdatedAt}
+			if err := writeState(tx.Bucket(bucket), r.Hash, state, r.ExpiresAt); err != nil {
+				return err
+			}
+			if r.Status != StatusEnqueued {
+				if err := tx.Bucket(bucketEnqueued).Delete([]byte(r.Hash)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to import records: %w", err)
+	}
+
+	return nil
+}
+
+// MaxFencingToken returns the last fencing token handed out
+func (s *BoltStorage) MaxFencingToken(ctx context.Context) (uint64, error) {
+	var token uint64
+	err := s.db.View(func(tx *bolt.Tx) error {
+		token = tx.Bucket(bucketFencing).Sequence()
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to read fencing token: %w", err)
+	}
+	return token, nil
+}
+
+// RaiseFencingToken makes later fencing tokens greater than token
+func (s *BoltStorage) RaiseFencingToken(ctx context.Context, token uint64) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketFencing)
+		if b.Sequence() >= token {
+			return nil
+		}
+		return b.SetSequence(token)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to raise fencing token: %w", err)
+	}
+	return nil
+}
+
+// GetLock acquires an in-process lock
+func (s *BoltStorage) GetLock(ctx context.Context, hash string) (Lock, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.locks[hash]; exists {
+		return nil, fmt.Errorf("lock already held")
+	}
+
+	// The bucket sequence is persisted, so tokens keep growing across restarts
+	var token uint64
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		token, err = tx.Bucket(bucketFencing).NextSequence()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+
+	lock := &boltLock{
+		storage:    s,
+		hash:       hash,
+		token:      token,
+		lostSignal: newLostSignal(),
+	}
+
+	s.locks[hash] = lock
+	return lock, nil
+}
+
+// Close stops the compaction job and closes the database
+func (s *BoltStorage) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.wg.Wait()
+		err = s.db.Close()
+	})
+	return err
+}
+
+// compactLoop purges expired records until Close
+func (s *BoltStorage) compactLoop(interval time.Duration) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			_, _ = s.compact(time.Now())
+		}
+	}
+}
+
+// compact deletes every record that expired before now. bbolt reuses the
+// freed pages, so the file stops growing once the working set is stable.
+func (s *BoltStorage) compact(now time.Time) (int, error) {
+	removed := 0
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketProcessed, bucketEnqueued, bucketFailed} {
+			b := tx.Bucket(name)
+
+			// Collect first: deleting while iterating skips keys
+			var keys [][]byte
+			_ = b.ForEach(func(k, v []byte) error {
+				if expired(v, now) {
+					keys = append(keys, append([]byte(nil), k...))
+				}
+				return nil
+			})
+
+			for _, k := range keys {
+				if err := b.Delete(k); err != nil {
+					return err
+				}
+			}
+			removed += len(keys)
+		}
+		return nil
+	})
+
+	return removed, err
+}
+
+// boltState is the JSON value stored under each status bucket
+type boltState struct {
+	Path      string    `json:"path,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// putState stores a state with an expiry, stamped with the current time
+func putState(b *bolt.Bucket, hash string, state boltState, ttl time.Duration) error {
+	state.UpdatedAt = time.Now()
+	return writeState(b, hash, state, state.UpdatedAt.Add(ttl))
+}
+
+// writeState stores a state as-is with an absolute expiry
+func writeState(b *bolt.Bucket, hash string, state boltState, expiresAt time.Time) error {
+	value, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return putRecord(b, hash, string(value), expiresAt)
+}
+
+// decodeState parses a stored value
+func decodeState(value []byte) boltState {
+	var state boltState
+	_ = json.Unmarshal(value, &state)
+	return state
+}
+
+// currentRecord returns the most recently updated unexpired status of a
+// hash, or nil
+func currentRecord(tx *bolt.Tx, hash string, now time.Time) *Record {
+	var record *Record
+
+	for _, st := range []struct {
+		status string
+		bucket []byte
+	}{
+		{StatusEnqueued, bucketEnqueued},
+		{StatusProcessed, bucketProcessed},
+		{StatusFailed, bucketFailed},
+	} {
+		v := tx.Bucket(st.bucket).Get([]byte(hash))
+		if v == nil || expired(v, now) {
+			continue
+		}
+
+		state := decodeState(v[8:])
+		if record != nil && !state.UpdatedAt.After(record.UpdatedAt) {
+			continue
+		}
+		record = &Record{
+			Hash:      hash,
+			Status:    st.status,
+			Path:      state.Path,
+			Filename:  filenameOf(state.Path),
+			Reason:    state.Reason,
+			UpdatedAt: state.UpdatedAt,
+			ExpiresAt: time.Unix(0, int64(binary.BigEndian.Uint64(v))),
+		}
+	}
+
+	return record
+}
+
+// lastPath returns the path stored under any status of a hash
+func lastPath(tx *bolt.Tx, hash string) string {
+	for _, st := range []struct {
+		status string
+		bucket []byte
+	}{
+		{StatusEnqueued, bucketEnqueued},
+		{StatusFailed, bucketFailed},
+		{StatusProcessed, bucketProcessed},
+	} {
+		if v := tx.Bucket(st.bucket).Get([]byte(hash)); len(v) >= 8 {
+			if path := decodeState(v[8:]).Path; path != "" {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+// putRecord stores value with an expiry. Records are an 8-byte big-endian
+// expiry (unix nanoseconds) followed by the value.
+func putRecord(b *bolt.Bucket, key, value string, expiresAt time.Time) error {
+	buf := make([]byte, 8+len(value))
+	binary.BigEndian.PutUint64(buf, uint64(expiresAt.UnixNano()))
+	copy(buf[8:], value)
+	return b.Put([]byte(key), buf)
+}
+
+// getRecord returns the value of an unexpired record
+func getRecord(b *bolt.Bucket, key string, now time.Time) (string, bool) {
+	v := b.Get([]byte(key))
+	if v == nil || expired(v, now) {
+		return "", false
+	}
+	return string(v[8:]), true
+}
+
+// expired reports whether a record's expiry is at or before now
+func expired(v []byte, now time.Time) bool {
+	if len(v) < 8 {
+		return true
+	}
+	return int64(binary.BigEndian.Uint64(v)) <= now.UnixNano()
+}
+
+// boltLock implements Lock for bolt storage. It has no lease, so it is
+// never lost.
+type boltLock struct {
+	storage *BoltStorage
+	hash    string
+	token   uint64
+
+	*lostSignal
+}
+
+// Token returns the fencing token
+func (l *boltLock) Token() uint64 {
+	return l.token
+}
+
+// Release releases the lock
+func (l *boltLock) Release(ctx context.Context) error {
+	l.storage.mu.Lock()
+	defer l.storage.mu.Unlock()
+
+	// A repeated Release must not drop a later holder's lock
+	if l.storage.locks[l.hash] == l {
+		delete(l.storage.locks, l.hash)
+	}
+	return nil
+}
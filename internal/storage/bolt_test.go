@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestBolt(t *testing.T, path string) *BoltStorage {
+	t.Helper()
+
+	s, err := NewBoltStorage(BoltConfig{Path: path})
+	if err != nil {
+		t.Fatalf("NewBoltStorage() failed: %v", err)
+	}
+	return s
+}
+
+func TestBoltStorage_SurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "state.db")
+
+	s := newTestBolt(t, path)
+	if err := s.MarkEnqueued(ctx, "h1", "/processing/a.xml"); err != nil {
+		t.Fatalf("MarkEnqueued() failed: %v", err)
+	}
+	if err := s.MarkProcessed(ctx, "h1"); err != nil {
+		t.Fatalf("MarkProcessed() failed: %v", err)
+	}
+
+	lock, _ := s.GetLock(ctx, "h1")
+	before := lock.Token()
+	_ = lock.Release(ctx)
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	s = newTestBolt(t, path)
+	defer s.Close()
+
+	processed, err := s.IsProcessed(ctx, "h1")
+	if err != nil || !processed {
+		t.Errorf("IsProcessed() after restart = %v, %v; want true", processed, err)
+	}
+
+	lock, _ = s.GetLock(ctx, "h1")
+	if lock.Token() <= before {
+		t.Errorf("fencing token after restart = %d, want > %d", lock.Token(), before)
+	}
+}
+
+func TestBoltStorage_PathsKeptVerbatim(t *testing.T) {
+	ctx := context.Background()
+	s := newTestBolt(t, filepath.Join(t.TempDir(), "state.db"))
+	defer s.Close()
+
+	// Paths are stored inside the JSON record, whatever they look like
+	path := "{batch}.xml"
+	if err := s.MarkEnqueued(ctx, "h1", path); err != nil {
+		t.Fatalf("MarkEnqueued() failed: %v", err)
+	}
+
+	record, err := s.Get(ctx, "h1")
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if record.Path != path || record.Filename != "{batch}.xml" {
+		t.Errorf("record = %+v, want path %s", record, path)
+	}
+}
+
+func TestBoltStorage_CompactRemovesExpired(t *testing.T) {
+	ctx := context.Background()
+	s := newTestBolt(t, filepath.Join(t.TempDir(), "state.db"))
+	defer s.Close()
+
+	_ = s.MarkEnqueued(ctx, "h1", "/processing/a.xml")
+	_ = s.MarkFailed(ctx, "h2", "boom")
+	_ = s.MarkProcessed(ctx, "h3")
+
+	// Enqueued records expire after defaultTTL, the others after processedTTL
+	removed, err := s.compact(time.Now().Add(defaultTTL + time.Minute))
+	if err != nil {
+		t.Fatalf("compact() failed: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("compact() removed %d records, want 1", removed)
+	}
+
+	removed, _ = s.compact(time.Now().Add(processedTTL + time.Minute))
+	if removed != 2 {
+		t.Errorf("compact() removed %d records, want 2", removed)
+	}
+}
+
+func TestBoltStorage_Lock(t *testing.T) {
+	ctx := context.Background()
+	s := newTestBolt(t, filepath.Join(t.TempDir(), "state.db"))
+	defer s.Close()
+
+	lock, err := s.GetLock(ctx, "h1")
+	if err != nil {
+		t.Fatalf("GetLock() failed: %v", err)
+	}
+	if _, err := s.GetLock(ctx, "h1"); err == nil {
+		t.Error("second GetLock() should fail while the lock is held")
+	}
+
+	_ = lock.Release(ctx)
+	if _, err := s.GetLock(ctx, "h1"); err != nil {
+		t.Errorf("GetLock() after Release failed: %v", err)
+	}
+}
@@ -0,0 +1,23 @@
+package storage
+
+import "sync"
+
+// lostSignal backs Lock.Lost
+type lostSignal struct {
+	ch   chan struct{}
+	once sync.Once
+}
+
+func newLostSignal() *lostSignal {
+	return &lostSignal{ch: make(chan struct{})}
+}
+
+// Lost returns the channel closed by markLost
+func (s *lostSignal) Lost() <-chan struct{} {
+	return s.ch
+}
+
+// markLost closes the channel; safe to call more than once
+func (s *lostSignal) markLost() {
+	s.once.Do(func() { close(s.ch) })
+}
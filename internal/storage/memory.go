@@ -7,24 +7,64 @@ import (
 	"time"
 )
 
+// MemoryConfig configures in-memory storage
+type MemoryConfig struct {
+	TTL TTLConfig
+
+	// JanitorInterval is how often expired entries are evicted (default 1m)
+	JanitorInterval time.Duration
+}
+
+// memoryEntry is the state of a hash under one status
+type memoryEntry struct {
+	path      string
+	reason    string
+	updatedAt time.Time
+	expiresAt time.Time
+}
+
 // MemoryStorage implements Storage using in-memory maps
 type MemoryStorage struct {
-	mu sync.RWMutex
+	mu  sync.RWMutex
+	ttl TTLConfig
 
-	processed map[string]time.Time
-	enqueued  map[string]string
-	failed    map[string]string
+	processed map[string]memoryEntry
+	enqueued  map[string]memoryEntry
+	failed    map[string]memoryEntry
 	locks     map[string]*memoryLock
+
+	fencingToken uint64
+
+	done      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
 }
 
-// NewMemoryStorage creates a new in-memory storage
+// NewMemoryStorage creates a new in-memory storage with the default TTLs
 func NewMemoryStorage() *MemoryStorage {
-	return &MemoryStorage{
-		processed: make(map[string]time.Time),
-		enqueued:  make(map[string]string),
-		failed:    make(map[string]string),
+	return NewMemoryStorageWithConfig(MemoryConfig{})
+}
+
+// NewMemoryStorageWithConfig creates a new in-memory storage and starts the
+// janitor that evicts expired entries
+func NewMemoryStorageWithConfig(cfg MemoryConfig) *MemoryStorage {
+	if cfg.JanitorInterval <= 0 {
+		cfg.JanitorInterval = time.Minute
+	}
+
+	s := &MemoryStorage{
+		ttl:       cfg.TTL.withDefaults(),
+		processed: make(map[string]memoryEntry),
+		enqueued:  make(map[string]memoryEntry),
+		failed:    make(map[string]memoryEntry),
 		locks:     make(map[string]*memoryLock),
+		done:      make(chan struct{}),
 	}
+
+	s.wg.Add(1)
+	go s.janitor(cfg.JanitorInterval)
+
+	return s
 }
 
 // IsProcessed checks if a file hash has been processed
@@ -32,8 +72,8 @@ func (s *MemoryStorage) IsProcessed(ctx context.Context, hash string) (bool, err
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	_, exists := s.processed[hash]
-	return exists, nil
+	entry, exists := s.processed[hash]
+	return exists && time.Now().Before(entry.expiresAt), nil
 }
 
 // MarkEnqueued marks a file as enqueued
@@ -41,7 +81,8 @@ func (s *MemoryStorage) MarkEnqueued(ctx context.Context, hash, path string) err
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.enqueued[hash] = path
+	now := time.Now()
+	s.enqueued[hash] = memoryEntry{path: path, updatedAt: now, expiresAt: now.Add(s.ttl.Enqueued)}
 	return nil
 }
 
@@ -50,7 +91,8 @@ func (s *MemoryStorage) MarkProcessed(ctx context.Context, hash string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.processed[hash] = time.Now()
+	now := time.Now()
+	s.processed[hash] = memoryEntry{path: s.pathOf(hash), updatedAt: now, expiresAt: now.Add(s.ttl.Processed)}
 	delete(s.enqueued, hash)
 
 	return nil
@@ -61,12 +103,64 @@ func (s *MemoryStorage) MarkFailed(ctx context.Context, hash, reason string) err
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.failed[hash] = reason
+	now := time.Now()
+	s.failed[hash] = memoryEntry{path: s.pathOf(hash), reason: reason, updatedAt: now, expiresAt: now.Add(s.ttl.Failed)}
 	delete(s.enqueued, hash)
 
 	return nil
 }
 
+// pathOf returns the last known path of a hash. Callers hold s.mu.
+func (s *MemoryStorage) pathOf(hash string) string {
+	if entry, ok := s.enqueued[hash]; ok {
+		return entry.path
+	}
+	if entry, ok := s.failed[hash]; ok {
+		return entry.path
+	}
+	return s.processed[hash].path
+}
+
+// Import writes records keeping their timestamps and expiry
+func (s *MemoryStorage) Import(ctx context.Context, records []Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, r := range records {
+		entry := memoryEntry{path: r.Path, reason: r.Reason, updatedAt: r.UpdatedAt, expiresAt: r.ExpiresAt}
+
+		switch r.Status {
+		case StatusEnqueued:
+			s.enqueued[r.Hash] = entry
+		case StatusProcessed:
+			s.processed[r.Hash] = entry
+			delete(s.enqueued, r.Hash)
+		case StatusFailed:
+			s.failed[r.Hash] = entry
+			delete(s.enqueued, r.Hash)
+		default:
+			return fmt.Errorf("unknown status %q for %s", r.Status, r.Hash)
+		}
+	}
+
+	return nil
+}
+
+// MaxFencingToken returns the last fencing token handed out
+func (s *MemoryStorage) MaxFencingToken(ctx context.Context) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fencingToken, nil
+}
+
+// RaiseFencingToken makes later fencing tokens greater than token
+func (s *MemoryStorage) RaiseFencingToken(ctx context.Context, token uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fencingToken = max(s.fencingToken, token)
+	return nil
+}
+
 // GetLock acquires a lock
 func (s *MemoryStorage) GetLock(ctx context.Context, hash string) (Lock, error) {
 	s.mu.Lock()
@@ -76,31 +170,239 @@ func (s *MemoryStorage) GetLock(ctx context.Context, hash string) (Lock, error)
 		return nil, fmt.Errorf("lock already held")
 	}
 
+	// Seeded from the clock in microseconds like the Redis backend, so
+	// tokens keep growing across restarts
+	s.fencingToken = max(s.fencingToken+1, uint64(time.Now().UnixMicro()))
+
 	lock := &memoryLock{
-		storage: s,
-		hash:    hash,
+		storage:    s,
+		hash:       hash,
+		token:      s.fencingToken,
+		acquiredAt: time.Now(),
+		lostSignal: newLostSignal(),
+		stop:       make(chan struct{}),
+		stopped:    make(chan struct{}),
 	}
+	go lock.keepAlive(s.ttl.Lock / 3)
 
 	s.locks[hash] = lock
 	return lock, nil
 }
 
-// Close does nothing for memory storage
+// Get returns the current record for a hash
+func (s *MemoryStorage) Get(ctx context.Context, hash string) (*Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, ok := s.current(hash, time.Now())
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &record, nil
+}
+
+// SearchByFilename returns records whose filename starts with prefix
+func (s *MemoryStorage) SearchByFilename(ctx context.Context, prefix string, limit int) ([]Record, error) {
+	return searchFilename(s.records(), prefix, limit), nil
+}
+
+// List returns a page of records
+func (s *MemoryStorage) List(ctx context.Context, opts ListOptions) (*Page, error) {
+	return paginate(s.records(), opts)
+}
+
+// Count returns the number of records with a status (empty = all)
+func (s *MemoryStorage) Count(ctx context.Context, status string) (int64, error) {
+	var n int64
+	for _, r := range s.records() {
+		if status == "" || r.Status == status {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// records returns the current record of every unexpired hash
+func (s *MemoryStorage) records() []Record {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	seen := make(map[string]bool)
+	var records []Record
+
+	for _, entries := range []map[string]memoryEntry{s.enqueued, s.processed, s.failed} {
+		for hash := range entries {
+			if seen[hash] {
+				continue
+			}
+			seen[hash] = true
+			if record, ok := s.current(hash, now); ok {
+				records = append(records, record)
+			}
+		}
+	}
+
+	return records
+}
+
+// current returns the most recently updated unexpired status of a hash.
+// Callers hold s.mu.
+func (s *MemoryStorage) current(hash string, now time.Time) (Record, bool) {
+	var record Record
+	found := false
+
+	for _, st := range []struct {
+		status  string
+		entries map[string]memoryEntry
+	}{
+		{StatusEnqueued, s.enqueued},
+		{StatusProcessed, s.processed},
+		{StatusFailed, s.failed},
+	} {
+		entry, ok := st.entries[hash]
+		if !ok || !now.Before(entry.expiresAt) {
+			continue
+		}
+		if found && !entry.updatedAt.After(record.UpdatedAt) {
+			continue
+		}
+		record = Record{
+			Hash:      hash,
+			Status:    st.status,
+			Path:      entry.path,
+			Filename:  filenameOf(entry.path),
+			Reason:    entry.reason,
+			UpdatedAt: entry.updatedAt,
+			ExpiresAt: entry.expiresAt,
+		}
+		found = true
+	}
+
+	return record, found
+}
+
+// CountEntries returns the number of stored entries per status
+func (s *MemoryStorage) CountEntries(ctx context.Context) (map[string]int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return map[string]int64{
+		StatusEnqueued:  int64(len(s.enqueued)),
+		StatusProcessed: int64(len(s.processed)),
+		StatusFailed:    int64(len(s.failed)),
+		"locks":         int64(len(s.locks)),
+	}, nil
+}
+
+// Close stops the janitor
 func (s *MemoryStorage) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.wg.Wait()
+	})
 	return nil
 }
 
-// memoryLock implements Lock for in-memory storage
+// janitor evicts expired entries until Close
+func (s *MemoryStorage) janitor(interval time.Duration) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.evict(time.Now())
+		}
+	}
+}
+
+// evict removes entries that expired before now. Locks not renewed within
+// the lock TTL are dropped and reported lost, like an expired Redis lease.
+func (s *MemoryStorage) evict(now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+	for _, entries := range []map[string]memoryEntry{s.processed, s.enqueued, s.failed} {
+		for key, entry := range entries {
+			if !now.Before(entry.expiresAt) {
+				delete(entries, key)
+				removed++
+			}
+		}
+	}
+
+	for hash, lock := range s.locks {
+		if now.Sub(lock.acquiredAt) >= s.ttl.Lock {
+			delete(s.locks, hash)
+			lock.markLost()
+			removed++
+		}
+	}
+
+	return removed
+}
+
+// memoryLock implements Lock for in-memory storage. Its lease is renewed
+// until Release; it is lost only when the janitor evicts it because
+// renewal stalled for the whole lock TTL.
 type memoryLock struct {
-	storage *MemoryStorage
-	hash    string
+	storage    *MemoryStorage
+	hash       string
+	token      uint64
+	acquiredAt time.Time // last renewal, guarded by storage.mu
+
+	*lostSignal
+
+	stop     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+// Token returns the fencing token
+func (l *memoryLock) Token() uint64 {
+	return l.token
 }
 
-// Release releases the lock
+// keepAlive renews the lease until Release or eviction
+func (l *memoryLock) keepAlive(interval time.Duration) {
+	defer close(l.stopped)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-l.Lost():
+			return
+		case now := <-ticker.C:
+			l.storage.mu.Lock()
+			if l.storage.locks[l.hash] == l {
+				l.acquiredAt = now
+			}
+			l.storage.mu.Unlock()
+		}
+	}
+}
+
+// Release stops the renewal and releases the lock
 func (l *memoryLock) Release(ctx context.Context) error {
+	l.stopOnce.Do(func() { close(l.stop) })
+	<-l.stopped
+
 	l.storage.mu.Lock()
 	defer l.storage.mu.Unlock()
 
-	delete(l.storage.locks, l.hash)
+	// The janitor may have evicted us and another holder taken the hash
+	if l.storage.locks[l.hash] == l {
+		delete(l.storage.locks, l.hash)
+	}
 	return nil
 }
@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStorage_EvictsExpiredEntries(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStorageWithConfig(MemoryConfig{
+		TTL: TTLConfig{Enqueued: time.Minute, Processed: time.Hour, Failed: 2 * time.Hour},
+	})
+	defer s.Close()
+
+	_ = s.MarkEnqueued(ctx, "e1", "/tmp/e1")
+	_ = s.MarkProcessed(ctx, "p1")
+	_ = s.MarkFailed(ctx, "f1", "boom")
+
+	if removed := s.evict(time.Now().Add(2 * time.Minute)); removed != 1 {
+		t.Errorf("evict() after enqueued TTL removed %d, want 1", removed)
+	}
+	if removed := s.evict(time.Now().Add(90 * time.Minute)); removed != 1 {
+		t.Errorf("evict() after processed TTL removed %d, want 1", removed)
+	}
+
+	counts, _ := s.CountEntries(ctx)
+	if counts[StatusEnqueued] != 0 || counts[StatusProcessed] != 0 || counts[StatusFailed] != 1 {
+		t.Errorf("CountEntries() = %v, want only the failed entry left", counts)
+	}
+}
+
+func TestMemoryStorage_ProcessedExpires(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStorageWithConfig(MemoryConfig{TTL: TTLConfig{Processed: 10 * time.Millisecond}})
+	defer s.Close()
+
+	_ = s.MarkProcessed(ctx, "h1")
+	if processed, _ := s.IsProcessed(ctx, "h1"); !processed {
+		t.Fatal("IsProcessed() = false right after MarkProcessed")
+	}
+
+	// Expired entries are ignored even before the janitor runs
+	time.Sleep(20 * time.Millisecond)
+	if processed, _ := s.IsProcessed(ctx, "h1"); processed {
+		t.Error("IsProcessed() = true after the TTL")
+	}
+}
+
+func TestMemoryStorage_RenewsHeldLocks(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStorageWithConfig(MemoryConfig{
+		TTL:             TTLConfig{Lock: 90 * time.Millisecond},
+		JanitorInterval: 10 * time.Millisecond,
+	})
+	defer s.Close()
+
+	lock, err := s.GetLock(ctx, "h1")
+	if err != nil {
+		t.Fatalf("GetLock() failed: %v", err)
+	}
+
+	// A slow publish holds the lock for several TTLs
+	select {
+	case <-lock.Lost():
+		t.Fatal("held lock reported lost")
+	case <-time.After(400 * time.Millisecond):
+	}
+
+	if _, err := s.GetLock(ctx, "h1"); err == nil {
+		t.Error("GetLock() succeeded while the lock is held")
+	}
+
+	_ = lock.Release(ctx)
+	if other, err := s.GetLock(ctx, "h1"); err != nil {
+		t.Errorf("GetLock() after Release failed: %v", err)
+	} else {
+		_ = other.Release(ctx)
+	}
+}
+
+func TestMemoryStorage_EvictsStaleLocks(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStorageWithConfig(MemoryConfig{TTL: TTLConfig{Lock: time.Second}})
+	defer s.Close()
+
+	stale, err := s.GetLock(ctx, "h1")
+	if err != nil {
+		t.Fatalf("GetLock() failed: %v", err)
+	}
+
+	s.evict(time.Now().Add(2 * time.Second))
+
+	select {
+	case <-stale.Lost():
+	default:
+		t.Error("evicted lock not reported lost")
+	}
+
+	fresh, err := s.GetLock(ctx, "h1")
+	if err != nil {
+		t.Fatalf("GetLock() after eviction failed: %v", err)
+	}
+
+	// Releasing the stale lock must not drop the new holder's
+	_ = stale.Release(ctx)
+	if _, err := s.GetLock(ctx, "h1"); err == nil {
+		t.Error("GetLock() succeeded while the new holder still owns the lock")
+	}
+	_ = fresh.Release(ctx)
+}
+
+func TestMemoryStorage_TokensGrowAcrossRestarts(t *testing.T) {
+	ctx := context.Background()
+
+	token := func() uint64 {
+		s := NewMemoryStorage()
+		defer s.Close()
+
+		lock, err := s.GetLock(ctx, "h1")
+		if err != nil {
+			t.Fatalf("GetLock() failed: %v", err)
+		}
+		defer lock.Release(ctx)
+		return lock.Token()
+	}
+
+	// A restarted process must not hand out lower tokens than before
+	first := token()
+	if second := token(); second <= first {
+		t.Errorf("token after restart = %d, want > %d", second, first)
+	}
+}
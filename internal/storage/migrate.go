@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Importer writes records as they are, keeping their timestamps and expiry.
+// A record replaces whatever the destination holds for its hash under the
+// same status.
+type Importer interface {
+	Import(ctx context.Context, records []Record) error
+}
+
+// Backfiller is implemented by storages that may hold state written before
+// records existed. Backfill writes the missing records and returns how many
+// per status; a dry run only counts them.
+type Backfiller interface {
+	Backfill(ctx context.Context, dryRun bool) (map[string]int64, error)
+}
+
+// FencingCounter is implemented by storages whose fencing tokens Migrate
+// carries over. MaxFencingToken returns the highest token handed out so far;
+// RaiseFencingToken makes every later token greater than token.
+type FencingCounter interface {
+	MaxFencingToken(ctx context.Context) (uint64, error)
+	RaiseFencingToken(ctx context.Context, token uint64) error
+}
+
+// MigrateOptions configures Migrate
+type MigrateOptions struct {
+	// BatchSize is the number of records read and written at a time
+	// (default DefaultListLimit)
+	BatchSize int
+
+	// DryRun counts the records without writing them
+	DryRun bool
+
+	// Resume continues a previous run from its last reported progress
+	Resume MigrateProgress
+
+	// OnBatch is called after every batch, e.g. to persist the progress.
+	// Returning an error stops the migration.
+	OnBatch func(MigrateProgress) error
+}
+
+// MigrateProgress is the position and totals of a migration
+type MigrateProgress struct {
+	// Cursor resumes after the last migrated record (empty = start)
+	Cursor string `json:"cursor,omitempty"`
+
+	// Counts are the records migrated (or, in a dry run, found) per status
+	Counts map[string]int64 `json:"counts"`
+
+	// Skipped are records that expired before they could be written
+	Skipped int64 `json:"skipped"`
+
+	// FencingToken is the floor the destination's tokens now exceed (in a
+	// dry run, the one they would)
+	FencingToken uint64 `json:"fencing_token,omitempty"`
+}
+
+// Total returns the number of records migrated
+func (p MigrateProgress) Total() int64 {
+	var n int64
+	for _, c := range p.Counts {
+		n += c
+	}
+	return n
+}
+
+// Migrate copies every unexpired record from one storage to another, newest
+// first. Locks are not copied, but the destination's fencing tokens are
+// raised above the source's so consumers do not reject new messages as
+// stale. Records updated after the migration passed them are missed, so the
+// watcher should be stopped while it runs. A source that is a Backfiller is
+// backfilled first; a dry run only counts its legacy state.
+func Migrate(ctx context.Context, from Querier, to Importer, opts MigrateOptions) (MigrateProgress, error) {
+	progress := opts.Resume
+	counts := make(map[string]int64, len(progress.Counts))
+	for status, n := range progress.Counts {
+		counts[status] = n
+	}
+	progress.Counts = counts
+
+	if b, ok := from.(Backfiller); ok {
+		legacy, err := b.Backfill(ctx, opts.DryRun)
+		if err != nil {
+			return progress, err
+		}
+		// Backfilled records are listed below; counted ones are not
+		if opts.DryRun {
+			for status, n := range legacy {
+				progress.Counts[status] += n
+			}
+		}
+	}
+
+	token, err := fencingTokenFloor(ctx, from)
+	if err != nil {
+		return progress, err
+	}
+	progress.FencingToken = token
+
+	if !opts.DryRun {
+		counter, ok := to.(FencingCounter)
+		if !ok {
+			return progress, fmt.Errorf("destination cannot carry over fencing token %d", token)
+		}
+		if err := counter.RaiseFencingToken(ctx, token); err != nil {
+			return progress, fmt.Errorf("failed to carry over fencing tokens: %w", err)
+		}
+	}
+
+	for {
+		page, err := from.List(ctx, ListOptions{Limit: opts.BatchSize, Cursor: progress.Cursor})
+		if err != nil {
+			return progress, fmt.Errorf("failed to read records: %w", err)
+		}
+
+		// Drop records that expire before they reach the destination
+		now := time.Now()
+		batch := make([]Record, 0, len(page.Records))
+		for _, r := range page.Records {
+			if !r.ExpiresAt.After(now) {
+				progress.Skipped++
+				continue
+			}
+			batch = append(batch, r)
+		}
+
+		if !opts.DryRun && len(batch) > 0 {
+			if err := to.Import(ctx, batch); err != nil {
+				return progress, fmt.Errorf("failed to write records: %w", err)
+			}
+		}
+
+		for _, r := range batch {
+			progress.Counts[r.Status]++
+		}
+		progress.Cursor = page.NextCursor
+
+		if opts.OnBatch != nil {
+			if err := opts.OnBatch(progress); err != nil {
+				return progress, err
+			}
+		}
+
+		if page.NextCursor == "" {
+			return progress, nil
+		}
+	}
+}
+
+// fencingClockMargin covers clock skew between the hosts whose clocks
+// fencing tokens derive from: the source's, this one's and the destination's
+const fencingClockMargin = time.Minute
+
+// fencingTokenFloor returns the floor for the destination's fencing tokens:
+// the highest token the source handed out, but no less than this host's
+// clock in microseconds plus fencingClockMargin. Memory and Redis tokens
+// derive from a clock, so the floor also holds when the source does not
+// report its tokens (memory storage read through the API) or its clock is
+// ahead of the destination's.
+func fencingTokenFloor(ctx context.Context, from Querier) (uint64, error) {
+	floor := uint64(time.Now().Add(fencingClockMargin).UnixMicro())
+
+	counter, ok := from.(FencingCounter)
+	if !ok {
+		return floor, nil
+	}
+
+	token, err := counter.MaxFencingToken(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read fencing tokens: %w", err)
+	}
+	return max(token, floor), nil
+}
@@ -0,0 +1,297 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func seedMemory(t *testing.T) *MemoryStorage {
+	t.Helper()
+
+	ctx := context.Background()
+	s := NewMemoryStorage()
+	t.Cleanup(func() { _ = s.Close() })
+
+	_ = s.MarkEnqueued(ctx, "h1", "/processing/a.xml")
+	_ = s.MarkEnqueued(ctx, "h2", "/processing/b.xml")
+	_ = s.MarkProcessed(ctx, "h2")
+	_ = s.MarkEnqueued(ctx, "h3", "/processing/c.xml")
+	_ = s.MarkFailed(ctx, "h3", "queue_error")
+	return s
+}
+
+func TestMigrate_KeepsRecords(t *testing.T) {
+	ctx := context.Background()
+
+	targets := map[string]func(t *testing.T) Storage{
+		"memory": func(t *testing.T) Storage { return NewMemoryStorage() },
+		"bolt": func(t *testing.T) Storage {
+			return newTestBolt(t, filepath.Join(t.TempDir(), "state.db"))
+		},
+		"sqlite": func(t *testing.T) Storage { return newTestSQLite(t) },
+		"redis": func(t *testing.T) Storage {
+			s, _ := newTestRedis(t)
+			return s
+		},
+	}
+
+	for name, newTarget := range targets {
+		t.Run(name, func(t *testing.T) {
+			source := seedMemory(t)
+			target := newTarget(t)
+			defer target.Close()
+
+			progress, err := Migrate(ctx, source, target.(Importer), MigrateOptions{BatchSize: 2})
+			if err != nil {
+				t.Fatalf("Migrate() failed: %v", err)
+			}
+			if progress.Total() != 3 || progress.Cursor != "" {
+				t.Errorf("progress = %+v, want 3 records and no cursor", progress)
+			}
+
+			for _, hash := range []string{"h1", "h2", "h3"} {
+				want, _ := source.Get(ctx, hash)
+				got, err := target.Get(ctx, hash)
+				if err != nil {
+					t.Fatalf("Get(%s) failed: %v", hash, err)
+				}
+				if got.Status != want.Status || got.Path != want.Path || got.Reason != want.Reason {
+					t.Errorf("Get(%s) = %+v, want %+v", hash, got, want)
+				}
+				if got.ExpiresAt.Sub(want.ExpiresAt).Abs() > time.Millisecond {
+					t.Errorf("Get(%s) expires at %v, want %v", hash, got.ExpiresAt, want.ExpiresAt)
+				}
+			}
+
+			if processed, _ := target.IsProcessed(ctx, "h2"); !processed {
+				t.Error("h2 not processed in target")
+			}
+		})
+	}
+}
+
+func TestMigrate_DryRun(t *testing.T) {
+	source := seedMemory(t)
+
+	progress, err := Migrate(context.Background(), source, nil, MigrateOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Migrate() failed: %v", err)
+	}
+	if progress.Counts[StatusEnqueued] != 1 || progress.Counts[StatusProcessed] != 1 || progress.Counts[StatusFailed] != 1 {
+		t.Errorf("counts = %v, want one per status", progress.Counts)
+	}
+}
+
+func TestMigrate_Resume(t *testing.T) {
+	ctx := context.Background()
+	source := seedMemory(t)
+	target := NewMemoryStorage()
+	defer target.Close()
+
+	// Stop after the first batch, as if interrupted
+	errStop := errors.New("stop")
+	saved, err := Migrate(ctx, source, target, MigrateOptions{
+		BatchSize: 2,
+		OnBatch:   func(MigrateProgress) error { return errStop },
+	})
+	if !errors.Is(err, errStop) || saved.Total() != 2 {
+		t.Fatalf("first run = %+v, %v", saved, err)
+	}
+
+	progress, err := Migrate(ctx, source, target, MigrateOptions{BatchSize: 2, Resume: saved})
+	if err != nil {
+		t.Fatalf("resumed Migrate() failed: %v", err)
+	}
+	if progress.Total() != 3 {
+		t.Errorf("total = %d, want 3", progress.Total())
+	}
+	if n, _ := target.Count(ctx, ""); n != 3 {
+		t.Errorf("target has %d records, want 3", n)
+	}
+}
+
+func TestMigrate_RedisLegacyKeys(t *testing.T) {
+	ctx := context.Background()
+	source, mr := newTestRedis(t)
+	target := NewMemoryStorage()
+	defer target.Close()
+
+	// State written before records existed
+	_ = mr.Set("gordon:watcher:processed:h1", "1700000000")
+	mr.SetTTL("gordon:watcher:processed:h1", time.Hour)
+	_ = mr.Set("gordon:watcher:failed:h2", "1700000000:queue_error")
+	mr.SetTTL("gordon:watcher:failed:h2", time.Hour)
+	_ = mr.Set("gordon:watcher:enqueued:h3", "/processing/c.xml")
+	mr.SetTTL("gordon:watcher:enqueued:h3", time.Hour)
+	_ = source.MarkProcessed(ctx, "h4")
+
+	progress, err := Migrate(ctx, source, target, MigrateOptions{})
+	if err != nil {
+		t.Fatalf("Migrate() failed: %v", err)
+	}
+	if progress.Total() != 4 {
+		t.Errorf("total = %d, want 4", progress.Total())
+	}
+
+	failed, err := target.Get(ctx, "h2")
+	if err != nil {
+		t.Fatalf("Get(h2) failed: %v", err)
+	}
+	if failed.Status != StatusFailed || failed.Reason != "queue_error" || !failed.UpdatedAt.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("h2 = %+v", failed)
+	}
+	if until := time.Until(failed.ExpiresAt); until <= 59*time.Minute || until > time.Hour {
+		t.Errorf("h2 expires in %v, want the key's remaining TTL", until)
+	}
+
+	enqueued, err := target.Get(ctx, "h3")
+	if err != nil {
+		t.Fatalf("Get(h3) failed: %v", err)
+	}
+	if enqueued.Path != "/processing/c.xml" || enqueued.Filename != "c.xml" {
+		t.Errorf("h3 = %+v", enqueued)
+	}
+	if processed, _ := target.IsProcessed(ctx, "h1"); !processed {
+		t.Error("h1 not processed in target")
+	}
+}
+
+func TestMigrate_DryRunLeavesRedisUntouched(t *testing.T) {
+	ctx := context.Background()
+	source, mr := newTestRedis(t)
+
+	// Legacy state, one hash under two statuses
+	_ = mr.Set("gordon:watcher:processed:h1", "1700000000")
+	_ = mr.Set("gordon:watcher:enqueued:h1", "/processing/a.xml")
+	_ = mr.Set("gordon:watcher:failed:h2", "1700000000:queue_error")
+	_ = source.MarkProcessed(ctx, "h3")
+	keys := len(mr.Keys())
+
+	progress, err := Migrate(ctx, source, nil, MigrateOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Migrate() failed: %v", err)
+	}
+	if progress.Counts[StatusProcessed] != 2 || progress.Counts[StatusFailed] != 1 || progress.Counts[StatusEnqueued] != 0 {
+		t.Errorf("counts = %v, want legacy keys counted once per hash", progress.Counts)
+	}
+	if n := len(mr.Keys()); n != keys {
+		t.Errorf("dry run left %d keys, want %d", n, keys)
+	}
+}
+
+func TestMigrate_CarriesFencingTokens(t *testing.T) {
+	ctx := context.Background()
+
+	sources := map[string]func(t *testing.T) Storage{
+		"redis": func(t *testing.T) Storage {
+			s, _ := newTestRedis(t)
+			return s
+		},
+		"memory": func(t *testing.T) Storage { return NewMemoryStorage() },
+		"sqlite": func(t *testing.T) Storage { return newTestSQLite(t) },
+	}
+	targets := map[string]func(t *testing.T) Storage{
+		"bolt": func(t *testing.T) Storage {
+			return newTestBolt(t, filepath.Join(t.TempDir(), "state.db"))
+		},
+		"sqlite": func(t *testing.T) Storage { return newTestSQLite(t) },
+		"redis": func(t *testing.T) Storage {
+			s, _ := newTestRedis(t)
+			return s
+		},
+	}
+
+	for from, newSource := range sources {
+		for to, newTarget := range targets {
+			t.Run(from+"->"+to, func(t *testing.T) {
+				source := newSource(t)
+				defer source.Close()
+				target := newTarget(t)
+				defer target.Close()
+
+				lock, err := source.GetLock(ctx, "h1")
+				if err != nil {
+					t.Fatalf("GetLock() failed: %v", err)
+				}
+				_ = lock.Release(ctx)
+
+				if _, err := Migrate(ctx, source.(Querier), target.(Importer), MigrateOptions{}); err != nil {
+					t.Fatalf("Migrate() failed: %v", err)
+				}
+
+				next, err := target.GetLock(ctx, "h1")
+				if err != nil {
+					t.Fatalf("GetLock() on target failed: %v", err)
+				}
+				defer next.Release(ctx)
+				if next.Token() <= lock.Token() {
+					t.Errorf("target token = %d, want > source token %d", next.Token(), lock.Token())
+				}
+			})
+		}
+	}
+}
+
+// tokenHidingQuerier hides the FencingCounter of a source, like the API
+// client reading memory storage
+type tokenHidingQuerier struct {
+	Querier
+}
+
+func TestMigrate_FencingTokensWhenDestinationClockIsBehind(t *testing.T) {
+	ctx := context.Background()
+
+	// Source tokens derive from a clock running 30s ahead
+	source := NewMemoryStorage()
+	defer source.Close()
+	_ = source.RaiseFencingToken(ctx, uint64(time.Now().Add(30*time.Second).UnixMicro()))
+	lock, err := source.GetLock(ctx, "h1")
+	if err != nil {
+		t.Fatalf("GetLock() failed: %v", err)
+	}
+	_ = lock.Release(ctx)
+
+	sources := map[string]Querier{
+		"reported": source,
+		"hidden":   tokenHidingQuerier{source},
+	}
+
+	for name, from := range sources {
+		t.Run(name, func(t *testing.T) {
+			// Destination Redis clock an hour behind
+			target, mr := newTestRedis(t)
+			mr.SetTime(time.Now().Add(-time.Hour))
+
+			if _, err := Migrate(ctx, from, target, MigrateOptions{}); err != nil {
+				t.Fatalf("Migrate() failed: %v", err)
+			}
+
+			next, err := target.GetLock(ctx, "h2")
+			if err != nil {
+				t.Fatalf("GetLock() on target failed: %v", err)
+			}
+			defer next.Release(ctx)
+			if next.Token() <= lock.Token() {
+				t.Errorf("target token = %d, want > source token %d", next.Token(), lock.Token())
+			}
+
+			// A restarted instance reads the floor back
+			reopened, err := NewRedisStorage(RedisConfig{Addr: mr.Addr()})
+			if err != nil {
+				t.Fatalf("NewRedisStorage() failed: %v", err)
+			}
+			defer reopened.Close()
+			again, err := reopened.GetLock(ctx, "h3")
+			if err != nil {
+				t.Fatalf("GetLock() after restart failed: %v", err)
+			}
+			defer again.Release(ctx)
+			if again.Token() <= lock.Token() {
+				t.Errorf("token after restart = %d, want > source token %d", again.Token(), lock.Token())
+			}
+		})
+	}
+}
@@ -0,0 +1,15 @@
+-- File state, one row per content hash
+CREATE TABLE IF NOT EXISTS gordon_files (
+    hash         TEXT PRIMARY KEY,
+    status       TEXT NOT NULL CHECK (status IN ('enqueued', 'processed', 'failed')),
+    path         TEXT NOT NULL DEFAULT '',
+    reason       TEXT NOT NULL DEFAULT '',
+    enqueued_at  TIMESTAMPTZ,
+    processed_at TIMESTAMPTZ,
+    failed_at    TIMESTAMPTZ,
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at   TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS gordon_files_status_idx ON gordon_files (status);
+CREATE INDEX IF NOT EXISTS gordon_files_expires_at_idx ON gordon_files (expires_at);
@@ -0,0 +1,2 @@
+-- Monotonic lock fencing tokens
+CREATE SEQUENCE IF NOT EXISTS gordon_fencing_token_seq;
@@ -0,0 +1,7 @@
+-- Base name of path, for filename searches
+ALTER TABLE gordon_files ADD COLUMN IF NOT EXISTS filename TEXT NOT NULL DEFAULT '';
+
+UPDATE gordon_files SET filename = regexp_replace(path, '^.*/', '') WHERE filename = '';
+
+CREATE INDEX IF NOT EXISTS gordon_files_filename_idx ON gordon_files (filename COLLATE "C", hash);
+CREATE INDEX IF NOT EXISTS gordon_files_updated_at_idx ON gordon_files (updated_at, hash);
@@ -0,0 +1,26 @@
+-- Current state, one row per content hash
+CREATE TABLE IF NOT EXISTS files (
+    hash       TEXT PRIMARY KEY,
+    status     TEXT NOT NULL,
+    path       TEXT NOT NULL DEFAULT '',
+    reason     TEXT NOT NULL DEFAULT '',
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+    expires_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS files_status_idx ON files (status);
+CREATE INDEX IF NOT EXISTS files_expires_at_idx ON files (expires_at);
+
+-- Every status change; hash is NULL for files ignored before hashing
+CREATE TABLE IF NOT EXISTS file_events (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    hash       TEXT,
+    status     TEXT NOT NULL CHECK (status IN ('detected', 'enqueued', 'processed', 'failed', 'ignored')),
+    path       TEXT NOT NULL DEFAULT '',
+    reason     TEXT NOT NULL DEFAULT '',
+    created_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS file_events_hash_idx ON file_events (hash, id);
+CREATE INDEX IF NOT EXISTS file_events_created_at_idx ON file_events (created_at);
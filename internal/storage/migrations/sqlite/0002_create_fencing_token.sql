@@ -0,0 +1,7 @@
+-- Single-row counter for lock fencing tokens
+CREATE TABLE IF NOT EXISTS fencing_token (
+    id    INTEGER PRIMARY KEY CHECK (id = 1),
+    value INTEGER NOT NULL
+);
+
+INSERT OR IGNORE INTO fencing_token (id, value) VALUES (1, 0);
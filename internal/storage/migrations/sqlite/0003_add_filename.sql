@@ -0,0 +1,8 @@
+-- Base name of path, for filename searches
+ALTER TABLE files ADD COLUMN filename TEXT NOT NULL DEFAULT '';
+
+-- rtrim strips every trailing character that is not '/', leaving the directory
+UPDATE files SET filename = replace(path, rtrim(path, replace(path, '/', '')), '');
+
+CREATE INDEX IF NOT EXISTS files_filename_idx ON files (filename, hash);
+CREATE INDEX IF NOT EXISTS files_updated_at_idx ON files (updated_at, hash);
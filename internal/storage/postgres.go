@@ -0,0 +1,598 @@
+package storage
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed migrations/postgres/*.sql
+var postgresMigrations embed.FS
+
+// migrationLockKey serializes migrations across instances starting together
+const migrationLockKey = 0x676f72646f6e // "gordon"
+
+// PostgresConfig configures Postgres storage
+type PostgresConfig struct {
+	DSN string
+
+	// MaxConns bounds the pool. Every held lock pins one connection, so it
+	// must exceed the number of workers.
+	MaxConns int32
+
+	// RetentionInterval is how often expired rows are deleted (default 10m)
+	RetentionInterval time.Duration
+
+	// TTL sets the row expiry per status; Lock sets the session ping interval
+	TTL TTLConfig
+}
+
+// PostgresStorage implements Storage on a Postgres table. Rows keep the
+// status history of each file until they expire, like the Redis keys.
+type PostgresStorage struct {
+	pool *pgxpool.Pool
+	ttl  TTLConfig
+
+	done      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// NewPostgresStorage connects, applies pending migrations and starts the
+// retention job
+func NewPostgresStorage(cfg PostgresConfig) (*PostgresStorage, error) {
+	if cfg.RetentionInterval <= 0 {
+		cfg.RetentionInterval = 10 * time.Minute
+	}
+
+	poolCfg, err := pgxpool.ParseConfig(cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Postgres DSN: %w", err)
+	}
+	if cfg.MaxConns > 0 {
+		poolCfg.MaxConns = cfg.MaxConns
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Postgres: %w", err)
+	}
+
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect to Postgres: %w", err)
+	}
+
+	if err := migratePostgres(ctx, pool); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	s := &PostgresStorage{
+		pool: pool,
+		ttl:  cfg.TTL.withDefaults(),
+		done: make(chan struct{}),
+	}
+
+	s.wg.Add(1)
+	go s.retentionLoop(cfg.RetentionInterval)
+
+	return s, nil
+}
+
+// IsProcessed checks if a file hash has been processed
+func (s *PostgresStorage) IsProcessed(ctx context.Context, hash string) (bool, error) {
+	var processed bool
+
+	err := s.pool.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM gordon_files
+			WHERE hash = $1 AND status = 'processed' AND expires_at > now()
+		)`, hash).Scan(&processed)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if processed: %w", err)
+	}
+
+	return processed, nil
+}
+
+// MarkEnqueued marks a file as enqueued
+func (s *PostgresStorage) MarkEnqueued(ctx context.Context, hash, path string) error {
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO gordon_files (hash, status, path, filename, enqueued_at, updated_at, expires_at)
+		VALUES ($1, 'enqueued', $2, $4, now(), now(), now() + $3 * interval '1 millisecond')
+		ON CONFLICT (hash) DO UPDATE SET
+			status      = 'enqueued',
+			path        = EXCLUDED.path,
+			filename    = EXCLUDED.filename,
+			reason      = '',
+			enqueued_at = EXCLUDED.enqueued_at,
+			updated_at  = EXCLUDED.updated_at,
+			expires_at  = EXCLUDED.expires_at`,
+		hash, path, s.ttl.Enqueued.Milliseconds(), filenameOf(path))
+	if err != nil {
+		return fmt.Errorf("failed to mark as enqueued: %w", err)
+	}
+
+	return nil
+}
+
+// MarkProcessed marks a file as processed
+func (s *PostgresStorage) MarkProcessed(ctx context.Context, hash string) error {
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO gordon_files (hash, status, processed_at, updated_at, expires_at)
+		VALUES ($1, 'processed', now(), now(), now() + $2 * interval '1 millisecond')
+		ON CONFLICT (hash) DO UPDATE SET
+			status       = 'processed',
+			reason       = '',
+			processed_at = EXCLUDED.processed_at,
+			updated_at   = EXCLUDED.updated_at,
+			expires_at   = EXCLUDED.expires_at`,
+		hash, s.ttl.Processed.Milliseconds())
+	if err != nil {
+		return fmt.Errorf("failed to mark as processed: %w", err)
+	}
+
+	return nil
+}
+
+// MarkFailed marks a file as failed
+func (s *PostgresStorage) MarkFailed(ctx context.Context, hash, reason string) error {
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO gordon_files (hash, status, reason, failed_at, updated_at, expires_at)
+		VALUES ($1, 'failed', $2, now(), now(), now() + $3 * interval '1 millisecond')
+		ON CONFLICT (hash) DO UPDATE SET
+			status     = 'failed',
+			reason     = EXCLUDED.reason,
+			failed_at  = EXCLUDED.failed_at,
+			updated_at = EXCLUDED.updated_at,
+			expires_at = EXCLUDED.expires_at`,
+		hash, reason, s.ttl.Failed.Milliseconds())
+	if err != nil {
+		return fmt.Errorf("failed to mark as failed: %w", err)
+	}
+
+	return nil
+}
+
+// postgresRecordColumns is the column list scanned by scanPostgresRecord
+const postgresRecordColumns = "hash, status, path, filename, reason, updated_at, expires_at"
+
+// Get returns the current record for a hash
+func (s *PostgresStorage) Get(ctx context.Context, hash string) (*Record, error) {
+	row := s.pool.QueryRow(ctx,
+		"SELECT "+postgresRecordColumns+" FROM gordon_files WHERE hash = $1 AND expires_at > now()", hash)
+
+	record, err := scanPostgresRecord(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get record: %w", err)
+	}
+	return record, nil
+}
+
+// SearchByFilename returns records whose filename starts with prefix
+func (s *PostgresStorage) SearchByFilename(ctx context.Context, prefix string, limit int) ([]Record, error) {
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+
+	// COLLATE "C" orders byte-wise, like the other backends
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+postgresRecordColumns+` FROM gordon_files
+		WHERE starts_with(filename, $1) AND filename != '' AND expires_at > now()
+		ORDER BY filename COLLATE "C", hash COLLATE "C"
+		LIMIT $2`, prefix, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search by filename: %w", err)
+	}
+
+	records, err := scanPostgresRecords(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search by filename: %w", err)
+	}
+	return records, nil
+}
+
+// List returns a page of records
+func (s *PostgresStorage) List(ctx context.Context, opts ListOptions) (*Page, error) {
+	cursor, err := decodeCursor(opts.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	query := "SELECT " + postgresRecordColumns + " FROM gordon_files WHERE expires_at > now()"
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if opts.Status != "" {
+		query += " AND status = " + arg(opts.Status)
+	}
+	if !opts.Since.IsZero() {
+		query += " AND updated_at >= " + arg(opts.Since)
+	}
+	if !opts.Until.IsZero() {
+		query += " AND updated_at < " + arg(opts.Until)
+	}
+	if cursor != nil {
+		query += " AND (updated_at, hash COLLATE \"C\") < (" + arg(cursor.updatedAt) + ", " + arg(cursor.hash) + ")"
+	}
+
+	limit := opts.limit()
+	query += " ORDER BY updated_at DESC, hash COLLATE \"C\" DESC LIMIT " + arg(limit+1)
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list records: %w", err)
+	}
+
+	records, err := scanPostgresRecords(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list records: %w", err)
+	}
+
+	page := &Page{Records: records}
+	if len(records) > limit {
+		page.Records = records[:limit]
+		page.NextCursor = encodeCursor(page.Records[limit-1])
+	}
+	return page, nil
+}
+
+// Count returns the number of records with a status (empty = all)
+func (s *PostgresStorage) Count(ctx context.Context, status string) (int64, error) {
+	var n int64
+	err := s.pool.QueryRow(ctx, `
+		SELECT count(*) FROM gordon_files
+		WHERE expires_at > now() AND ($1 = '' OR status = $1)`, status).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count records: %w", err)
+	}
+	return n, nil
+}
+
+// scanPostgresRecord scans the postgresRecordColumns of one row
+func scanPostgresRecord(row pgx.Row) (*Record, error) {
+	var r Record
+	if err := row.Scan(&r.Hash, &r.Status, &r.Path, &r.Filename, &r.Reason, &r.UpdatedAt, &r.ExpiresAt); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// scanPostgresRecords scans and closes rows
+func scanPostgresRecords(rows pgx.Rows) ([]Record, error) {
+	defer rows.Close()
+
+	records := make([]Record, 0)
+	for rows.Next() {
+		r, err := scanPostgresRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, *r)
+	}
+	return records, rows.Err()
+}
+
+// CountEntries returns the number of unexpired rows per status
+func (s *PostgresStorage) CountEntries(ctx context.Context) (map[string]int64, error) {
+	rows, err := s.pool.Query(ctx,
+		"SELECT status, count(*) FROM gordon_files WHERE expires_at > now() GROUP BY status")
+	if err != nil {
+		return nil, fmt.Errorf("failed to count entries: %w", err)
+	}
+	defer rows.Close()
+
+	counts := map[string]int64{StatusEnqueued: 0, StatusProcessed: 0, StatusFailed: 0}
+	for rows.Next() {
+		var status string
+		var n int64
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, fmt.Errorf("failed to count entries: %w", err)
+		}
+		counts[status] = n
+	}
+
+	return counts, rows.Err()
+}
+
+// Import writes records keeping their timestamps and expiry, in one
+// transaction per call
+func (s *PostgresStorage) Import(ctx context.Context, records []Record) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to import records: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	batch := &pgx.Batch{}
+	for _, r := range records {
+		// The <status>_at column of the record's status is its update time
+		batch.Queue(`
+			INSERT INTO gordon_files (hash, status, path, filename, reason,
+				enqueued_at, processed_at, failed_at, updated_at, expires_at)
+			VALUES ($1, $2, $3, $4, $5,
+				CASE WHEN $2 = 'enqueued' THEN $6::timestamptz END,
+				CASE WHEN $2 = 'processed' THEN $6::timestamptz END,
+				CASE WHEN $2 = 'failed' THEN $6::timestamptz END,
+				$6, $7)
+			ON CONFLICT (hash) DO UPDATE SET
+				status       = EXCLUDED.status,
+				path         = EXCLUDED.path,
+				filename     = EXCLUDED.filename,
+				reason       = EXCLUDED.reason,
+				enqueued_at  = COALESCE(EXCLUDED.enqueued_at, gordon_files.enqueued_at),
+				processed_at = COALESCE(EXCLUDED.processed_at, gordon_files.processed_at),
+				failed_at    = COALESCE(EXCLUDED.failed_at, gordon_files.failed_at),
+				updated_at   = EXCLUDED.updated_at,
+				expires_at   = EXCLUDED.expires_at`,
+			r.Hash, r.Status, r.Path, r.Filename, r.Reason, r.UpdatedAt, r.ExpiresAt)
+	}
+
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to import records: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to import records: %w", err)
+	}
+	return nil
+}
+
+// MaxFencingToken returns the last fencing token handed out
+func (s *PostgresStorage) MaxFencingToken(ctx context.Context) (uint64, error) {
+	var token int64
+	err := s.pool.QueryRow(ctx,
+		"SELECT CASE WHEN is_called THEN last_value ELSE 0 END FROM gordon_fencing_token_seq",
+	).Scan(&token)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read fencing token: %w", err)
+	}
+	return uint64(token), nil
+}
+
+// RaiseFencingToken makes later fencing tokens greater than token
+func (s *PostgresStorage) RaiseFencingToken(ctx context.Context, token uint64) error {
+	_, err := s.pool.Exec(ctx, `
+		SELECT setval('gordon_fencing_token_seq', $1)
+		WHERE $1 > (SELECT CASE WHEN is_called THEN last_value ELSE 0 END FROM gordon_fencing_token_seq)`,
+		int64(token))
+	if err != nil {
+		return fmt.Errorf("failed to raise fencing token: %w", err)
+	}
+	return nil
+}
+
+// GetLock acquires a session-level advisory lock. The lock lives on a
+// dedicated pool connection, so it is released by Postgres if the
+// connection dies; a keep-alive ping closes Lost when that happens.
+func (s *PostgresStorage) GetLock(ctx context.Context, hash string) (Lock, error) {
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+
+	key := advisoryKey(hash)
+
+	var ok bool
+	if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&ok); err != nil {
+		conn.Release()
+		return nil, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+
+	if !ok {
+		conn.Release()
+		return nil, fmt.Errorf("lock already held")
+	}
+
+	var token int64
+	if err := conn.QueryRow(ctx, "SELECT nextval('gordon_fencing_token_seq')").Scan(&token); err != nil {
+		_, _ = conn.Exec(ctx, "SELECT pg_advisory_unlock($1)", key)
+		conn.Release()
+		return nil, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+
+	lock := &postgresLock{
+		conn:       conn,
+		key:        key,
+		token:      uint64(token),
+		lostSignal: newLostSignal(),
+		stop:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+	go lock.keepAlive(s.ttl.Lock / 3)
+
+	return lock, nil
+}
+
+// Close stops the retention job and closes the pool
+func (s *PostgresStorage) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.wg.Wait()
+		s.pool.Close()
+	})
+	return nil
+}
+
+// retentionLoop deletes expired rows until Close
+func (s *PostgresStorage) retentionLoop(interval time.Duration) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			_, _ = s.pool.Exec(ctx, "DELETE FROM gordon_files WHERE expires_at <= now()")
+			cancel()
+		}
+	}
+}
+
+// migratePostgres applies the embedded migrations that are not recorded in
+// gordon_schema_migrations, in file name order
+func migratePostgres(ctx context.Context, pool *pgxpool.Pool) error {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire migration connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey); err != nil {
+		return fmt.Errorf("failed to lock migrations: %w", err)
+	}
+	defer func() {
+		_, _ = conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockKey)
+	}()
+
+	_, err = conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS gordon_schema_migrations (
+			version    INTEGER PRIMARY KEY,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`)
+	if err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	names, err := fs.Glob(postgresMigrations, "migrations/postgres/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		base := name[strings.LastIndex(name, "/")+1:]
+		version, err := strconv.Atoi(strings.SplitN(base, "_", 2)[0])
+		if err != nil {
+			return fmt.Errorf("invalid migration name %s: %w", base, err)
+		}
+
+		var applied bool
+		err = conn.QueryRow(ctx,
+			"SELECT EXISTS (SELECT 1 FROM gordon_schema_migrations WHERE version = $1)", version,
+		).Scan(&applied)
+		if err != nil {
+			return fmt.Errorf("failed to read migrations: %w", err)
+		}
+		if applied {
+			continue
+		}
+
+		sql, err := postgresMigrations.ReadFile(name)
+		if err != nil {
+			return err
+		}
+
+		tx, err := conn.Begin(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to begin migration %s: %w", base, err)
+		}
+		if _, err := tx.Exec(ctx, string(sql)); err != nil {
+			_ = tx.Rollback(ctx)
+			return fmt.Errorf("failed to apply migration %s: %w", base, err)
+		}
+		if _, err := tx.Exec(ctx, "INSERT INTO gordon_schema_migrations (version) VALUES ($1)", version); err != nil {
+			_ = tx.Rollback(ctx)
+			return fmt.Errorf("failed to record migration %s: %w", base, err)
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return fmt.Errorf("failed to commit migration %s: %w", base, err)
+		}
+	}
+
+	return nil
+}
+
+// advisoryKey maps a file hash to the bigint key space of advisory locks
+func advisoryKey(hash string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte("gordon:watcher:lock:" + hash))
+	return int64(h.Sum64())
+}
+
+// postgresLock implements Lock using a Postgres advisory lock
+type postgresLock struct {
+	conn  *pgxpool.Conn
+	key   int64
+	token uint64
+
+	*lostSignal
+
+	stop    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// Token returns the fencing token
+func (l *postgresLock) Token() uint64 {
+	return l.token
+}
+
+// keepAlive pings the lock's session until Release. A failed ping means
+// the session, and with it the advisory lock, may be gone.
+func (l *postgresLock) keepAlive(interval time.Duration) {
+	defer close(l.stopped)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		err := l.conn.Ping(ctx)
+		cancel()
+
+		if err != nil {
+			l.markLost()
+			return
+		}
+	}
+}
+
+// Release unlocks and returns the connection to the pool
+func (l *postgresLock) Release(ctx context.Context) error {
+	var err error
+	l.once.Do(func() {
+		// Stop pinging first; the connection is not safe for concurrent use
+		close(l.stop)
+		<-l.stopped
+
+		defer l.conn.Release()
+
+		if _, execErr := l.conn.Exec(ctx, "SELECT pg_advisory_unlock($1)", l.key); execErr != nil {
+			// Close the session so Postgres drops the lock with it
+			_ = l.conn.Conn().Close(context.Background())
+			err = fmt.Errorf("failed to release lock: %w", execErr)
+		}
+	})
+	return err
+}
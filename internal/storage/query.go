@@ -0,0 +1,204 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned by Get when no unexpired record exists
+var ErrNotFound = errors.New("record not found")
+
+// ErrInvalidCursor is returned by List for a malformed cursor
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// DefaultListLimit is the page size used when ListOptions.Limit is zero
+const DefaultListLimit = 100
+
+// Record is the current state of a file
+type Record struct {
+	Hash      string    `json:"hash"`
+	Status    string    `json:"status"` // enqueued, processed, failed
+	Path      string    `json:"path,omitempty"`
+	Filename  string    `json:"filename,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ListOptions filters and pages List results. Records are returned newest
+// first (UpdatedAt descending, then hash descending).
+type ListOptions struct {
+	// Status filters by status (empty = any)
+	Status string
+
+	// Since and Until bound UpdatedAt; Since is inclusive, Until exclusive.
+	// Zero values leave the range open.
+	Since time.Time
+	Until time.Time
+
+	// Limit is the page size (default DefaultListLimit)
+	Limit int
+
+	// Cursor is Page.NextCursor from the previous call
+	Cursor string
+}
+
+// Page is one page of List results
+type Page struct {
+	Records []Record `json:"records"`
+
+	// NextCursor is empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// Querier answers "what happened to file X?"
+type Querier interface {
+	// Get returns the current record for a hash, or ErrNotFound
+	Get(ctx context.Context, hash string) (*Record, error)
+
+	// SearchByFilename returns records whose filename starts with prefix,
+	// ordered by filename then hash
+	SearchByFilename(ctx context.Context, prefix string, limit int) ([]Record, error)
+
+	// List returns a page of records matching opts
+	List(ctx context.Context, opts ListOptions) (*Page, error)
+
+	// Count returns the number of records with a status (empty = all)
+	Count(ctx context.Context, status string) (int64, error)
+}
+
+// listCursor is the position after the last record of a page
+type listCursor struct {
+	updatedAt time.Time
+	hash      string
+}
+
+// encodeCursor returns the cursor that resumes after r
+func encodeCursor(r Record) string {
+	return strconv.FormatInt(r.UpdatedAt.UnixNano(), 10) + ":" + r.Hash
+}
+
+// decodeCursor parses a cursor; an empty string yields nil
+func decodeCursor(s string) (*listCursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	nanos, hash, ok := strings.Cut(s, ":")
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrInvalidCursor, s)
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q", ErrInvalidCursor, s)
+	}
+
+	return &listCursor{updatedAt: time.Unix(0, n), hash: hash}, nil
+}
+
+// before reports whether r sorts after the cursor position (newest first)
+func (c *listCursor) before(r Record) bool {
+	if c == nil {
+		return true
+	}
+	if !r.UpdatedAt.Equal(c.updatedAt) {
+		return r.UpdatedAt.Before(c.updatedAt)
+	}
+	return r.Hash < c.hash
+}
+
+// matches reports whether r passes the status and time filters
+func (o ListOptions) matches(r Record) bool {
+	if o.Status != "" && r.Status != o.Status {
+		return false
+	}
+	if !o.Since.IsZero() && r.UpdatedAt.Before(o.Since) {
+		return false
+	}
+	if !o.Until.IsZero() && !r.UpdatedAt.Before(o.Until) {
+		return false
+	}
+	return true
+}
+
+// limit returns the page size
+func (o ListOptions) limit() int {
+	if o.Limit <= 0 {
+		return DefaultListLimit
+	}
+	return o.Limit
+}
+
+// paginate filters, sorts and pages records held in memory
+func paginate(records []Record, opts ListOptions) (*Page, error) {
+	cursor, err := decodeCursor(opts.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := make([]Record, 0, len(records))
+	for _, r := range records {
+		if opts.matches(r) && cursor.before(r) {
+			matched = append(matched, r)
+		}
+	}
+	sortNewestFirst(matched)
+
+	page := &Page{Records: matched}
+	if limit := opts.limit(); len(matched) > limit {
+		page.Records = matched[:limit]
+		page.NextCursor = encodeCursor(page.Records[limit-1])
+	}
+
+	return page, nil
+}
+
+// searchFilename filters records by filename prefix, ordered by filename
+// then hash
+func searchFilename(records []Record, prefix string, limit int) []Record {
+	matched := make([]Record, 0)
+	for _, r := range records {
+		if r.Filename != "" && strings.HasPrefix(r.Filename, prefix) {
+			matched = append(matched, r)
+		}
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		if matched[i].Filename != matched[j].Filename {
+			return matched[i].Filename < matched[j].Filename
+		}
+		return matched[i].Hash < matched[j].Hash
+	})
+
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+	if len(matched) > limit {
+		matched = matched[:limit]
+	}
+	return matched
+}
+
+// sortNewestFirst orders by UpdatedAt then hash, descending
+func sortNewestFirst(records []Record) {
+	sort.Slice(records, func(i, j int) bool {
+		if !records[i].UpdatedAt.Equal(records[j].UpdatedAt) {
+			return records[i].UpdatedAt.After(records[j].UpdatedAt)
+		}
+		return records[i].Hash > records[j].Hash
+	})
+}
+
+// filenameOf returns the base name of a stored path ("" for no path)
+func filenameOf(path string) string {
+	if path == "" {
+		return ""
+	}
+	return filepath.Base(path)
+}
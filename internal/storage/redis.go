@@ -2,59 +2,254 @@ package storage
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
 const (
-	keyPrefixProcessed = "gordon:watcher:processed:"
-	keyPrefixEnqueued  = "gordon:watcher:enqueued:"
-	keyPrefixFailed    = "gordon:watcher:failed:"
-	keyPrefixLock      = "gordon:watcher:lock:"
-
-	defaultTTL   = 24 * time.Hour     // 24 hours
-	lockTTL      = 30 * time.Second   // 30 seconds
-	processedTTL = 7 * 24 * time.Hour // 7 days
+	// DefaultRedisKeyPrefix namespaces every key written by the watcher
+	DefaultRedisKeyPrefix = "gordon:watcher:"
+)
+
+// Redis deployment modes
+const (
+	RedisModeStandalone = "standalone"
+	RedisModeSentinel   = "sentinel"
+	RedisModeCluster    = "cluster"
 )
 
 // RedisConfig configures Redis connection
 type RedisConfig struct {
-	Addr     string
+	// Mode is standalone (default), sentinel or cluster
+	Mode string
+
+	// Addr is the server address in standalone mode
+	Addr string
+
+	// Addrs are the sentinel addresses or the cluster seed nodes
+	Addrs []string
+
+	// MasterName is the sentinel master set name
+	MasterName string
+
+	// SentinelUsername and SentinelPassword authenticate against the
+	// sentinels when they differ from the data nodes
+	SentinelUsername string
+	SentinelPassword string
+
+	// Username enables Redis 6 ACL authentication
+	Username string
 	Password string
-	DB       int
+
+	// DB is ignored in cluster mode
+	DB int
+
+	TLS RedisTLSConfig
+
+	// KeyPrefix namespaces keys so several deployments can share one
+	// Redis (default DefaultRedisKeyPrefix)
+	KeyPrefix string
+
+	// TTL sets the key expiry per status and the lock lease
+	TTL TTLConfig
+}
+
+// RedisTLSConfig configures TLS for Redis connections
+type RedisTLSConfig struct {
+	Enabled bool
+
+	// CAFile is a PEM bundle used instead of the system roots
+	CAFile string
+
+	// CertFile and KeyFile enable client certificate authentication
+	CertFile string
+	KeyFile  string
+
+	ServerName         string
+	InsecureSkipVerify bool
 }
 
 // RedisStorage implements Storage using Redis
 type RedisStorage struct {
-	client *redis.Client
+	client  redis.UniversalClient
+	cluster bool
+	prefix  string
+	ttl     TTLConfig
+
+	// Every fencing token handed out exceeds this floor, raised when
+	// tokens are carried over from another backend (see fencingFloorKey)
+	fencingFloor atomic.Uint64
+
+	// Index pruning runs at most once per pruneInterval
+	pruneMu   sync.Mutex
+	lastPrune time.Time
+
+	closeOnce sync.Once
+}
+
+// NewRedisClient builds a client for the configured deployment mode
+func NewRedisClient(cfg RedisConfig) (redis.UniversalClient, error) {
+	var tlsConfig *tls.Config
+	if cfg.TLS.Enabled {
+		var err error
+		if tlsConfig, err = cfg.TLS.build(); err != nil {
+			return nil, err
+		}
+	}
+
+	switch cfg.Mode {
+	case "", RedisModeStandalone:
+		return redis.NewClient(&redis.Options{
+			Addr:      cfg.Addr,
+			Username:  cfg.Username,
+			Password:  cfg.Password,
+			DB:        cfg.DB,
+			TLSConfig: tlsConfig,
+		}), nil
+	case RedisModeSentinel:
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       cfg.MasterName,
+			SentinelAddrs:    cfg.Addrs,
+			SentinelUsername: cfg.SentinelUsername,
+			SentinelPassword: cfg.SentinelPassword,
+			Username:         cfg.Username,
+			Password:         cfg.Password,
+			DB:               cfg.DB,
+			TLSConfig:        tlsConfig,
+		}), nil
+	case RedisModeCluster:
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:     cfg.Addrs,
+			Username:  cfg.Username,
+			Password:  cfg.Password,
+			TLSConfig: tlsConfig,
+		}), nil
+	default:
+		return nil, fmt.Errorf("unsupported Redis mode: %s", cfg.Mode)
+	}
+}
+
+// build loads the CA bundle and client certificate
+func (c RedisTLSConfig) build() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Redis CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in Redis CA file %s", c.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load Redis client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
 }
 
 // NewRedisStorage creates a new Redis storage
 func NewRedisStorage(cfg RedisConfig) (*RedisStorage, error) {
-	client := redis.NewClient(&redis.Options{
-		Addr:     cfg.Addr,
-		Password: cfg.Password,
-		DB:       cfg.DB,
-	})
+	client, err := NewRedisClient(cfg)
+	if err != nil {
+		return nil, err
+	}
 
 	// Test connection
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
-	return &RedisStorage{
-		client: client,
-	}, nil
+	prefix := cfg.KeyPrefix
+	if prefix == "" {
+		prefix = DefaultRedisKeyPrefix
+	}
+
+	s := &RedisStorage{
+		client:  client,
+		cluster: cfg.Mode == RedisModeCluster,
+		prefix:  prefix,
+		ttl:     cfg.TTL.withDefaults(),
+	}
+
+	floor, err := client.Get(ctx, s.fencingFloorKey()).Uint64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		client.Close()
+		return nil, fmt.Errorf("failed to read fencing token floor: %w", err)
+	}
+	s.fencingFloor.Store(floor)
+
+	return s, nil
+}
+
+// Key layout. State and lock keys keep the pre-namespacing format, so
+// existing data stays valid with the default prefix and older instances
+// still exclude each other during a rolling upgrade. In cluster mode the
+// lock and its fencing counter share the {hash} tag so the acquire script
+// touches a single slot.
+func (s *RedisStorage) processedKey(hash string) string {
+	return s.prefix + "processed:" + hash
+}
+
+func (s *RedisStorage) enqueuedKey(hash string) string {
+	return s.prefix + "enqueued:" + hash
+}
+
+func (s *RedisStorage) failedKey(hash string) string {
+	return s.prefix + "failed:" + hash
+}
+
+func (s *RedisStorage) lockKey(hash string) string {
+	return s.prefix + "lock:" + s.slotTag(hash)
+}
+
+func (s *RedisStorage) fencingKey(hash string) string {
+	return s.prefix + "fencing_token:" + s.slotTag(hash)
+}
+
+// fencingFloorKey holds the floor of every fencing token. It is read once at
+// startup and passed to the acquire script, which keeps the script on the
+// slot of its hash.
+func (s *RedisStorage) fencingFloorKey() string {
+	return s.prefix + "fencing_floor"
+}
+
+// slotTag wraps hash in a cluster hash tag when running against Redis
+// Cluster
+func (s *RedisStorage) slotTag(hash string) string {
+	if s.cluster {
+		return "{" + hash + "}"
+	}
+	return hash
 }
 
 // IsProcessed checks if a file hash has been processed
 func (s *RedisStorage) IsProcessed(ctx context.Context, hash string) (bool, error) {
-	key := keyPrefixProcessed + hash
+	key := s.processedKey(hash)
 
 	exists, err := s.client.Exists(ctx, key).Result()
 	if err != nil {
@@ -66,85 +261,285 @@ func (s *RedisStorage) IsProcessed(ctx context.Context, hash string) (bool, erro
 
 // MarkEnqueued marks a file as enqueued
 func (s *RedisStorage) MarkEnqueued(ctx context.Context, hash, path string) error {
-	key := keyPrefixEnqueued + hash
-
-	err := s.client.Set(ctx, key, path, defaultTTL).Err()
+	_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		s.MarkEnqueuedTx(ctx, pipe, hash, path)
+		return nil
+	})
 	if err != nil {
 		return fmt.Errorf("failed to mark as enqueued: %w", err)
 	}
 
+	s.maybePrune(ctx)
 	return nil
 }
 
+// MarkEnqueuedTx queues the enqueued state write on a transaction pipeline
+func (s *RedisStorage) MarkEnqueuedTx(ctx context.Context, pipe redis.Pipeliner, hash, path string) {
+	pipe.Set(ctx, s.enqueuedKey(hash), path, s.ttl.Enqueued)
+	s.writeRecord(ctx, pipe, newRecord(hash, StatusEnqueued, path, filenameOf(path), "", s.ttl.Enqueued))
+}
+
+// Client returns the underlying Redis client so other components can share
+// the connection
+func (s *RedisStorage) Client() redis.UniversalClient {
+	return s.client
+}
+
+// Cluster reports whether the client talks to Redis Cluster, where
+// transactions cannot span keys in different slots
+func (s *RedisStorage) Cluster() bool {
+	return s.cluster
+}
+
 // MarkProcessed marks a file as processed
 func (s *RedisStorage) MarkProcessed(ctx context.Context, hash string) error {
-	key := keyPrefixProcessed + hash
+	filename := s.storedFilename(ctx, hash)
 
-	err := s.client.Set(ctx, key, time.Now().Unix(), processedTTL).Err()
+	_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, s.processedKey(hash), time.Now().Unix(), s.ttl.Processed)
+		pipe.Del(ctx, s.enqueuedKey(hash))
+		s.writeRecord(ctx, pipe, newRecord(hash, StatusProcessed, "", filename, "", s.ttl.Processed))
+		return nil
+	})
 	if err != nil {
 		return fmt.Errorf("failed to mark as processed: %w", err)
 	}
 
-	// Remove from enqueued
-	enqueuedKey := keyPrefixEnqueued + hash
-	_ = s.client.Del(ctx, enqueuedKey).Err()
-
+	s.maybePrune(ctx)
 	return nil
 }
 
 // MarkFailed marks a file as failed
 func (s *RedisStorage) MarkFailed(ctx context.Context, hash, reason string) error {
-	key := keyPrefixFailed + hash
-
+	filename := s.storedFilename(ctx, hash)
 	data := fmt.Sprintf("%d:%s", time.Now().Unix(), reason)
-	err := s.client.Set(ctx, key, data, processedTTL).Err()
+
+	_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, s.failedKey(hash), data, s.ttl.Failed)
+		pipe.Del(ctx, s.enqueuedKey(hash))
+		s.writeRecord(ctx, pipe, newRecord(hash, StatusFailed, "", filename, reason, s.ttl.Failed))
+		return nil
+	})
 	if err != nil {
 		return fmt.Errorf("failed to mark as failed: %w", err)
 	}
 
-	// Remove from enqueued
-	enqueuedKey := keyPrefixEnqueued + hash
-	_ = s.client.Del(ctx, enqueuedKey).Err()
+	s.maybePrune(ctx)
+	return nil
+}
+
+// Import writes records keeping their timestamps and expiry
+func (s *RedisStorage) Import(ctx context.Context, records []Record) error {
+	_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, r := range records {
+			// Same values the Mark methods write
+			switch r.Status {
+			case StatusEnqueued:
+				pipe.Set(ctx, s.enqueuedKey(r.Hash), r.Path, 0)
+				pipe.PExpireAt(ctx, s.enqueuedKey(r.Hash), r.ExpiresAt)
+			case StatusProcessed:
+				pipe.Set(ctx, s.processedKey(r.Hash), r.UpdatedAt.Unix(), 0)
+				pipe.PExpireAt(ctx, s.processedKey(r.Hash), r.ExpiresAt)
+				pipe.Del(ctx, s.enqueuedKey(r.Hash))
+			case StatusFailed:
+				pipe.Set(ctx, s.failedKey(r.Hash), fmt.Sprintf("%d:%s", r.UpdatedAt.Unix(), r.Reason), 0)
+				pipe.PExpireAt(ctx, s.failedKey(r.Hash), r.ExpiresAt)
+				pipe.Del(ctx, s.enqueuedKey(r.Hash))
+			default:
+				return fmt.Errorf("unknown status %q for %s", r.Status, r.Hash)
+			}
+			s.writeRecord(ctx, pipe, r)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to import records: %w", err)
+	}
+
+	return nil
+}
+
+// MaxFencingToken returns the highest fencing token of any hash still
+// tracked, or the floor if that is higher
+func (s *RedisStorage) MaxFencingToken(ctx context.Context) (uint64, error) {
+	token := s.fencingFloor.Load()
+	err := s.scanKeys(ctx, s.prefix+"fencing_token:*", func(keys []string) error {
+		values := make([]*redis.StringCmd, len(keys))
+		_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, key := range keys {
+				values[i] = pipe.Get(ctx, key)
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return err
+		}
+		for _, v := range values {
+			if n, err := v.Uint64(); err == nil {
+				token = max(token, n)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to read fencing tokens: %w", err)
+	}
+	return token, nil
+}
 
+// RaiseFencingToken makes later fencing tokens greater than token, also when
+// the server clock they derive from is behind it. Instances started before
+// the floor was raised do not see it.
+func (s *RedisStorage) RaiseFencingToken(ctx context.Context, token uint64) error {
+	floor, err := raiseFloorScript.Run(ctx, s.client, []string{s.fencingFloorKey()}, token).Uint64()
+	if err != nil {
+		return fmt.Errorf("failed to raise fencing token: %w", err)
+	}
+	s.fencingFloor.Store(floor)
 	return nil
 }
 
-// GetLock acquires a distributed lock
+// raiseFloorScript raises the fencing token floor and returns the new one
+var raiseFloorScript = redis.NewScript(`
+	local floor = math.max(tonumber(redis.call("get", KEYS[1]) or "0"), tonumber(ARGV[1]))
+	redis.call("set", KEYS[1], string.format("%.0f", floor))
+	return floor
+`)
+
+// acquireScript takes the lock and, on success, returns the next fencing
+// token for the hash (0 = lock already held). Tokens are per hash so both
+// keys live in one cluster slot. The counter is seeded from the server
+// clock in microseconds, so tokens keep growing after it expires, and
+// never falls to the floor in ARGV[4].
+var acquireScript = redis.NewScript(`
+	if not redis.call("set", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+		return 0
+	end
+	local now = redis.call("time")
+	local token = tonumber(now[1]) * 1000000 + tonumber(now[2])
+	local last = math.max(tonumber(redis.call("get", KEYS[2]) or "0"), tonumber(ARGV[4]))
+	if token <= last then
+		token = last + 1
+	end
+	redis.call("set", KEYS[2], string.format("%.0f", token), "PX", ARGV[3])
+	return token
+`)
+
+// renewScript extends the lease only while we still own the lock
+var renewScript = redis.NewScript(`
+	if redis.call("get", KEYS[1]) == ARGV[1] then
+		return redis.call("pexpire", KEYS[1], ARGV[2])
+	end
+	return 0
+`)
+
+// GetLock acquires a distributed lock. The lease is renewed in the
+// background until Release; Lost is closed once renewal keeps failing and
+// less than a third of the lease is left.
 func (s *RedisStorage) GetLock(ctx context.Context, hash string) (Lock, error) {
-	key := keyPrefixLock + hash
-	value := fmt.Sprintf("%d", time.Now().UnixNano())
+	key := s.lockKey(hash)
+	sent := time.Now()
+	value := fmt.Sprintf("%d", sent.UnixNano())
 
 	// Try to acquire lock
-	ok, err := s.client.SetNX(ctx, key, value, lockTTL).Result()
+	token, err := acquireScript.Run(ctx, s.client,
+		[]string{key, s.fencingKey(hash)}, value, s.ttl.Lock.Milliseconds(), s.ttl.Processed.Milliseconds(),
+		s.fencingFloor.Load(),
+	).Int64()
 	if err != nil {
 		return nil, fmt.Errorf("failed to acquire lock: %w", err)
 	}
 
-	if !ok {
+	if token == 0 {
 		return nil, fmt.Errorf("lock already held")
 	}
 
-	return &redisLock{
-		client: s.client,
-		key:    key,
-		value:  value,
-	}, nil
+	lock := &redisLock{
+		client:     s.client,
+		key:        key,
+		value:      value,
+		token:      uint64(token),
+		ttl:        s.ttl.Lock,
+		lostSignal: newLostSignal(),
+		stop:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+	go lock.keepAlive(sent.Add(s.ttl.Lock))
+
+	return lock, nil
 }
 
 // Close closes the Redis connection
 func (s *RedisStorage) Close() error {
-	return s.client.Close()
+	var err error
+	s.closeOnce.Do(func() {
+		err = s.client.Close()
+	})
+	return err
 }
 
 // redisLock implements Lock using Redis
 type redisLock struct {
-	client *redis.Client
+	client redis.UniversalClient
 	key    string
 	value  string
+	token  uint64
+	ttl    time.Duration
+
+	*lostSignal
+
+	stop     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
 }
 
-// Release releases the lock
+// Token returns the fencing token
+func (l *redisLock) Token() uint64 {
+	return l.token
+}
+
+// keepAlive renews the lease every third of the TTL until Release.
+// expires is counted from when each request was sent, so it never runs
+// past the lease Redis holds.
+func (l *redisLock) keepAlive(expires time.Time) {
+	defer close(l.stopped)
+
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+		}
+
+		sent := time.Now()
+		ctx, cancel := context.WithTimeout(context.Background(), l.ttl/3)
+		renewed, err := renewScript.Run(ctx, l.client, []string{l.key}, l.value, l.ttl.Milliseconds()).Int64()
+		cancel()
+
+		switch {
+		case err == nil && renewed == 1:
+			expires = sent.Add(l.ttl)
+		case err == nil:
+			// Key expired or was taken over
+			l.markLost()
+			return
+		case time.Until(expires) < l.ttl/3:
+			// Transient errors ate the lease down to the safety margin;
+			// stop before another holder can take the key
+			l.markLost()
+			return
+		}
+	}
+}
+
+// Release stops the renewal and releases the lock
 func (l *redisLock) Release(ctx context.Context) error {
+	l.stopOnce.Do(func() { close(l.stop) })
+	<-l.stopped
+
 	// Use Lua script to ensure we only delete our own lock
 	script := `
 		if redis.call("get", KEYS[1]) == ARGV[1] then
@@ -0,0 +1,525 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Query layout. Each hash has a record (a Redis hash expiring with its
+// status) and index entries that do not expire on their own:
+//
+//	<prefix>index:status:<status>  zset  hash, scored by updated_at (ms)
+//	<prefix>index:filename         zset  "<filename>\x00<hash>", score 0 (lex order)
+//	<prefix>index:expiry           zset  "<filename>\x00<hash>", scored by expires_at (ms)
+//
+// Index entries of expired records are pruned from the expiry index. The
+// keys live in different cluster slots, so they are written with plain
+// pipelines and readers skip entries whose record is gone.
+
+const (
+	// pruneInterval rate-limits index pruning on the write path
+	pruneInterval = time.Minute
+
+	// pruneBatch bounds the members pruned per round trip
+	pruneBatch = 100
+)
+
+var recordStatuses = []string{StatusEnqueued, StatusProcessed, StatusFailed}
+
+func (s *RedisStorage) recordKey(hash string) string {
+	return s.prefix + "record:" + hash
+}
+
+func (s *RedisStorage) statusIndexKey(status string) string {
+	return s.prefix + "index:status:" + status
+}
+
+func (s *RedisStorage) filenameIndexKey() string {
+	return s.prefix + "index:filename"
+}
+
+func (s *RedisStorage) expiryIndexKey() string {
+	return s.prefix + "index:expiry"
+}
+
+// newRecord returns the record for a status change made now
+func newRecord(hash, status, path, filename, reason string, ttl time.Duration) Record {
+	now := time.Now()
+	return Record{
+		Hash:      hash,
+		Status:    status,
+		Path:      path,
+		Filename:  filename,
+		Reason:    reason,
+		UpdatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+}
+
+// writeRecord queues the record and index updates for a status change. An
+// empty Path keeps the stored one; Filename is used for the indexes.
+func (s *RedisStorage) writeRecord(ctx context.Context, pipe redis.Pipeliner, r Record) {
+	key := s.recordKey(r.Hash)
+
+	fields := []any{
+		"status", r.Status,
+		"reason", r.Reason,
+		"updated_at", r.UpdatedAt.UnixMilli(),
+		"expires_at", r.ExpiresAt.UnixMilli(),
+	}
+	if r.Path != "" {
+		fields = append(fields, "path", r.Path, "filename", r.Filename)
+	}
+	pipe.HSet(ctx, key, fields...)
+	pipe.PExpireAt(ctx, key, r.ExpiresAt)
+
+	for _, st := range recordStatuses {
+		if st != r.Status {
+			pipe.ZRem(ctx, s.statusIndexKey(st), r.Hash)
+		}
+	}
+	pipe.ZAdd(ctx, s.statusIndexKey(r.Status), redis.Z{Score: float64(r.UpdatedAt.UnixMilli()), Member: r.Hash})
+
+	member := r.Filename + "\x00" + r.Hash
+	if r.Filename != "" {
+		pipe.ZAdd(ctx, s.filenameIndexKey(), redis.Z{Score: 0, Member: member})
+	}
+	pipe.ZAdd(ctx, s.expiryIndexKey(), redis.Z{Score: float64(r.ExpiresAt.UnixMilli()), Member: member})
+}
+
+// storedFilename returns the filename recorded for a hash ("" if unknown)
+func (s *RedisStorage) storedFilename(ctx context.Context, hash string) string {
+	filename, _ := s.client.HGet(ctx, s.recordKey(hash), "filename").Result()
+	return filename
+}
+
+// maybePrune prunes the indexes at most once per pruneInterval
+func (s *RedisStorage) maybePrune(ctx context.Context) {
+	s.pruneMu.Lock()
+	if time.Since(s.lastPrune) < pruneInterval {
+		s.pruneMu.Unlock()
+		return
+	}
+	s.lastPrune = time.Now()
+	s.pruneMu.Unlock()
+
+	_ = s.pruneIndexes(ctx, 1)
+}
+
+// pruneIndexes removes index entries whose expiry passed, in up to rounds
+// batches. Entries of a hash whose record is still alive (it was rewritten
+// under another filename) only lose the stale filename entry.
+func (s *RedisStorage) pruneIndexes(ctx context.Context, rounds int) error {
+	for i := 0; i < rounds; i++ {
+		members, err := s.client.ZRangeByScore(ctx, s.expiryIndexKey(), &redis.ZRangeBy{
+			Min:   "-inf",
+			Max:   strconv.FormatInt(time.Now().UnixMilli(), 10),
+			Count: pruneBatch,
+		}).Result()
+		if err != nil {
+			return err
+		}
+		if len(members) == 0 {
+			return nil
+		}
+
+		alive := make([]*redis.IntCmd, len(members))
+		_, err = s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, member := range members {
+				_, hash, _ := strings.Cut(member, "\x00")
+				alive[i] = pipe.Exists(ctx, s.recordKey(hash))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, member := range members {
+				_, hash, _ := strings.Cut(member, "\x00")
+				pipe.ZRem(ctx, s.expiryIndexKey(), member)
+				pipe.ZRem(ctx, s.filenameIndexKey(), member)
+
+				if alive[i].Val() == 0 {
+					for _, st := range recordStatuses {
+						pipe.ZRem(ctx, s.statusIndexKey(st), hash)
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if len(members) < pruneBatch {
+			return nil
+		}
+	}
+	return nil
+}
+
+// Get returns the current record for a hash
+func (s *RedisStorage) Get(ctx context.Context, hash string) (*Record, error) {
+	fields, err := s.client.HGetAll(ctx, s.recordKey(hash)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get record: %w", err)
+	}
+	if len(fields) == 0 {
+		return nil, ErrNotFound
+	}
+
+	record := parseRedisRecord(hash, fields)
+	return &record, nil
+}
+
+// SearchByFilename returns records whose filename starts with prefix
+func (s *RedisStorage) SearchByFilename(ctx context.Context, prefix string, limit int) ([]Record, error) {
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+
+	bounds := &redis.ZRangeBy{Min: "-", Max: "+", Count: int64(limit)}
+	if prefix != "" {
+		bounds.Min = "[" + prefix
+		bounds.Max = "[" + prefix + "\xff"
+	}
+
+	records := make([]Record, 0)
+	for {
+		members, err := s.client.ZRangeByLex(ctx, s.filenameIndexKey(), bounds).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to search by filename: %w", err)
+		}
+
+		for _, member := range members {
+			filename, hash, _ := strings.Cut(member, "\x00")
+			record, err := s.Get(ctx, hash)
+			if errors.Is(err, ErrNotFound) {
+				continue // expired since indexed
+			}
+			if err != nil {
+				return nil, err
+			}
+			if record.Filename != filename {
+				continue // renamed since indexed
+			}
+			records = append(records, *record)
+			if len(records) == limit {
+				return records, nil
+			}
+		}
+
+		if len(members) < limit {
+			return records, nil
+		}
+		bounds.Offset += int64(len(members))
+	}
+}
+
+// List returns a page of records, newest first
+func (s *RedisStorage) List(ctx context.Context, opts ListOptions) (*Page, error) {
+	cursor, err := decodeCursor(opts.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := recordStatuses
+	if opts.Status != "" {
+		statuses = []string{opts.Status}
+	}
+
+	// Collect one extra candidate per index to know whether a page follows
+	limit := opts.limit()
+	var candidates []Record
+	for _, status := range statuses {
+		found, err := s.indexPage(ctx, status, opts, cursor, limit+1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list records: %w", err)
+		}
+		candidates = append(candidates, found...)
+	}
+	sortNewestFirst(candidates)
+
+	page := &Page{Records: make([]Record, 0, limit)}
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+		page.NextCursor = encodeCursor(candidates[limit-1])
+	}
+
+	for _, c := range candidates {
+		record, err := s.Get(ctx, c.Hash)
+		if errors.Is(err, ErrNotFound) {
+			continue // expired since indexed
+		}
+		if err != nil {
+			return nil, err
+		}
+		if record.Status != c.Status {
+			continue // moved to another status since indexed
+		}
+		page.Records = append(page.Records, *record)
+	}
+
+	return page, nil
+}
+
+// indexPage returns up to n index entries after the cursor within the
+// time range, newest first. Only Hash, Status and UpdatedAt are set.
+func (s *RedisStorage) indexPage(ctx context.Context, status string, opts ListOptions, cursor *listCursor, n int) ([]Record, error) {
+	bounds := &redis.ZRangeBy{Min: "-inf", Max: "+inf", Count: int64(n)}
+	if !opts.Since.IsZero() {
+		bounds.Min = strconv.FormatInt(opts.Since.UnixMilli(), 10)
+	}
+	if !opts.Until.IsZero() {
+		bounds.Max = "(" + strconv.FormatInt(opts.Until.UnixMilli(), 10)
+	}
+	if cursor != nil && (opts.Until.IsZero() || cursor.updatedAt.Before(opts.Until)) {
+		bounds.Max = strconv.FormatInt(cursor.updatedAt.UnixMilli(), 10)
+	}
+
+	var found []Record
+	for {
+		entries, err := s.client.ZRevRangeByScoreWithScores(ctx, s.statusIndexKey(status), bounds).Result()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, z := range entries {
+			r := Record{
+				Hash:      z.Member.(string),
+				Status:    status,
+				UpdatedAt: time.UnixMilli(int64(z.Score)),
+			}
+			// Ties with the cursor's timestamp may already have been returned
+			if !cursor.before(r) {
+				continue
+			}
+			found = append(found, r)
+			if len(found) == n {
+				return found, nil
+			}
+		}
+
+		if len(entries) < n {
+			return found, nil
+		}
+		bounds.Offset += int64(len(entries))
+	}
+}
+
+// Count returns the number of records with a status (empty = all)
+func (s *RedisStorage) Count(ctx context.Context, status string) (int64, error) {
+	// Drop expired entries so the index cardinality matches the records
+	if err := s.pruneIndexes(ctx, 10); err != nil {
+		return 0, fmt.Errorf("failed to count records: %w", err)
+	}
+
+	statuses := recordStatuses
+	if status != "" {
+		statuses = []string{status}
+	}
+
+	var total int64
+	for _, st := range statuses {
+		n, err := s.client.ZCard(ctx, s.statusIndexKey(st)).Result()
+		if err != nil {
+			return 0, fmt.Errorf("failed to count records: %w", err)
+		}
+		total += n
+	}
+
+	return total, nil
+}
+
+// Backfill writes records for state keys left by versions that predate
+// records, so List, Count and Migrate see them. Hashes that already have a
+// record are left alone. It returns the number of records written per
+// status; a dry run only counts them and writes nothing.
+func (s *RedisStorage) Backfill(ctx context.Context, dryRun bool) (map[string]int64, error) {
+	written := make(map[string]int64)
+
+	// A dry run writes no records, so hashes counted under an earlier
+	// status are tracked here instead
+	var seen map[string]bool
+	if dryRun {
+		seen = make(map[string]bool)
+	}
+
+	// Processed first: a hash with several legacy keys keeps that status
+	for _, status := range []string{StatusProcessed, StatusFailed, StatusEnqueued} {
+		prefix := s.prefix + status + ":"
+		err := s.scanKeys(ctx, prefix+"*", func(keys []string) error {
+			n, err := s.backfillKeys(ctx, status, prefix, keys, seen)
+			written[status] += n
+			return err
+		})
+		if err != nil {
+			return written, fmt.Errorf("failed to backfill records: %w", err)
+		}
+	}
+
+	return written, nil
+}
+
+// backfillKeys writes the records of one SCAN batch of legacy state keys.
+// When seen is not nil nothing is written: the hashes that would be are
+// added to seen and counted.
+func (s *RedisStorage) backfillKeys(ctx context.Context, status, prefix string, keys []string, seen map[string]bool) (int64, error) {
+	type legacy struct {
+		hasRecord *redis.IntCmd
+		value     *redis.StringCmd
+		ttl       *redis.DurationCmd
+	}
+
+	found := make([]legacy, len(keys))
+	_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			found[i] = legacy{
+				hasRecord: pipe.Exists(ctx, s.recordKey(strings.TrimPrefix(key, prefix))),
+				value:     pipe.Get(ctx, key),
+				ttl:       pipe.PTTL(ctx, key),
+			}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return 0, err
+	}
+
+	now := time.Now()
+	records := make([]Record, 0, len(keys))
+	for i, key := range keys {
+		if found[i].hasRecord.Val() > 0 || found[i].value.Err() != nil {
+			continue // already has a record, or expired since scanned
+		}
+
+		r := Record{Hash: strings.TrimPrefix(key, prefix), Status: status}
+		if seen != nil {
+			if !seen[r.Hash] {
+				seen[r.Hash] = true
+				records = append(records, r)
+			}
+			continue
+		}
+
+		ttl := s.ttlFor(status)
+		if remaining := found[i].ttl.Val(); remaining > 0 {
+			r.ExpiresAt = now.Add(remaining)
+		} else {
+			r.ExpiresAt = now.Add(ttl)
+		}
+		r.UpdatedAt = r.ExpiresAt.Add(-ttl)
+
+		// Same values the Mark methods write
+		value := found[i].value.Val()
+		switch status {
+		case StatusEnqueued:
+			r.Path, r.Filename = value, filenameOf(value)
+		case StatusProcessed:
+			if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+				r.UpdatedAt = time.Unix(unix, 0)
+			}
+		case StatusFailed:
+			at, reason, _ := strings.Cut(value, ":")
+			if unix, err := strconv.ParseInt(at, 10, 64); err == nil {
+				r.UpdatedAt = time.Unix(unix, 0)
+			}
+			r.Reason = reason
+		}
+		records = append(records, r)
+	}
+
+	if seen != nil {
+		return int64(len(records)), nil
+	}
+	if len(records) == 0 {
+		return 0, nil
+	}
+	_, err = s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, r := range records {
+			s.writeRecord(ctx, pipe, r)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(records)), nil
+}
+
+// ttlFor returns the configured expiry of a status
+func (s *RedisStorage) ttlFor(status string) time.Duration {
+	switch status {
+	case StatusEnqueued:
+		return s.ttl.Enqueued
+	case StatusFailed:
+		return s.ttl.Failed
+	default:
+		return s.ttl.Processed
+	}
+}
+
+// scanKeys calls fn with each SCAN batch of keys matching pattern, on
+// every master in cluster mode
+func (s *RedisStorage) scanKeys(ctx context.Context, pattern string, fn func(keys []string) error) error {
+	scan := func(ctx context.Context, client redis.Cmdable) error {
+		var cursor uint64
+		for {
+			keys, next, err := client.Scan(ctx, cursor, pattern, pruneBatch).Result()
+			if err != nil {
+				return err
+			}
+			if len(keys) > 0 {
+				if err := fn(keys); err != nil {
+					return err
+				}
+			}
+			if next == 0 {
+				return nil
+			}
+			cursor = next
+		}
+	}
+
+	cluster, ok := s.client.(*redis.ClusterClient)
+	if !ok {
+		return scan(ctx, s.client)
+	}
+
+	// Masters are scanned concurrently
+	var mu sync.Mutex
+	locked := fn
+	fn = func(keys []string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		return locked(keys)
+	}
+	return cluster.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+		return scan(ctx, client)
+	})
+}
+
+// parseRedisRecord converts the record hash fields
+func parseRedisRecord(hash string, fields map[string]string) Record {
+	updated, _ := strconv.ParseInt(fields["updated_at"], 10, 64)
+	expires, _ := strconv.ParseInt(fields["expires_at"], 10, 64)
+
+	return Record{
+		Hash:      hash,
+		Status:    fields["status"],
+		Path:      fields["path"],
+		Filename:  fields["filename"],
+		Reason:    fields["reason"],
+		UpdatedAt: time.UnixMilli(updated),
+		ExpiresAt: time.UnixMilli(expires),
+	}
+}
@@ -0,0 +1,243 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func newTestRedis(t *testing.T) (*RedisStorage, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	s, err := NewRedisStorage(RedisConfig{Addr: mr.Addr()})
+	if err != nil {
+		t.Fatalf("NewRedisStorage() failed: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+
+	// Short lease so renewal runs within the test
+	s.ttl.Lock = 300 * time.Millisecond
+	return s, mr
+}
+
+func TestRedisLock_FencingTokensIncrease(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestRedis(t)
+
+	first, err := s.GetLock(ctx, "h1")
+	if err != nil {
+		t.Fatalf("GetLock() failed: %v", err)
+	}
+	if _, err := s.GetLock(ctx, "h1"); err == nil {
+		t.Error("second GetLock() should fail while the lock is held")
+	}
+	_ = first.Release(ctx)
+
+	second, err := s.GetLock(ctx, "h1")
+	if err != nil {
+		t.Fatalf("GetLock() after Release failed: %v", err)
+	}
+	defer second.Release(ctx)
+
+	if second.Token() <= first.Token() {
+		t.Errorf("token %d after %d, want increasing", second.Token(), first.Token())
+	}
+}
+
+func TestRedisLock_RenewsLease(t *testing.T) {
+	ctx := context.Background()
+	s, mr := newTestRedis(t)
+
+	lock, err := s.GetLock(ctx, "h1")
+	if err != nil {
+		t.Fatalf("GetLock() failed: %v", err)
+	}
+	defer lock.Release(ctx)
+
+	// Age the lease, then give the keep-alive a tick to extend it
+	mr.FastForward(250 * time.Millisecond)
+	time.Sleep(150 * time.Millisecond)
+
+	if ttl := mr.TTL(s.lockKey("h1")); ttl <= 100*time.Millisecond {
+		t.Errorf("lock TTL = %v, want it renewed", ttl)
+	}
+
+	select {
+	case <-lock.Lost():
+		t.Error("lock reported lost while renewal succeeds")
+	default:
+	}
+}
+
+func TestRedisLock_LostWhenTakenOver(t *testing.T) {
+	ctx := context.Background()
+	s, mr := newTestRedis(t)
+
+	lock, err := s.GetLock(ctx, "h1")
+	if err != nil {
+		t.Fatalf("GetLock() failed: %v", err)
+	}
+	defer lock.Release(ctx)
+
+	// Another holder took the key after ours expired
+	_ = mr.Set(s.lockKey("h1"), "someone-else")
+
+	select {
+	case <-lock.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("Lost() not closed after the lock was taken over")
+	}
+
+	// Release must not delete the other holder's key
+	_ = lock.Release(ctx)
+	if got, _ := mr.Get(s.lockKey("h1")); got != "someone-else" {
+		t.Errorf("lock value = %q, want the other holder's", got)
+	}
+}
+
+func TestRedisLock_LostBeforeLeaseExpires(t *testing.T) {
+	ctx := context.Background()
+	s, mr := newTestRedis(t)
+
+	deadline := time.Now().Add(s.ttl.Lock)
+	lock, err := s.GetLock(ctx, "h1")
+	if err != nil {
+		t.Fatalf("GetLock() failed: %v", err)
+	}
+	defer lock.Release(ctx)
+
+	// Renewal can no longer reach Redis
+	mr.Close()
+
+	select {
+	case <-lock.Lost():
+	case <-time.After(time.Until(deadline)):
+		t.Fatal("Lost() not closed before the lease expired")
+	}
+}
+
+func TestRedisStorage_KeyPrefix(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+
+	a, err := NewRedisStorage(RedisConfig{Addr: mr.Addr(), KeyPrefix: "site-a:"})
+	if err != nil {
+		t.Fatalf("NewRedisStorage() failed: %v", err)
+	}
+	defer a.Close()
+	b, err := NewRedisStorage(RedisConfig{Addr: mr.Addr(), KeyPrefix: "site-b:"})
+	if err != nil {
+		t.Fatalf("NewRedisStorage() failed: %v", err)
+	}
+	defer b.Close()
+
+	if err := a.MarkProcessed(ctx, "h1"); err != nil {
+		t.Fatalf("MarkProcessed() failed: %v", err)
+	}
+	if !mr.Exists("site-a:processed:h1") {
+		t.Error("processed key not written under the prefix")
+	}
+	if processed, _ := b.IsProcessed(ctx, "h1"); processed {
+		t.Error("state leaked across key prefixes")
+	}
+
+	// Each deployment locks independently
+	lockA, err := a.GetLock(ctx, "h1")
+	if err != nil {
+		t.Fatalf("GetLock() failed: %v", err)
+	}
+	defer lockA.Release(ctx)
+	lockB, err := b.GetLock(ctx, "h1")
+	if err != nil {
+		t.Fatalf("GetLock() under another prefix failed: %v", err)
+	}
+	defer lockB.Release(ctx)
+}
+
+func TestRedisStorage_LockKeysShareSlot(t *testing.T) {
+	s, _ := newTestRedis(t)
+
+	// Standalone keeps the lock key older instances use
+	if got := s.lockKey("abc"); got != "gordon:watcher:lock:abc" {
+		t.Errorf("standalone lock key = %q, want gordon:watcher:lock:abc", got)
+	}
+
+	// Cluster slots hash only the {tag}; both keys must carry the same one
+	s.cluster = true
+	for _, key := range []string{s.lockKey("abc"), s.fencingKey("abc")} {
+		if !strings.HasSuffix(key, "{abc}") {
+			t.Errorf("key %q does not end with the {abc} hash tag", key)
+		}
+	}
+}
+
+func TestRedisLock_TokensSurviveCounterExpiry(t *testing.T) {
+	ctx := context.Background()
+	s, mr := newTestRedis(t)
+
+	first, err := s.GetLock(ctx, "h1")
+	if err != nil {
+		t.Fatalf("GetLock() failed: %v", err)
+	}
+	_ = first.Release(ctx)
+
+	// The counter expires with the state; tokens must not restart
+	mr.Del(s.fencingKey("h1"))
+
+	second, err := s.GetLock(ctx, "h1")
+	if err != nil {
+		t.Fatalf("GetLock() failed: %v", err)
+	}
+	defer second.Release(ctx)
+
+	if second.Token() <= first.Token() {
+		t.Errorf("token %d after counter expiry, want > %d", second.Token(), first.Token())
+	}
+}
+
+func TestNewRedisClient_Errors(t *testing.T) {
+	if _, err := NewRedisClient(RedisConfig{Mode: "replica"}); err == nil {
+		t.Error("expected error for unsupported mode")
+	}
+
+	ca := filepath.Join(t.TempDir(), "ca.pem")
+	_ = os.WriteFile(ca, []byte("not a certificate"), 0600)
+	_, err := NewRedisClient(RedisConfig{TLS: RedisTLSConfig{Enabled: true, CAFile: ca}})
+	if err == nil {
+		t.Error("expected error for a CA file without certificates")
+	}
+}
+
+func TestRedisStorage_ConfiguredTTLs(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+
+	s, err := NewRedisStorage(RedisConfig{
+		Addr: mr.Addr(),
+		TTL:  TTLConfig{Enqueued: time.Hour, Processed: 48 * time.Hour, Failed: 72 * time.Hour},
+	})
+	if err != nil {
+		t.Fatalf("NewRedisStorage() failed: %v", err)
+	}
+	defer s.Close()
+
+	_ = s.MarkEnqueued(ctx, "e1", "/tmp/e1")
+	_ = s.MarkProcessed(ctx, "p1")
+	_ = s.MarkFailed(ctx, "f1", "boom")
+
+	for key, want := range map[string]time.Duration{
+		s.enqueuedKey("e1"):  time.Hour,
+		s.processedKey("p1"): 48 * time.Hour,
+		s.failedKey("f1"):    72 * time.Hour,
+	} {
+		if got := mr.TTL(key); got != want {
+			t.Errorf("TTL(%s) = %v, want %v", key, got, want)
+		}
+	}
+}
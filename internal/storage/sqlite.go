@@ -0,0 +1,588 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite" // pure Go driver, registers "sqlite"
+)
+
+//go:embed migrations/sqlite/*.sql
+var sqliteMigrations embed.FS
+
+// sqliteTimeFormat sorts lexicographically and is readable from sqlite3
+const sqliteTimeFormat = "2006-01-02 15:04:05.000"
+
+// SQLiteConfig configures SQLite storage
+type SQLiteConfig struct {
+	Path string
+
+	// TTL sets how long the current state of each status is kept
+	TTL TTLConfig
+
+	// Retention keeps history events (default: the processed TTL)
+	Retention time.Duration
+
+	// RetentionInterval is how often expired rows are deleted (default 10m)
+	RetentionInterval time.Duration
+}
+
+// SQLiteStorage implements Storage and HistoryRecorder on a SQLite file in
+// WAL mode. The files table holds the current state; file_events holds
+// every status change. Locks are in-process only.
+type SQLiteStorage struct {
+	db        *sql.DB
+	ttl       TTLConfig
+	retention time.Duration
+
+	// In-process locks, same semantics as MemoryStorage
+	mu    sync.Mutex
+	locks map[string]*sqliteLock
+
+	done      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// NewSQLiteStorage opens (or creates) the database, applies pending
+// migrations and starts the retention job
+func NewSQLiteStorage(cfg SQLiteConfig) (*SQLiteStorage, error) {
+	cfg.TTL = cfg.TTL.withDefaults()
+	if cfg.Retention <= 0 {
+		cfg.Retention = cfg.TTL.Processed
+	}
+	if cfg.RetentionInterval <= 0 {
+		cfg.RetentionInterval = 10 * time.Minute
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create SQLite directory: %w", err)
+	}
+
+	dsn := "file:" + cfg.Path + "?" + url.Values{
+		"_pragma": {"journal_mode(WAL)", "busy_timeout(5000)", "synchronous(NORMAL)"},
+	}.Encode()
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite database %s: %w", cfg.Path, err)
+	}
+
+	// SQLite has a single writer; one connection avoids SQLITE_BUSY on
+	// lock upgrades and keeps writes ordered
+	db.SetMaxOpenConns(1)
+
+	if err := migrateSQLite(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	s := &SQLiteStorage{
+		db:        db,
+		ttl:       cfg.TTL,
+		retention: cfg.Retention,
+		locks:     make(map[string]*sqliteLock),
+		done:      make(chan struct{}),
+	}
+
+	s.wg.Add(1)
+	go s.retentionLoop(cfg.RetentionInterval)
+
+	return s, nil
+}
+
+// IsProcessed checks if a file hash has been processed
+func (s *SQLiteStorage) IsProcessed(ctx context.Context, hash string) (bool, error) {
+	var processed bool
+
+	err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM files WHERE hash = ? AND status = ? AND expires_at > ?
+		)`, hash, StatusProcessed, sqliteTime(time.Now())).Scan(&processed)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if processed: %w", err)
+	}
+
+	return processed, nil
+}
+
+// MarkEnqueued marks a file as enqueued
+func (s *SQLiteStorage) MarkEnqueued(ctx context.Context, hash, path string) error {
+	if err := s.setStatus(ctx, hash, StatusEnqueued, path, "", s.ttl.Enqueued); err != nil {
+		return fmt.Errorf("failed to mark as enqueued: %w", err)
+	}
+	return nil
+}
+
+// MarkProcessed marks a file as processed
+func (s *SQLiteStorage) MarkProcessed(ctx context.Context, hash string) error {
+	if err := s.setStatus(ctx, hash, StatusProcessed, "", "", s.ttl.Processed); err != nil {
+		return fmt.Errorf("failed to mark as processed: %w", err)
+	}
+	return nil
+}
+
+// MarkFailed marks a file as failed
+func (s *SQLiteStorage) MarkFailed(ctx context.Context, hash, reason string) error {
+	if err := s.setStatus(ctx, hash, StatusFailed, "", reason, s.ttl.Failed); err != nil {
+		return fmt.Errorf("failed to mark as failed: %w", err)
+	}
+	return nil
+}
+
+// RecordDetected records a stable file picked up for processing
+func (s *SQLiteStorage) RecordDetected(ctx context.Context, hash, path string) error {
+	if err := s.addEvent(ctx, s.db, hash, StatusDetected, path, ""); err != nil {
+		return fmt.Errorf("failed to record detected file: %w", err)
+	}
+	return nil
+}
+
+// RecordIgnored records a file moved to ignored
+func (s *SQLiteStorage) RecordIgnored(ctx context.Context, hash, path, reason string) error {
+	if err := s.addEvent(ctx, s.db, hash, StatusIgnored, path, reason); err != nil {
+		return fmt.Errorf("failed to record ignored file: %w", err)
+	}
+	return nil
+}
+
+// sqliteRecordColumns is the column list scanned by scanSQLiteRecord
+const sqliteRecordColumns = "hash, status, path, filename, reason, updated_at, expires_at"
+
+// Get returns the current record for a hash
+func (s *SQLiteStorage) Get(ctx context.Context, hash string) (*Record, error) {
+	row := s.db.QueryRowContext(ctx,
+		"SELECT "+sqliteRecordColumns+" FROM files WHERE hash = ? AND expires_at > ?",
+		hash, sqliteTime(time.Now()))
+
+	record, err := scanSQLiteRecord(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get record: %w", err)
+	}
+	return record, nil
+}
+
+// SearchByFilename returns records whose filename starts with prefix
+func (s *SQLiteStorage) SearchByFilename(ctx context.Context, prefix string, limit int) ([]Record, error) {
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+
+	// A byte-wise range uses the index, unlike LIKE (case-insensitive)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+sqliteRecordColumns+` FROM files
+		WHERE filename >= ? AND filename < ? AND filename != '' AND expires_at > ?
+		ORDER BY filename, hash
+		LIMIT ?`,
+		prefix, prefix+"\U0010FFFF", sqliteTime(time.Now()), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search by filename: %w", err)
+	}
+
+	records, err := scanSQLiteRecords(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search by filename: %w", err)
+	}
+	return records, nil
+}
+
+// List returns a page of records
+func (s *SQLiteStorage) List(ctx context.Context, opts ListOptions) (*Page, error) {
+	cursor, err := decodeCursor(opts.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	query := "SELECT " + sqliteRecordColumns + " FROM files WHERE expires_at > ?"
+	args := []any{sqliteTime(time.Now())}
+
+	if opts.Status != "" {
+		query += " AND status = ?"
+		args = append(args, opts.Status)
+	}
+	if !opts.Since.IsZero() {
+		query += " AND updated_at >= ?"
+		args = append(args, sqliteTime(opts.Since))
+	}
+	if !opts.Until.IsZero() {
+		query += " AND updated_at < ?"
+		args = append(args, sqliteTime(opts.Until))
+	}
+	if cursor != nil {
+		query += " AND (updated_at < ? OR (updated_at = ? AND hash < ?))"
+		at := sqliteTime(cursor.updatedAt)
+		args = append(args, at, at, cursor.hash)
+	}
+
+	limit := opts.limit()
+	query += " ORDER BY updated_at DESC, hash DESC LIMIT ?"
+	args = append(args, limit+1)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list records: %w", err)
+	}
+
+	records, err := scanSQLiteRecords(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list records: %w", err)
+	}
+
+	page := &Page{Records: records}
+	if len(records) > limit {
+		page.Records = records[:limit]
+		page.NextCursor = encodeCursor(page.Records[limit-1])
+	}
+	return page, nil
+}
+
+// Count returns the number of records with a status (empty = all)
+func (s *SQLiteStorage) Count(ctx context.Context, status string) (int64, error) {
+	query := "SELECT count(*) FROM files WHERE expires_at > ?"
+	args := []any{sqliteTime(time.Now())}
+	if status != "" {
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+
+	var n int64
+	if err := s.db.QueryRowContext(ctx, query, args...).Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count records: %w", err)
+	}
+	return n, nil
+}
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanSQLiteRecord scans the sqliteRecordColumns of one row
+func scanSQLiteRecord(row rowScanner) (*Record, error) {
+	var r Record
+	var updatedAt, expiresAt string
+
+	if err := row.Scan(&r.Hash, &r.Status, &r.Path, &r.Filename, &r.Reason, &updatedAt, &expiresAt); err != nil {
+		return nil, err
+	}
+
+	r.UpdatedAt, _ = time.Parse(sqliteTimeFormat, updatedAt)
+	r.ExpiresAt, _ = time.Parse(sqliteTimeFormat, expiresAt)
+	return &r, nil
+}
+
+// scanSQLiteRecords scans and closes rows
+func scanSQLiteRecords(rows *sql.Rows) ([]Record, error) {
+	defer rows.Close()
+
+	records := make([]Record, 0)
+	for rows.Next() {
+		r, err := scanSQLiteRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, *r)
+	}
+	return records, rows.Err()
+}
+
+// CountEntries returns the number of unexpired files per status and the
+// locks held by this process
+func (s *SQLiteStorage) CountEntries(ctx context.Context) (map[string]int64, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT status, count(*) FROM files WHERE expires_at > ? GROUP BY status", sqliteTime(time.Now()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to count entries: %w", err)
+	}
+	defer rows.Close()
+
+	counts := map[string]int64{StatusEnqueued: 0, StatusProcessed: 0, StatusFailed: 0}
+	for rows.Next() {
+		var status string
+		var n int64
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, fmt.Errorf("failed to count entries: %w", err)
+		}
+		counts[status] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count entries: %w", err)
+	}
+
+	s.mu.Lock()
+	counts["locks"] = int64(len(s.locks))
+	s.mu.Unlock()
+
+	return counts, nil
+}
+
+// Import writes records keeping their timestamps and expiry. History
+// events are not written: the source's history is not part of a Record.
+func (s *SQLiteStorage) Import(ctx context.Context, records []Record) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to import records: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, r := range records {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO files (hash, status, path, filename, reason, created_at, updated_at, expires_at)
+			VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?6, ?7)
+			ON CONFLICT (hash) DO UPDATE SET
+				status     = excluded.status,
+				path       = excluded.path,
+				filename   = excluded.filename,
+				reason     = excluded.reason,
+				updated_at = excluded.updated_at,
+				expires_at = excluded.expires_at`,
+			r.Hash, r.Status, r.Path, r.Filename, r.Reason, sqliteTime(r.UpdatedAt), sqliteTime(r.ExpiresAt))
+		if err != nil {
+			return fmt.Errorf("failed to import %s: %w", r.Hash, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to import records: %w", err)
+	}
+	return nil
+}
+
+// MaxFencingToken returns the last fencing token handed out
+func (s *SQLiteStorage) MaxFencingToken(ctx context.Context) (uint64, error) {
+	var token uint64
+	if err := s.db.QueryRowContext(ctx, "SELECT value FROM fencing_token WHERE id = 1").Scan(&token); err != nil {
+		return 0, fmt.Errorf("failed to read fencing token: %w", err)
+	}
+	return token, nil
+}
+
+// RaiseFencingToken makes later fencing tokens greater than token
+func (s *SQLiteStorage) RaiseFencingToken(ctx context.Context, token uint64) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE fencing_token SET value = MAX(value, ?) WHERE id = 1", token)
+	if err != nil {
+		return fmt.Errorf("failed to raise fencing token: %w", err)
+	}
+	return nil
+}
+
+// GetLock acquires an in-process lock
+func (s *SQLiteStorage) GetLock(ctx context.Context, hash string) (Lock, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.locks[hash]; exists {
+		return nil, fmt.Errorf("lock already held")
+	}
+
+	var token uint64
+	err := s.db.QueryRowContext(ctx,
+		"UPDATE fencing_token SET value = value + 1 WHERE id = 1 RETURNING value",
+	).Scan(&token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+
+	lock := &sqliteLock{
+		storage:    s,
+		hash:       hash,
+		token:      token,
+		lostSignal: newLostSignal(),
+	}
+
+	s.locks[hash] = lock
+	return lock, nil
+}
+
+// Close stops the retention job and closes the database
+func (s *SQLiteStorage) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.wg.Wait()
+		err = s.db.Close()
+	})
+	return err
+}
+
+// setStatus updates the current state and appends a history event in one
+// transaction. An empty path keeps the stored one.
+func (s *SQLiteStorage) setStatus(ctx context.Context, hash, status, path, reason string, ttl time.Duration) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO files (hash, status, path, filename, reason, created_at, updated_at, expires_at)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?6, ?7)
+		ON CONFLICT (hash) DO UPDATE SET
+			status     = excluded.status,
+			path       = CASE WHEN excluded.path = '' THEN files.path ELSE excluded.path END,
+			filename   = CASE WHEN excluded.path = '' THEN files.filename ELSE excluded.filename END,
+			reason     = excluded.reason,
+			updated_at = excluded.updated_at,
+			expires_at = excluded.expires_at`,
+		hash, status, path, filenameOf(path), reason, sqliteTime(now), sqliteTime(now.Add(ttl)))
+	if err != nil {
+		return err
+	}
+
+	if path == "" {
+		_ = tx.QueryRowContext(ctx, "SELECT path FROM files WHERE hash = ?", hash).Scan(&path)
+	}
+
+	if err := s.addEvent(ctx, tx, hash, status, path, reason); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// execer is satisfied by *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// addEvent appends a history event
+func (s *SQLiteStorage) addEvent(ctx context.Context, db execer, hash, status, path, reason string) error {
+	var hashValue any
+	if hash != "" {
+		hashValue = hash
+	}
+
+	_, err := db.ExecContext(ctx,
+		"INSERT INTO file_events (hash, status, path, reason, created_at) VALUES (?, ?, ?, ?, ?)",
+		hashValue, status, path, reason, sqliteTime(time.Now()))
+	return err
+}
+
+// retentionLoop deletes expired state and old events until Close
+func (s *SQLiteStorage) retentionLoop(interval time.Duration) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			_ = s.purge(time.Now())
+		}
+	}
+}
+
+// purge deletes state that expired before now and events older than the
+// retention period
+func (s *SQLiteStorage) purge(now time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM files WHERE expires_at <= ?", sqliteTime(now)); err != nil {
+		return err
+	}
+
+	_, err := s.db.ExecContext(ctx, "DELETE FROM file_events WHERE created_at <= ?", sqliteTime(now.Add(-s.retention)))
+	return err
+}
+
+// migrateSQLite applies the embedded migrations newer than PRAGMA user_version
+func migrateSQLite(db *sql.DB) error {
+	var current int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&current); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	names, err := fs.Glob(sqliteMigrations, "migrations/sqlite/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		base := filepath.Base(name)
+		version, err := strconv.Atoi(strings.SplitN(base, "_", 2)[0])
+		if err != nil {
+			return fmt.Errorf("invalid migration name %s: %w", base, err)
+		}
+		if version <= current {
+			continue
+		}
+
+		script, err := sqliteMigrations.ReadFile(name)
+		if err != nil {
+			return err
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin migration %s: %w", base, err)
+		}
+		if _, err := tx.Exec(string(script)); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("failed to apply migration %s: %w", base, err)
+		}
+		// PRAGMA does not accept bound parameters
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", version)); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("failed to record migration %s: %w", base, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %s: %w", base, err)
+		}
+	}
+
+	return nil
+}
+
+// sqliteTime formats t for the TEXT timestamp columns
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeFormat)
+}
+
+// sqliteLock implements Lock for SQLite storage. It has no lease, so it
+// is never lost.
+type sqliteLock struct {
+	storage *SQLiteStorage
+	hash    string
+	token   uint64
+
+	*lostSignal
+}
+
+// Token returns the fencing token
+func (l *sqliteLock) Token() uint64 {
+	return l.token
+}
+
+// Release releases the lock
+func (l *sqliteLock) Release(ctx context.Context) error {
+	l.storage.mu.Lock()
+	defer l.storage.mu.Unlock()
+
+	// A repeated Release must not drop a later holder's lock
+	if l.storage.locks[l.hash] == l {
+		delete(l.storage.locks, l.hash)
+	}
+	return nil
+}
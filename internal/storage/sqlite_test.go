@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestSQLite(t *testing.T) *SQLiteStorage {
+	t.Helper()
+
+	s, err := NewSQLiteStorage(SQLiteConfig{Path: filepath.Join(t.TempDir(), "state.sqlite")})
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage() failed: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func TestSQLiteStorage_History(t *testing.T) {
+	ctx := context.Background()
+	s := newTestSQLite(t)
+
+	_ = s.RecordIgnored(ctx, "", "/incoming/tiny.xml", "file_too_small")
+	_ = s.RecordDetected(ctx, "h1", "/incoming/a.xml")
+	_ = s.MarkEnqueued(ctx, "h1", "/processing/a.xml")
+	_ = s.MarkFailed(ctx, "h1", "queue_error")
+	_ = s.MarkEnqueued(ctx, "h1", "/processing/a.xml")
+	if err := s.MarkProcessed(ctx, "h1"); err != nil {
+		t.Fatalf("MarkProcessed() failed: %v", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT status, path, reason FROM file_events WHERE hash = ? ORDER BY id", "h1")
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	defer rows.Close()
+
+	var got []string
+	for rows.Next() {
+		var status, path, reason string
+		_ = rows.Scan(&status, &path, &reason)
+		got = append(got, status+":"+reason)
+		if status != StatusDetected && path != "/processing/a.xml" {
+			t.Errorf("%s event path = %q, want the processing path", status, path)
+		}
+	}
+
+	want := []string{"detected:", "enqueued:", "failed:queue_error", "enqueued:", "processed:"}
+	if len(got) != len(want) {
+		t.Fatalf("history = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("history[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	var unhashed int
+	_ = s.db.QueryRowContext(ctx, "SELECT count(*) FROM file_events WHERE hash IS NULL").Scan(&unhashed)
+	if unhashed != 1 {
+		t.Errorf("events without hash = %d, want 1", unhashed)
+	}
+
+	if processed, _ := s.IsProcessed(ctx, "h1"); !processed {
+		t.Error("IsProcessed() = false, want true")
+	}
+}
+
+func TestSQLiteStorage_Purge(t *testing.T) {
+	ctx := context.Background()
+	s := newTestSQLite(t)
+
+	_ = s.MarkEnqueued(ctx, "h1", "/processing/a.xml")
+	_ = s.MarkProcessed(ctx, "h2")
+
+	if err := s.purge(time.Now().Add(defaultTTL + time.Minute)); err != nil {
+		t.Fatalf("purge() failed: %v", err)
+	}
+
+	var files int
+	_ = s.db.QueryRowContext(ctx, "SELECT count(*) FROM files").Scan(&files)
+	if files != 1 {
+		t.Errorf("files after purge = %d, want 1 (processed kept)", files)
+	}
+
+	_ = s.purge(time.Now().Add(processedTTL + time.Minute))
+
+	var events int
+	_ = s.db.QueryRowContext(ctx, "SELECT count(*) FROM file_events").Scan(&events)
+	if events != 0 {
+		t.Errorf("events after retention = %d, want 0", events)
+	}
+}
+
+func TestSQLiteStorage_WALMode(t *testing.T) {
+	s := newTestSQLite(t)
+
+	var mode string
+	if err := s.db.QueryRow("PRAGMA journal_mode").Scan(&mode); err != nil || mode != "wal" {
+		t.Errorf("journal_mode = %q, %v; want wal", mode, err)
+	}
+}
@@ -1,6 +1,51 @@
 package storage
 
-import "context"
+import (
+	"context"
+	"time"
+)
+
+// Default TTLs, used for every zero TTLConfig field
+const (
+	defaultTTL   = 24 * time.Hour     // enqueued
+	lockTTL      = 30 * time.Second   // lock lease
+	processedTTL = 7 * 24 * time.Hour // processed and failed
+)
+
+// File statuses, shared by every backend. Records carry enqueued,
+// processed or failed; history also has detected and ignored.
+const (
+	StatusDetected  = "detected"
+	StatusEnqueued  = "enqueued"
+	StatusProcessed = "processed"
+	StatusFailed    = "failed"
+	StatusIgnored   = "ignored"
+)
+
+// TTLConfig sets how long each state is kept. Zero fields use the defaults.
+type TTLConfig struct {
+	Enqueued  time.Duration // default 24h
+	Processed time.Duration // default 7d
+	Failed    time.Duration // default 7d
+	Lock      time.Duration // lease for distributed locks, default 30s
+}
+
+// withDefaults fills zero fields with the default TTLs
+func (c TTLConfig) withDefaults() TTLConfig {
+	if c.Enqueued <= 0 {
+		c.Enqueued = defaultTTL
+	}
+	if c.Processed <= 0 {
+		c.Processed = processedTTL
+	}
+	if c.Failed <= 0 {
+		c.Failed = processedTTL
+	}
+	if c.Lock <= 0 {
+		c.Lock = lockTTL
+	}
+	return c
+}
 
 // Storage is the interface for state storage
 type Storage interface {
@@ -19,12 +64,41 @@ type Storage interface {
 	// GetLock acquires a distributed lock for a file
 	GetLock(ctx context.Context, hash string) (Lock, error)
 
+	// Querier reads back the stored state
+	Querier
+
 	// Close closes the storage connection
 	Close() error
 }
 
+// HistoryRecorder is implemented by storages that keep a status history per
+// file. The watcher reports the events that have no Storage method.
+type HistoryRecorder interface {
+	// RecordDetected records a stable file picked up for processing
+	RecordDetected(ctx context.Context, hash, path string) error
+
+	// RecordIgnored records a file moved to ignored; hash is empty when the
+	// file was rejected before it was hashed
+	RecordIgnored(ctx context.Context, hash, path, reason string) error
+}
+
+// EntryCounter is implemented by storages that can count their entries
+// cheaply. Counts are keyed by status (enqueued, processed, failed, locks).
+type EntryCounter interface {
+	CountEntries(ctx context.Context) (map[string]int64, error)
+}
+
 // Lock represents a distributed lock
 type Lock interface {
 	// Release releases the lock
 	Release(ctx context.Context) error
+
+	// Lost is closed when the lock can no longer be guaranteed, e.g. the
+	// lease could not be renewed before it expired. Work done after that
+	// may overlap with another holder.
+	Lost() <-chan struct{}
+
+	// Token returns the fencing token. Tokens increase monotonically, so a
+	// consumer can reject messages from a holder that was superseded.
+	Token() uint64
 }
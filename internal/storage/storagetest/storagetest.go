@@ -0,0 +1,418 @@
+// Package storagetest is a conformance suite for storage.Storage
+// implementations. A backend passes it by running
+//
+//	func TestConformance(t *testing.T) {
+//		storagetest.Run(t, func(t *testing.T, ttl storage.TTLConfig) storage.Storage {
+//			return newMyStorage(t, ttl)
+//		})
+//	}
+//
+// Backends that share state between runs (a database, a Redis server) can
+// clean up after the suite: every hash it writes starts with "test-".
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fabyo/gordon-watcher/internal/storage"
+)
+
+// Factory returns a storage using ttl (zero fields keep the defaults). The
+// factory registers its own cleanup; the suite closes the storage itself
+// only in the Close test.
+type Factory func(t *testing.T, ttl storage.TTLConfig) storage.Storage
+
+// shortTTL is used by the expiry checks
+const shortTTL = 500 * time.Millisecond
+
+// Run runs every conformance check against storages made by factory
+func Run(t *testing.T, factory Factory) {
+	t.Run("Lifecycle", func(t *testing.T) { testLifecycle(t, factory) })
+	t.Run("Idempotency", func(t *testing.T) { testIdempotency(t, factory) })
+	t.Run("LockExclusive", func(t *testing.T) { testLockExclusive(t, factory) })
+	t.Run("LockRelease", func(t *testing.T) { testLockRelease(t, factory) })
+	t.Run("TTL", func(t *testing.T) { testTTL(t, factory) })
+	t.Run("Query", func(t *testing.T) { testQuery(t, factory) })
+	t.Run("Import", func(t *testing.T) { testImport(t, factory) })
+	t.Run("Close", func(t *testing.T) { testClose(t, factory) })
+}
+
+// prefix returns a hash prefix unique to this run
+func prefix() string {
+	return fmt.Sprintf("test-%d-", time.Now().UnixNano())
+}
+
+func testLifecycle(t *testing.T, factory Factory) {
+	ctx := context.Background()
+	s := factory(t, storage.TTLConfig{})
+	h := prefix() + "a"
+
+	if _, err := s.Get(ctx, h); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("Get(unknown) error = %v, want ErrNotFound", err)
+	}
+	if processed, err := s.IsProcessed(ctx, h); err != nil || processed {
+		t.Fatalf("IsProcessed(unknown) = %v, %v", processed, err)
+	}
+
+	if err := s.MarkEnqueued(ctx, h, "/processing/a.xml"); err != nil {
+		t.Fatalf("MarkEnqueued() failed: %v", err)
+	}
+	expectRecord(t, s, h, storage.StatusEnqueued, "a.xml", "")
+	if processed, _ := s.IsProcessed(ctx, h); processed {
+		t.Error("enqueued file reported as processed")
+	}
+
+	if err := s.MarkFailed(ctx, h, "queue_error"); err != nil {
+		t.Fatalf("MarkFailed() failed: %v", err)
+	}
+	expectRecord(t, s, h, storage.StatusFailed, "a.xml", "queue_error")
+	if processed, _ := s.IsProcessed(ctx, h); processed {
+		t.Error("failed file reported as processed")
+	}
+
+	// A retry succeeds
+	_ = s.MarkEnqueued(ctx, h, "/processing/a.xml")
+	if err := s.MarkProcessed(ctx, h); err != nil {
+		t.Fatalf("MarkProcessed() failed: %v", err)
+	}
+	expectRecord(t, s, h, storage.StatusProcessed, "a.xml", "")
+	if processed, err := s.IsProcessed(ctx, h); err != nil || !processed {
+		t.Errorf("IsProcessed() = %v, %v, want true", processed, err)
+	}
+}
+
+func testIdempotency(t *testing.T, factory Factory) {
+	ctx := context.Background()
+	s := factory(t, storage.TTLConfig{})
+	p := prefix()
+	start := time.Now().Add(-time.Millisecond)
+
+	for i := 0; i < 2; i++ {
+		if err := s.MarkEnqueued(ctx, p+"a", "/processing/a.xml"); err != nil {
+			t.Fatalf("MarkEnqueued() #%d failed: %v", i+1, err)
+		}
+		if err := s.MarkEnqueued(ctx, p+"b", "/processing/b.xml"); err != nil {
+			t.Fatalf("MarkEnqueued() #%d failed: %v", i+1, err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if err := s.MarkProcessed(ctx, p+"a"); err != nil {
+			t.Fatalf("MarkProcessed() #%d failed: %v", i+1, err)
+		}
+		if err := s.MarkFailed(ctx, p+"b", "queue_error"); err != nil {
+			t.Fatalf("MarkFailed() #%d failed: %v", i+1, err)
+		}
+	}
+
+	expectRecord(t, s, p+"a", storage.StatusProcessed, "a.xml", "")
+	expectRecord(t, s, p+"b", storage.StatusFailed, "b.xml", "queue_error")
+
+	// One record per hash, however many times it was written
+	page, err := s.List(ctx, storage.ListOptions{Since: start})
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if got := hashes(page.Records); got != join(p+"b", p+"a") {
+		t.Errorf("List() = %s, want one record per hash", got)
+	}
+}
+
+func testLockExclusive(t *testing.T, factory Factory) {
+	ctx := context.Background()
+	s := factory(t, storage.TTLConfig{})
+	h := prefix() + "lock"
+
+	const workers = 16
+	var (
+		acquired atomic.Int32
+		start    = make(chan struct{})
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		holders  []storage.Lock
+	)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			lock, err := s.GetLock(ctx, h)
+			if err != nil {
+				return
+			}
+			acquired.Add(1)
+			mu.Lock()
+			holders = append(holders, lock)
+			mu.Unlock()
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	for _, lock := range holders {
+		defer lock.Release(ctx)
+	}
+	if n := acquired.Load(); n != 1 {
+		t.Fatalf("%d of %d concurrent GetLock calls succeeded, want exactly 1", n, workers)
+	}
+
+	select {
+	case <-holders[0].Lost():
+		t.Error("lock reported lost while held")
+	default:
+	}
+
+	// Other hashes are independent
+	other, err := s.GetLock(ctx, h+"-other")
+	if err != nil {
+		t.Fatalf("GetLock(other hash) failed: %v", err)
+	}
+	_ = other.Release(ctx)
+}
+
+func testLockRelease(t *testing.T, factory Factory) {
+	ctx := context.Background()
+	s := factory(t, storage.TTLConfig{})
+	h := prefix() + "lock"
+
+	first, err := s.GetLock(ctx, h)
+	if err != nil {
+		t.Fatalf("GetLock() failed: %v", err)
+	}
+	if _, err := s.GetLock(ctx, h); err == nil {
+		t.Fatal("GetLock() succeeded while the lock was held")
+	}
+	if err := first.Release(ctx); err != nil {
+		t.Fatalf("Release() failed: %v", err)
+	}
+
+	second, err := s.GetLock(ctx, h)
+	if err != nil {
+		t.Fatalf("GetLock() after Release failed: %v", err)
+	}
+	defer second.Release(ctx)
+
+	if second.Token() <= first.Token() {
+		t.Errorf("fencing token did not increase: %d then %d", first.Token(), second.Token())
+	}
+
+	// A stale Release must not free the new holder's lock
+	if err := first.Release(ctx); err != nil {
+		t.Errorf("repeated Release() failed: %v", err)
+	}
+	if third, err := s.GetLock(ctx, h); err == nil {
+		_ = third.Release(ctx)
+		t.Error("stale Release() freed another holder's lock")
+	}
+}
+
+func testTTL(t *testing.T, factory Factory) {
+	ctx := context.Background()
+	s := factory(t, storage.TTLConfig{Enqueued: shortTTL, Processed: shortTTL, Failed: shortTTL})
+	p := prefix()
+
+	_ = s.MarkEnqueued(ctx, p+"enqueued", "/processing/a.xml")
+	_ = s.MarkEnqueued(ctx, p+"processed", "/processing/b.xml")
+	_ = s.MarkProcessed(ctx, p+"processed")
+	_ = s.MarkEnqueued(ctx, p+"failed", "/processing/c.xml")
+	_ = s.MarkFailed(ctx, p+"failed", "queue_error")
+
+	r, err := s.Get(ctx, p+"processed")
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if ttl := r.ExpiresAt.Sub(r.UpdatedAt); ttl < shortTTL-50*time.Millisecond || ttl > shortTTL+50*time.Millisecond {
+		t.Errorf("record expires %v after its update, want %v", ttl, shortTTL)
+	}
+	if processed, _ := s.IsProcessed(ctx, p+"processed"); !processed {
+		t.Fatal("IsProcessed() = false before expiry")
+	}
+
+	time.Sleep(shortTTL + 300*time.Millisecond)
+
+	if processed, err := s.IsProcessed(ctx, p+"processed"); err != nil || processed {
+		t.Errorf("IsProcessed() = %v, %v after expiry, want false", processed, err)
+	}
+	for _, h := range []string{p + "enqueued", p + "processed", p + "failed"} {
+		if _, err := s.Get(ctx, h); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("Get(%s) error = %v after expiry, want ErrNotFound", h, err)
+		}
+	}
+}
+
+// testQuery checks the Querier ordering and filters. Counts are compared as
+// deltas and listings bounded by Since, so rows left by other runs in a
+// shared backend do not matter.
+func testQuery(t *testing.T, factory Factory) {
+	ctx := context.Background()
+	s := factory(t, storage.TTLConfig{})
+	p := prefix()
+	name := p + "qry"
+
+	countBefore, err := s.Count(ctx, "")
+	if err != nil {
+		t.Fatalf("Count() failed: %v", err)
+	}
+	failedBefore, _ := s.Count(ctx, storage.StatusFailed)
+
+	// Distinct timestamps at millisecond precision
+	step := func() { time.Sleep(5 * time.Millisecond) }
+
+	start := time.Now().Add(-time.Millisecond)
+	_ = s.MarkEnqueued(ctx, p+"1", "/processing/"+name+"-a-1.xml")
+	step()
+	_ = s.MarkEnqueued(ctx, p+"2", "/processing/"+name+"-a-2.xml")
+	step()
+	_ = s.MarkEnqueued(ctx, p+"3", "/processing/"+name+"-b-1.xml")
+	step()
+	enqueuedAll := time.Now()
+	step()
+	_ = s.MarkProcessed(ctx, p+"1")
+	step()
+	_ = s.MarkFailed(ctx, p+"2", "queue_error")
+
+	t.Run("SearchByFilename", func(t *testing.T) {
+		got, err := s.SearchByFilename(ctx, name+"-a-", 10)
+		if err != nil {
+			t.Fatalf("SearchByFilename() failed: %v", err)
+		}
+		if hashes(got) != join(p+"1", p+"2") {
+			t.Errorf("SearchByFilename() = %s, want %s", hashes(got), join(p+"1", p+"2"))
+		}
+
+		got, _ = s.SearchByFilename(ctx, name, 2)
+		if len(got) != 2 {
+			t.Errorf("SearchByFilename(limit 2) returned %d records", len(got))
+		}
+	})
+
+	t.Run("ListNewestFirst", func(t *testing.T) {
+		opts := storage.ListOptions{Since: start, Limit: 2}
+		page, err := s.List(ctx, opts)
+		if err != nil {
+			t.Fatalf("List() failed: %v", err)
+		}
+		if hashes(page.Records) != join(p+"2", p+"1") || page.NextCursor == "" {
+			t.Fatalf("first page = %s (cursor %q)", hashes(page.Records), page.NextCursor)
+		}
+
+		opts.Cursor = page.NextCursor
+		page, err = s.List(ctx, opts)
+		if err != nil {
+			t.Fatalf("List(cursor) failed: %v", err)
+		}
+		if hashes(page.Records) != p+"3" || page.NextCursor != "" {
+			t.Errorf("second page = %s (cursor %q)", hashes(page.Records), page.NextCursor)
+		}
+	})
+
+	t.Run("ListFilters", func(t *testing.T) {
+		page, _ := s.List(ctx, storage.ListOptions{Since: start, Status: storage.StatusProcessed})
+		if hashes(page.Records) != p+"1" {
+			t.Errorf("List(processed) = %s", hashes(page.Records))
+		}
+
+		page, _ = s.List(ctx, storage.ListOptions{Since: start, Until: enqueuedAll})
+		if hashes(page.Records) != p+"3" {
+			t.Errorf("List(until) = %s", hashes(page.Records))
+		}
+
+		if _, err := s.List(ctx, storage.ListOptions{Cursor: "bogus"}); !errors.Is(err, storage.ErrInvalidCursor) {
+			t.Errorf("List(bogus cursor) error = %v, want ErrInvalidCursor", err)
+		}
+	})
+
+	t.Run("Count", func(t *testing.T) {
+		total, err := s.Count(ctx, "")
+		if err != nil {
+			t.Fatalf("Count() failed: %v", err)
+		}
+		if total-countBefore != 3 {
+			t.Errorf("Count() grew by %d, want 3", total-countBefore)
+		}
+
+		failed, _ := s.Count(ctx, storage.StatusFailed)
+		if failed-failedBefore != 1 {
+			t.Errorf("Count(failed) grew by %d, want 1", failed-failedBefore)
+		}
+	})
+}
+
+// testImport checks that imported records keep their timestamps and expiry
+func testImport(t *testing.T, factory Factory) {
+	s := factory(t, storage.TTLConfig{})
+	importer, ok := s.(storage.Importer)
+	if !ok {
+		t.Skip("storage does not implement storage.Importer")
+	}
+
+	ctx := context.Background()
+	p := prefix()
+	updated := time.Now().Add(-time.Hour).Truncate(time.Millisecond)
+	expires := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+
+	err := importer.Import(ctx, []storage.Record{{
+		Hash:      p + "imported",
+		Status:    storage.StatusProcessed,
+		Path:      "/processing/imported.xml",
+		Filename:  "imported.xml",
+		UpdatedAt: updated,
+		ExpiresAt: expires,
+	}})
+	if err != nil {
+		t.Fatalf("Import() failed: %v", err)
+	}
+
+	r := expectRecord(t, s, p+"imported", storage.StatusProcessed, "imported.xml", "")
+	if r != nil && (!r.UpdatedAt.Equal(updated) || !r.ExpiresAt.Equal(expires)) {
+		t.Errorf("imported record times = %v / %v, want %v / %v", r.UpdatedAt, r.ExpiresAt, updated, expires)
+	}
+	if processed, _ := s.IsProcessed(ctx, p+"imported"); !processed {
+		t.Error("imported processed record not reported by IsProcessed")
+	}
+}
+
+func testClose(t *testing.T, factory Factory) {
+	s := factory(t, storage.TTLConfig{})
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("second Close() failed: %v", err)
+	}
+}
+
+// expectRecord checks the current record of a hash and returns it
+func expectRecord(t *testing.T, s storage.Storage, hash, status, filename, reason string) *storage.Record {
+	t.Helper()
+
+	r, err := s.Get(context.Background(), hash)
+	if err != nil {
+		t.Errorf("Get(%s) failed: %v", hash, err)
+		return nil
+	}
+	if r.Status != status || r.Filename != filename || r.Reason != reason {
+		t.Errorf("Get(%s) = %s/%s/%q, want %s/%s/%q", hash, r.Status, r.Filename, r.Reason, status, filename, reason)
+	}
+	return r
+}
+
+func hashes(records []storage.Record) string {
+	list := make([]string, 0, len(records))
+	for _, r := range records {
+		list = append(list, r.Hash)
+	}
+	return join(list...)
+}
+
+func join(hashes ...string) string {
+	return strings.Join(hashes, ",")
+}
@@ -0,0 +1,73 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/fabyo/gordon-watcher/internal/metrics"
+)
+
+// Stability modes decide when a detected file is complete
+const (
+	// StabilityModePoll waits until size and mtime stop changing
+	StabilityModePoll = "poll"
+
+	// StabilityModeCloseWrite waits until the writer closes the file
+	// (inotify IN_CLOSE_WRITE) or renames it into place (IN_MOVED_TO).
+	// Linux only; other systems and unsupported filesystems use poll.
+	StabilityModeCloseWrite = "close_write"
+)
+
+// errCompletionUnsupported is returned where no close-write detector exists
+var errCompletionUnsupported = errors.New("close-write detection is not supported on this platform")
+
+// completionDetector reports when a writer has finished with a file
+type completionDetector interface {
+	// Add starts tracking files written in dir
+	Add(dir string) error
+
+	// Wait blocks until path is complete. ok is false when the detector
+	// cannot tell for this path (the directory is not tracked or events
+	// were lost) and the caller should fall back to polling.
+	Wait(ctx context.Context, path string) (done, ok bool)
+
+	Close() error
+}
+
+// waitForCompletion waits until a file is fully written, using the
+// close-write detector when the source asks for it and the stability poll
+// otherwise
+func (w *Watcher) waitForCompletion(ctx context.Context, src *source, path string) bool {
+	if w.detector != nil && src.StabilityMode == StabilityModeCloseWrite {
+		start := time.Now()
+		if done, ok := w.detector.Wait(ctx, path); ok {
+			if done {
+				metrics.FileStabilityDuration.Observe(time.Since(start).Seconds())
+			}
+			return done
+		}
+		w.cfg.Logger.Debug("Close-write detection unavailable, polling for stability", "path", path)
+	}
+
+	return src.stability.WaitForStability(ctx, path)
+}
+
+// addWatch registers a directory with fsnotify and, for close_write
+// sources, the close-write detector. A detector failure only disables
+// close-write detection there.
+func (w *Watcher) addWatch(dir string) error {
+	if err := w.fsWatcher.Add(dir); err != nil {
+		return err
+	}
+
+	if w.detector != nil && w.sourceOf(dir).StabilityMode == StabilityModeCloseWrite {
+		if err := w.detector.Add(dir); err != nil {
+			w.cfg.Logger.Warn("Close-write detection unavailable for directory, polling for stability",
+				"path", dir,
+				"error", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,246 @@
+//go:build linux
+
+package watcher
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// completedRetention is how long a close-write is remembered for a file
+// nobody has waited on yet
+const completedRetention = time.Minute
+
+// completionResult is sent to a waiter when its file is settled
+type completionResult struct {
+	done, ok bool
+}
+
+// inotifyDetector tracks IN_CLOSE_WRITE and IN_MOVED_TO on its own inotify
+// instance, next to the fsnotify watcher that reports file creation
+type inotifyDetector struct {
+	// The fd is non-blocking, so reads go through the runtime poller and
+	// Close interrupts them. file.Fd() would switch it back to blocking.
+	fd   int
+	file *os.File
+
+	mu        sync.Mutex
+	dirs      map[string]int // dir -> watch descriptor
+	paths     map[int]string // watch descriptor -> dir
+	completed map[string]time.Time
+	waiters   map[string][]chan completionResult
+
+	done chan struct{}
+}
+
+// newCompletionDetector starts an inotify based close-write detector
+func newCompletionDetector() (completionDetector, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("inotify_init1: %w", err)
+	}
+
+	d := &inotifyDetector{
+		fd:        fd,
+		file:      os.NewFile(uintptr(fd), "inotify"),
+		dirs:      make(map[string]int),
+		paths:     make(map[int]string),
+		completed: make(map[string]time.Time),
+		waiters:   make(map[string][]chan completionResult),
+		done:      make(chan struct{}),
+	}
+
+	go d.readLoop()
+
+	return d, nil
+}
+
+// Add starts tracking files written in dir
+func (d *inotifyDetector) Add(dir string) error {
+	dir = filepath.Clean(dir)
+
+	const mask = unix.IN_CLOSE_WRITE | unix.IN_MOVED_TO | unix.IN_MOVED_FROM | unix.IN_DELETE
+	wd, err := unix.InotifyAddWatch(d.fd, dir, mask)
+	if err != nil {
+		return fmt.Errorf("inotify_add_watch %s: %w", dir, err)
+	}
+
+	d.mu.Lock()
+	d.dirs[dir] = wd
+	d.paths[wd] = dir
+	d.mu.Unlock()
+
+	return nil
+}
+
+// Wait blocks until the writer of path closes it or it is renamed into place
+func (d *inotifyDetector) Wait(ctx context.Context, path string) (bool, bool) {
+	d.mu.Lock()
+
+	if _, tracked := d.dirs[filepath.Dir(path)]; !tracked {
+		d.mu.Unlock()
+		return false, false
+	}
+
+	// Checked under the lock so a close-write can't slip in between the
+	// check and the waiter registration
+	info, err := os.Stat(path)
+	if err != nil {
+		d.mu.Unlock()
+		return false, true
+	}
+
+	// Closed after the last write: nothing is writing it anymore
+	if at, ok := d.completed[path]; ok && !at.Before(info.ModTime()) {
+		d.mu.Unlock()
+		return true, true
+	}
+
+	ch := make(chan completionResult, 1)
+	d.waiters[path] = append(d.waiters[path], ch)
+	d.mu.Unlock()
+
+	select {
+	case res := <-ch:
+		return res.done, res.ok
+	case <-ctx.Done():
+		d.removeWaiter(path, ch)
+		return false, true
+	}
+}
+
+// Close stops the detector
+func (d *inotifyDetector) Close() error {
+	err := d.file.Close()
+	<-d.done
+	return err
+}
+
+// removeWaiter drops a waiter that gave up
+func (d *inotifyDetector) removeWaiter(path string, ch chan completionResult) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	waiters := d.waiters[path]
+	for i, w := range waiters {
+		if w == ch {
+			waiters = append(waiters[:i], waiters[i+1:]...)
+			break
+		}
+	}
+
+	if len(waiters) == 0 {
+		delete(d.waiters, path)
+	} else {
+		d.waiters[path] = waiters
+	}
+}
+
+// readLoop decodes inotify events until the file is closed
+func (d *inotifyDetector) readLoop() {
+	defer close(d.done)
+
+	buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+
+	for {
+		n, err := d.file.Read(buf)
+		if err != nil {
+			// Closed or broken: nobody will be notified anymore
+			d.failAll()
+			return
+		}
+
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			raw := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + unix.SizeofInotifyEvent
+			name := string(bytes.TrimRight(buf[nameStart:nameStart+int(raw.Len)], "\x00"))
+			offset = nameStart + int(raw.Len)
+
+			d.handle(int(raw.Wd), raw.Mask, name)
+		}
+	}
+}
+
+// handle applies one inotify event
+func (d *inotifyDetector) handle(wd int, mask uint32, name string) {
+	// Events were dropped; nobody can be told reliably
+	if mask&unix.IN_Q_OVERFLOW != 0 {
+		d.failAll()
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	dir, ok := d.paths[wd]
+	if !ok {
+		return
+	}
+
+	// The directory was removed or unmounted
+	if mask&unix.IN_IGNORED != 0 {
+		delete(d.paths, wd)
+		if d.dirs[dir] == wd {
+			delete(d.dirs, dir)
+		}
+		return
+	}
+
+	if name == "" || mask&unix.IN_ISDIR != 0 {
+		return
+	}
+
+	path := filepath.Join(dir, name)
+
+	switch {
+	case mask&(unix.IN_CLOSE_WRITE|unix.IN_MOVED_TO) != 0:
+		d.completed[path] = time.Now()
+		d.notify(path, completionResult{done: true, ok: true})
+		d.prune()
+	case mask&(unix.IN_MOVED_FROM|unix.IN_DELETE) != 0:
+		delete(d.completed, path)
+		d.notify(path, completionResult{done: false, ok: true})
+	}
+}
+
+// notify wakes every waiter of path. Callers hold d.mu.
+func (d *inotifyDetector) notify(path string, res completionResult) {
+	for _, ch := range d.waiters[path] {
+		ch <- res
+	}
+	delete(d.waiters, path)
+}
+
+// failAll sends every waiter back to polling and forgets past close-writes
+func (d *inotifyDetector) failAll() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for path := range d.waiters {
+		d.notify(path, completionResult{done: false, ok: false})
+	}
+	d.completed = make(map[string]time.Time)
+}
+
+// prune forgets close-writes nobody waited on, such as files moved to
+// ignored before their stability check. Callers hold d.mu.
+func (d *inotifyDetector) prune() {
+	if len(d.completed) < 1024 {
+		return
+	}
+
+	cutoff := time.Now().Add(-completedRetention)
+	for path, at := range d.completed {
+		if at.Before(cutoff) {
+			delete(d.completed, path)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+//go:build linux
+
+package watcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fabyo/gordon-watcher/internal/logger"
+)
+
+func newTestDetector(t *testing.T) (completionDetector, string) {
+	t.Helper()
+
+	d, err := newCompletionDetector()
+	if err != nil {
+		t.Fatalf("newCompletionDetector() failed: %v", err)
+	}
+	t.Cleanup(func() { _ = d.Close() })
+
+	dir := t.TempDir()
+	if err := d.Add(dir); err != nil {
+		t.Fatalf("Add() failed: %v", err)
+	}
+	return d, dir
+}
+
+func TestInotifyDetector_WaitsForClose(t *testing.T) {
+	d, dir := newTestDetector(t)
+	path := filepath.Join(dir, "slow.xml")
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("<nfe>"); err != nil {
+		t.Fatal(err)
+	}
+
+	type result struct{ done, ok bool }
+	results := make(chan result, 1)
+	go func() {
+		done, ok := d.Wait(context.Background(), path)
+		results <- result{done, ok}
+	}()
+
+	// The writer still holds the file, however long it pauses
+	select {
+	case r := <-results:
+		t.Fatalf("Wait() returned %+v while the file was open", r)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	if _, err := f.WriteString("</nfe>"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case r := <-results:
+		if !r.done || !r.ok {
+			t.Errorf("Wait() = %+v, want done", r)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Wait() did not return after close")
+	}
+}
+
+func TestInotifyDetector_AlreadyClosed(t *testing.T) {
+	d, dir := newTestDetector(t)
+	path := filepath.Join(dir, "fast.xml")
+
+	if err := os.WriteFile(path, []byte("<nfe/>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if done, ok := d.Wait(ctx, path); !done || !ok {
+		t.Errorf("Wait() = (%v, %v), want (true, true)", done, ok)
+	}
+}
+
+func TestInotifyDetector_MovedIn(t *testing.T) {
+	d, dir := newTestDetector(t)
+	path := filepath.Join(dir, "moved.xml")
+
+	// Written elsewhere on the same filesystem, then renamed into place
+	tmp := filepath.Join(dir, "..", filepath.Base(dir)+"-upload.tmp")
+	if err := os.WriteFile(tmp, []byte("<nfe/>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Remove(tmp) })
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if done, ok := d.Wait(ctx, path); !done || !ok {
+		t.Errorf("Wait() = (%v, %v), want (true, true)", done, ok)
+	}
+}
+
+func TestInotifyDetector_Untracked(t *testing.T) {
+	d, _ := newTestDetector(t)
+	path := filepath.Join(t.TempDir(), "other.xml")
+
+	if err := os.WriteFile(path, []byte("<nfe/>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if done, ok := d.Wait(context.Background(), path); done || ok {
+		t.Errorf("Wait() = (%v, %v), want (false, false) for an untracked directory", done, ok)
+	}
+}
+
+func TestInotifyDetector_Cancelled(t *testing.T) {
+	d, dir := newTestDetector(t)
+	path := filepath.Join(dir, "open.xml")
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if done, ok := d.Wait(ctx, path); done || !ok {
+		t.Errorf("Wait() = (%v, %v), want (false, true) after cancel", done, ok)
+	}
+}
+
+func TestWatcher_CloseWriteMode(t *testing.T) {
+	tmpDir := t.TempDir()
+	incoming := filepath.Join(tmpDir, "incoming")
+
+	w, err := New(Config{
+		Paths:         []string{incoming},
+		StabilityMode: StabilityModeCloseWrite,
+		MinFileSize:   1,
+		MaxFileSize:   1024,
+		// Polling would take far longer than the test waits
+		StableAttempts:    5,
+		StableDelay:       10 * time.Second,
+		CleanupInterval:   1 * time.Minute,
+		MaxWorkers:        1,
+		MaxFilesPerSecond: 10,
+		WorkingDir:        tmpDir,
+		Queue:             &MockQueue{},
+		Storage:           &MockStorage{processed: make(map[string]bool)},
+		Logger:            logger.New(logger.Config{Level: "error", Format: "text", Output: "stdout"}),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	if w.detector == nil {
+		t.Fatal("close-write detector not initialized")
+	}
+
+	if err := w.Start(context.Background()); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	defer func() { _ = w.Stop(context.Background()) }()
+
+	if err := os.WriteFile(filepath.Join(incoming, "nfe.xml"), []byte("<nfe/>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	processing := filepath.Join(tmpDir, "processing", "nfe.xml")
+	deadline := time.Now().Add(3 * time.Second)
+	for !isRegularFile(processing) {
+		if time.Now().After(deadline) {
+			t.Fatal("closed file was not picked up")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
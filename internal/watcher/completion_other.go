@@ -0,0 +1,9 @@
+//go:build !linux
+
+package watcher
+
+// newCompletionDetector is only implemented on Linux; the watcher falls
+// back to the stability poll
+func newCompletionDetector() (completionDetector, error) {
+	return nil, errCompletionUnsupported
+}
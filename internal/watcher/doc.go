@@ -9,9 +9,10 @@ through SHA256 hashing, and publishes them to a message queue for downstream pro
 The watcher follows an event-driven architecture with the following components:
 
   - fsnotify integration for real-time file system events
+  - Directory polling for network filesystems (NFS, SMB, FUSE)
   - Worker pool for concurrent file processing
   - Rate limiter to prevent system overload
-  - Stability checker to ensure files are fully written
+  - Stability checker (or inotify close-write on Linux) to ensure files are fully written
   - Circuit breaker for resilient queue publishing
   - Distributed locks via Redis for multi-instance coordination
 
@@ -41,6 +42,10 @@ The watcher follows an event-driven architecture with the following components:
 	// Graceful shutdown
 	defer w.Stop(context.Background())
 
+Watch paths that need their own patterns, size limits, stability mode or rate
+limit are listed as Sources instead of Paths; unset Source fields inherit the
+top-level settings.
+
 # File Processing Flow
 
 1. File detected in monitored directory
@@ -51,7 +56,7 @@ The watcher follows an event-driven architecture with the following components:
 6. Distributed lock acquisition
 7. Move to processing directory
 8. Publish to message queue (with retry + circuit breaker)
-9. File remains in processing until external worker completes
+9. File moves to processed or failed once the consumer reports a result
 
 # Resilience Features
 
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package watcher
+
+import "os"
+
+// inodeOf returns 0 where inode numbers are not available; changes are
+// then detected by size and mtime only
+func inodeOf(info os.FileInfo) uint64 {
+	return 0
+}
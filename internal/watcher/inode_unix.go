@@ -0,0 +1,17 @@
+//go:build unix
+
+package watcher
+
+import (
+	"os"
+	"syscall"
+)
+
+// inodeOf returns the inode number of a file, so a file replaced under the
+// same name with the same size and mtime is still seen as changed
+func inodeOf(info os.FileInfo) uint64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Ino)
+	}
+	return 0
+}
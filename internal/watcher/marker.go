@@ -0,0 +1,204 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fabyo/gordon-watcher/internal/metrics"
+)
+
+// StabilityModeMarker submits a file only once a marker file written by
+// the uploader (such as file.xml.done) appears next to it
+const StabilityModeMarker = "marker"
+
+// Marker actions once the data file has been taken
+const (
+	MarkerActionDelete  = "delete"
+	MarkerActionArchive = "archive"
+)
+
+// Marker defaults
+const (
+	DefaultMarkerTimeout      = 30 * time.Minute
+	DefaultMarkerPollInterval = time.Second
+)
+
+// MarkerConfig configures the done-marker completion protocol
+type MarkerConfig struct {
+	// Templates name the marker of a data file. {name} is the full file
+	// name and {stem} the name without its last extension, so for
+	// nfe.xml "{name}.done" is nfe.xml.done and "{stem}.ok" is nfe.ok.
+	// Any one existing marker completes the file.
+	Templates []string
+
+	// Timeout after which a data file without marker is moved to failed
+	Timeout time.Duration
+
+	// PollInterval is how often the marker is looked for
+	PollInterval time.Duration
+
+	// Action is delete (default) or archive
+	Action string
+
+	// ArchiveDir receives markers with the archive action
+	// (default: <working_dir>/markers)
+	ArchiveDir string
+}
+
+// markerMatcher renders and recognizes marker names
+type markerMatcher struct {
+	templates []string
+	patterns  []*regexp.Regexp
+}
+
+// newMarkerMatcher validates the templates
+func newMarkerMatcher(templates []string) (*markerMatcher, error) {
+	if len(templates) == 0 {
+		return nil, errors.New("at least one marker template is required")
+	}
+
+	m := &markerMatcher{}
+	for _, tmpl := range templates {
+		if !strings.Contains(tmpl, "{name}") && !strings.Contains(tmpl, "{stem}") {
+			return nil, fmt.Errorf("marker template %q must contain {name} or {stem}", tmpl)
+		}
+		if tmpl == "{name}" {
+			return nil, fmt.Errorf("marker template %q names the data file itself", tmpl)
+		}
+		if strings.ContainsAny(tmpl, `/\`) {
+			return nil, fmt.Errorf("marker template %q must be a file name, not a path", tmpl)
+		}
+
+		// {name} and {stem} match any non-empty name
+		expr := regexp.QuoteMeta(tmpl)
+		expr = strings.ReplaceAll(expr, regexp.QuoteMeta("{name}"), ".+")
+		expr = strings.ReplaceAll(expr, regexp.QuoteMeta("{stem}"), ".+")
+
+		m.templates = append(m.templates, tmpl)
+		m.patterns = append(m.patterns, regexp.MustCompile("^"+expr+"$"))
+	}
+
+	return m, nil
+}
+
+// markersFor returns the marker paths that complete the data file at path
+func (m *markerMatcher) markersFor(path string) []string {
+	dir, name := filepath.Split(path)
+	stem := strings.TrimSuffix(name, filepath.Ext(name))
+
+	markers := make([]string, 0, len(m.templates))
+	for _, tmpl := range m.templates {
+		marker := strings.NewReplacer("{name}", name, "{stem}", stem).Replace(tmpl)
+		markers = append(markers, filepath.Join(dir, marker))
+	}
+	return markers
+}
+
+// isMarker reports whether a file name has the shape of a marker. Markers
+// are never processed as data files.
+func (m *markerMatcher) isMarker(path string) bool {
+	name := filepath.Base(path)
+	for _, p := range m.patterns {
+		if p.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// existingMarker returns the first marker of path that exists
+func (m *markerMatcher) existingMarker(path string) (string, bool) {
+	for _, marker := range m.markersFor(path) {
+		if isRegularFile(marker) {
+			return marker, true
+		}
+	}
+	return "", false
+}
+
+// waitForMarker waits until a marker of path appears. A file still without
+// marker when ctx times out is moved to failed.
+func (w *Watcher) waitForMarker(ctx context.Context, src *source, path string) bool {
+	start := time.Now()
+
+	ticker := time.NewTicker(src.Marker.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Files we extracted from a ZIP carry no marker of their own
+		if _, ok := w.markerExempt.LoadAndDelete(path); ok {
+			return true
+		}
+
+		if _, ok := src.markers.existingMarker(path); ok {
+			metrics.FileStabilityDuration.Observe(time.Since(start).Seconds())
+			return true
+		}
+
+		if !isRegularFile(path) {
+			// Taken or removed by someone else
+			return false
+		}
+
+		select {
+		case <-ctx.Done():
+			if w.ctx.Err() == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				w.cfg.Logger.Warn("Marker did not appear in time", "path", path, "timeout", src.Marker.Timeout)
+				metrics.MarkerTimeouts.Inc()
+				_ = w.moveToFailed(path, "marker_timeout")
+			}
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+// finishMarker deletes or archives the markers of a data file once the
+// file has left its watch directory. Markers of a file still in place are
+// kept so it can be picked up again.
+func (w *Watcher) finishMarker(path string) {
+	src := w.sourceOf(path)
+	if src.markers == nil || isRegularFile(path) {
+		return
+	}
+
+	for _, marker := range src.markers.markersFor(path) {
+		if !isRegularFile(marker) {
+			continue
+		}
+
+		if src.Marker.Action == MarkerActionArchive {
+			dest := filepath.Join(src.Marker.ArchiveDir, filepath.Base(marker))
+			if err := moveFile(marker, dest); err != nil {
+				w.cfg.Logger.Error("Failed to archive marker", "marker", marker, "dest", dest, "error", err)
+			} else {
+				w.cfg.Logger.Debug("Marker archived", "marker", marker, "dest", dest)
+			}
+			continue
+		}
+
+		if err := os.Remove(marker); err != nil {
+			w.cfg.Logger.Error("Failed to delete marker", "marker", marker, "error", err)
+		} else {
+			w.cfg.Logger.Debug("Marker deleted", "marker", marker)
+		}
+	}
+}
+
+// markerArchiveDirs returns the archive directories of sources that keep
+// their markers
+func (w *Watcher) markerArchiveDirs() []string {
+	var dirs []string
+	for _, src := range w.sources {
+		if src.markers != nil && src.Marker.Action == MarkerActionArchive {
+			dirs = append(dirs, src.Marker.ArchiveDir)
+		}
+	}
+	return dirs
+}
@@ -0,0 +1,194 @@
+package watcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fabyo/gordon-watcher/internal/logger"
+)
+
+func TestMarkerMatcher(t *testing.T) {
+	m, err := newMarkerMatcher([]string{"{name}.done", "{stem}.ok"})
+	if err != nil {
+		t.Fatalf("newMarkerMatcher() failed: %v", err)
+	}
+
+	got := m.markersFor("/in/nfe.xml")
+	want := []string{"/in/nfe.xml.done", "/in/nfe.ok"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("markersFor() = %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/in/nfe.xml.done", true},
+		{"/in/nfe.ok", true},
+		{"/in/nfe.xml", false},
+		{"/in/.done", false},
+	}
+	for _, tt := range tests {
+		if got := m.isMarker(tt.path); got != tt.want {
+			t.Errorf("isMarker(%s) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	for _, invalid := range [][]string{nil, {"done"}, {"{name}"}, {"markers/{name}.done"}} {
+		if _, err := newMarkerMatcher(invalid); err == nil {
+			t.Errorf("newMarkerMatcher(%q) succeeded, want error", invalid)
+		}
+	}
+}
+
+func newMarkerTestWatcher(t *testing.T, marker MarkerConfig) (*Watcher, string) {
+	t.Helper()
+
+	tmpDir := t.TempDir()
+	marker.PollInterval = 20 * time.Millisecond
+
+	w, err := New(Config{
+		Paths:             []string{filepath.Join(tmpDir, "incoming")},
+		StabilityMode:     StabilityModeMarker,
+		Marker:            marker,
+		MinFileSize:       1,
+		MaxFileSize:       1024,
+		CleanupInterval:   1 * time.Minute,
+		MaxWorkers:        1,
+		MaxFilesPerSecond: 10,
+		WorkerQueueSize:   10,
+		WorkingDir:        tmpDir,
+		Queue:             &MockQueue{},
+		Storage:           &MockStorage{processed: make(map[string]bool)},
+		Logger:            logger.New(logger.Config{Level: "error", Format: "text", Output: "stdout"}),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+
+	if err := w.Start(context.Background()); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	t.Cleanup(func() { _ = w.Stop(context.Background()) })
+
+	return w, tmpDir
+}
+
+// waitForFile polls until path exists
+func waitForFile(t *testing.T, path string) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !isRegularFile(path) {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s did not appear", path)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWatcher_MarkerMode(t *testing.T) {
+	_, tmpDir := newMarkerTestWatcher(t, MarkerConfig{
+		Templates: []string{"{name}.done", "{stem}.ok"},
+		Action:    MarkerActionArchive,
+	})
+	incoming := filepath.Join(tmpDir, "incoming")
+
+	data := filepath.Join(incoming, "nfe.xml")
+	if err := os.WriteFile(data, []byte("<nfe/>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Held back until the marker shows up
+	time.Sleep(200 * time.Millisecond)
+	if !isRegularFile(data) {
+		t.Fatal("file was taken before its marker appeared")
+	}
+
+	if err := os.WriteFile(filepath.Join(incoming, "nfe.ok"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForFile(t, filepath.Join(tmpDir, "processing", "nfe.xml"))
+	waitForFile(t, filepath.Join(tmpDir, "markers", "nfe.ok"))
+
+	if isRegularFile(filepath.Join(incoming, "nfe.ok")) {
+		t.Error("marker left in the watch directory")
+	}
+	if isRegularFile(filepath.Join(tmpDir, "ignored", "nfe.ok")) {
+		t.Error("marker was treated as a data file")
+	}
+}
+
+func TestWatcher_MarkerTimeout(t *testing.T) {
+	_, tmpDir := newMarkerTestWatcher(t, MarkerConfig{
+		Templates: []string{"{name}.done"},
+		Timeout:   100 * time.Millisecond,
+	})
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "incoming", "orphan.xml"), []byte("<nfe/>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForFile(t, filepath.Join(tmpDir, "failed", "orphan.xml"))
+}
+
+func TestWatcher_MarkerExistingFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	incoming := filepath.Join(tmpDir, "incoming")
+	if err := os.MkdirAll(incoming, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Left over from before a restart: one complete, one still uploading
+	for name, content := range map[string]string{
+		"ready.xml":      "<nfe/>",
+		"ready.xml.done": "",
+		"partial.xml":    "<nf",
+	} {
+		if err := os.WriteFile(filepath.Join(incoming, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w, err := New(Config{
+		Paths:             []string{incoming},
+		StabilityMode:     StabilityModeMarker,
+		Marker:            MarkerConfig{Templates: []string{"{name}.done"}, PollInterval: 20 * time.Millisecond},
+		MinFileSize:       1,
+		MaxFileSize:       1024,
+		CleanupInterval:   1 * time.Minute,
+		MaxWorkers:        1,
+		MaxFilesPerSecond: 10,
+		WorkerQueueSize:   10,
+		WorkingDir:        tmpDir,
+		Queue:             &MockQueue{},
+		Storage:           &MockStorage{processed: make(map[string]bool)},
+		Logger:            logger.New(logger.Config{Level: "error", Format: "text", Output: "stdout"}),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	if err := w.Start(context.Background()); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	defer func() { _ = w.Stop(context.Background()) }()
+
+	waitForFile(t, filepath.Join(tmpDir, "processing", "ready.xml"))
+
+	deadline := time.Now().Add(time.Second)
+	for isRegularFile(filepath.Join(incoming, "ready.xml.done")) {
+		if time.Now().After(deadline) {
+			t.Fatal("marker was not deleted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if !isRegularFile(filepath.Join(incoming, "partial.xml")) {
+		t.Error("file without marker was taken")
+	}
+}
@@ -35,6 +35,7 @@ func (w *Watcher) ensureDirectories() {
 		filepath.Join(w.cfg.WorkingDir, w.cfg.SubDirs.Failed),
 		filepath.Join(w.cfg.WorkingDir, w.cfg.SubDirs.Ignored),
 		filepath.Join(w.cfg.WorkingDir, w.cfg.SubDirs.Tmp),
+		w.originsDir(),
 	}
 
 	for _, dir := range dirs {
@@ -0,0 +1,99 @@
+package watcher
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// originsDirName holds one small file per file in processing naming the
+// watch path it was detected in and its path below it, so orphans go back
+// where they came from after a restart
+const originsDirName = ".origins"
+
+// ErrProcessingCollision is returned when a file with the same name is
+// already in processing
+var ErrProcessingCollision = errors.New("a file with the same name is already in processing")
+
+// origin is the content of an origin record
+type origin struct {
+	Source string `json:"source"`
+	Path   string `json:"path"` // relative to Source
+}
+
+// originsDir returns the directory of the origin records
+func (w *Watcher) originsDir() string {
+	return filepath.Join(w.cfg.WorkingDir, originsDirName)
+}
+
+// claimProcessing reserves the processing name of a file for the duration
+// of its move. Processing is flat, so files with the same name from two
+// sources (or two subdirectories) would overwrite each other and their
+// origin records.
+func (w *Watcher) claimProcessing(destPath string) (func(), error) {
+	name := filepath.Base(destPath)
+	if _, busy := w.moving.LoadOrStore(name, struct{}{}); busy {
+		return nil, fmt.Errorf("%s: %w", name, ErrProcessingCollision)
+	}
+	if _, err := os.Lstat(destPath); err == nil {
+		w.moving.Delete(name)
+		return nil, fmt.Errorf("%s: %w", name, ErrProcessingCollision)
+	}
+	return func() { w.moving.Delete(name) }, nil
+}
+
+// recordOrigin remembers the source of a file about to enter processing
+func (w *Watcher) recordOrigin(path string) {
+	source := w.sourceFor(path)
+	if source == "" {
+		return
+	}
+
+	rel, err := filepath.Rel(source, path)
+	if err != nil {
+		rel = filepath.Base(path)
+	}
+
+	data, err := json.Marshal(origin{Source: source, Path: rel})
+	if err != nil {
+		return
+	}
+
+	record := filepath.Join(w.originsDir(), filepath.Base(path))
+	if err := os.WriteFile(record, data, 0644); err != nil {
+		w.cfg.Logger.Warn("Failed to record file origin", "path", path, "error", err)
+	}
+}
+
+// originOf returns where a file in processing came from. Files without a
+// record, or whose source is no longer configured, go to the first watch
+// path under their own name.
+func (w *Watcher) originOf(processingPath string) string {
+	name := filepath.Base(processingPath)
+
+	var o origin
+	data, err := os.ReadFile(filepath.Join(w.originsDir(), name))
+	if err == nil && json.Unmarshal(data, &o) == nil && filepath.IsLocal(o.Path) {
+		for _, s := range w.sources {
+			if s.Path == o.Source {
+				return filepath.Join(o.Source, o.Path)
+			}
+		}
+	}
+	return filepath.Join(w.cfg.Paths[0], name)
+}
+
+// clearOrigin drops the origin record of a file that left processing
+func (w *Watcher) clearOrigin(processingPath string) {
+	processingDir := filepath.Join(w.cfg.WorkingDir, w.cfg.SubDirs.Processing)
+	if filepath.Dir(filepath.Clean(processingPath)) != filepath.Clean(processingDir) {
+		return
+	}
+
+	err := os.Remove(filepath.Join(w.originsDir(), filepath.Base(processingPath)))
+	if err != nil && !os.IsNotExist(err) {
+		w.cfg.Logger.Warn("Failed to remove file origin", "path", processingPath, "error", err)
+	}
+}
@@ -0,0 +1,137 @@
+package watcher
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// PatternSet is an ordered list of file pattern rules. Each rule is one of
+//
+//	*.xml             glob on the file name (no slash)
+//	*/nfe/**/*.xml    glob on the path relative to the watch root; ** spans
+//	                  any number of directories
+//	re:nfe/.*\.xml    regular expression on the relative path, anchored at
+//	                  both ends
+//
+// and may be negated with a leading "!". The last matching rule decides, so
+// a negation after a broader rule carves files back out of it.
+type PatternSet struct {
+	rules []patternRule
+}
+
+// patternRule is one compiled rule of a PatternSet
+type patternRule struct {
+	negate bool
+	match  func(rel string) bool
+}
+
+// CompilePatterns compiles patterns into a PatternSet
+func CompilePatterns(patterns []string) (*PatternSet, error) {
+	set := &PatternSet{}
+
+	for _, raw := range patterns {
+		pattern, negate := strings.CutPrefix(raw, "!")
+		if pattern == "" {
+			return nil, fmt.Errorf("pattern %q is empty", raw)
+		}
+
+		match, err := compilePattern(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %q: %w", raw, err)
+		}
+
+		set.rules = append(set.rules, patternRule{negate: negate, match: match})
+	}
+
+	return set, nil
+}
+
+// compilePattern returns a matcher on the slash-separated relative path
+func compilePattern(pattern string) (func(rel string) bool, error) {
+	if expr, ok := strings.CutPrefix(pattern, "re:"); ok {
+		re, err := regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString, nil
+	}
+
+	// Without a slash the pattern applies to the file name, in any directory
+	if !strings.Contains(pattern, "/") {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, err
+		}
+		return func(rel string) bool {
+			return matchPattern(path.Base(rel), pattern)
+		}, nil
+	}
+
+	segments := strings.Split(strings.TrimPrefix(pattern, "/"), "/")
+	for _, seg := range segments {
+		if seg == "**" {
+			continue
+		}
+		if strings.Contains(seg, "**") {
+			return nil, fmt.Errorf("** must be a whole path segment")
+		}
+		if _, err := path.Match(seg, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(rel string) bool {
+		return matchSegments(segments, strings.Split(rel, "/"))
+	}, nil
+}
+
+// matchSegments matches path segments against glob segments, where **
+// matches zero or more whole segments
+func matchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Collapse repeated ** and try every possible split
+			for len(pattern) > 0 && pattern[0] == "**" {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := range parts {
+				if matchSegments(pattern, parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(parts) == 0 || !matchPattern(parts[0], pattern[0]) {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+
+	return len(parts) == 0
+}
+
+// Match reports whether the last rule matching rel is not a negation, and
+// whether any rule matched at all. rel is relative to the watch root and
+// slash-separated.
+func (s *PatternSet) Match(rel string) (included, matched bool) {
+	if s == nil {
+		return false, false
+	}
+	for _, r := range s.rules {
+		if r.match(rel) {
+			included, matched = !r.negate, true
+		}
+	}
+	return included, matched
+}
+
+// Empty reports whether the set has no rules
+func (s *PatternSet) Empty() bool {
+	return s == nil || len(s.rules) == 0
+}
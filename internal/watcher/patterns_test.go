@@ -0,0 +1,82 @@
+package watcher
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestPatternSet_Match(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		rel      string
+		want     bool
+	}{
+		{"file name glob", []string{"*.xml"}, "a/b/nfe.xml", true},
+		{"file name glob is case-insensitive on extension", []string{"*.xml"}, "NFE.XML", true},
+		{"file name glob mismatch", []string{"*.xml"}, "nfe.json", false},
+		{"single-level directory", []string{"*/nfe/*.xml"}, "partner/nfe/1.xml", true},
+		{"single-level directory too deep", []string{"*/nfe/*.xml"}, "a/partner/nfe/1.xml", false},
+		{"doublestar any depth", []string{"**/nfe/*.xml"}, "a/b/nfe/1.xml", true},
+		{"doublestar zero directories", []string{"**/nfe/*.xml"}, "nfe/1.xml", true},
+		{"doublestar in the middle", []string{"nfe/**/*.xml"}, "nfe/2024/05/1.xml", true},
+		{"doublestar subtree", []string{"archive/**"}, "archive/old/1.xml", true},
+		{"doublestar other subtree", []string{"archive/**"}, "incoming/1.xml", false},
+		{"leading slash is the root", []string{"/nfe/*.xml"}, "nfe/1.xml", true},
+		{"regex anchored", []string{`re:nfe/\d+\.xml`}, "nfe/123.xml", true},
+		{"regex anchored at start", []string{`re:nfe/\d+\.xml`}, "x/nfe/123.xml", false},
+		{"regex anchored at end", []string{`re:nfe/\d+\.xml`}, "nfe/123.xml.bak", false},
+		{"negation carves out", []string{"**/*.xml", "!test/**"}, "test/1.xml", false},
+		{"negation leaves the rest", []string{"**/*.xml", "!test/**"}, "prod/1.xml", true},
+		{"later rule wins", []string{"!test/**", "**/*.xml"}, "test/1.xml", true},
+		{"re-include after negation", []string{"*.xml", "!**/drafts/**", "**/drafts/final-*.xml"}, "a/drafts/final-1.xml", true},
+		{"only negations include nothing", []string{"!*.tmp"}, "nfe.xml", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set, err := CompilePatterns(tt.patterns)
+			if err != nil {
+				t.Fatalf("CompilePatterns(%q) failed: %v", tt.patterns, err)
+			}
+			if got, _ := set.Match(tt.rel); got != tt.want {
+				t.Errorf("Match(%q) with %q = %v, want %v", tt.rel, tt.patterns, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompilePatterns_Invalid(t *testing.T) {
+	for _, pattern := range []string{"!", "[", "nfe/[/*.xml", "re:(", "a/x**/b"} {
+		if _, err := CompilePatterns([]string{pattern}); err == nil {
+			t.Errorf("CompilePatterns(%q) succeeded, want error", pattern)
+		}
+	}
+}
+
+func TestMatchesPatterns_RelativeToRoot(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "incoming")
+
+	include, _ := CompilePatterns([]string{"*/nfe/**/*.xml"})
+	exclude, _ := CompilePatterns([]string{".*", "re:.*/tmp/.*"})
+	w := &Watcher{
+		cfg:     Config{Paths: []string{root}},
+		sources: []*source{{Source: Source{Path: root}, include: include, exclude: exclude}},
+	}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{filepath.Join(root, "partner", "nfe", "1.xml"), true},
+		{filepath.Join(root, "partner", "nfe", "2024", "1.xml"), true},
+		{filepath.Join(root, "nfe", "1.xml"), false},
+		{filepath.Join(root, "partner", "nfe", ".1.xml"), false},
+		{filepath.Join(root, "partner", "tmp", "nfe", "1.xml"), false},
+	}
+	for _, tt := range tests {
+		if got := w.matchesPatterns(tt.path); got != tt.want {
+			t.Errorf("matchesPatterns(%s) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
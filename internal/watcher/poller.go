@@ -0,0 +1,159 @@
+package watcher
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/fabyo/gordon-watcher/internal/metrics"
+)
+
+// Watch modes for a watch path
+const (
+	WatchModeFSNotify = "fsnotify"
+	WatchModePoll     = "poll"
+	WatchModeBoth     = "both"
+)
+
+// DefaultPollInterval is used when a polled path has no interval set
+const DefaultPollInterval = 10 * time.Second
+
+// PathOptions configures how a single watch path is monitored
+type PathOptions struct {
+	// Mode is fsnotify (default), poll or both. Network filesystems
+	// (NFS, SMB, FUSE) often deliver no fsnotify events and need poll.
+	Mode string
+
+	// PollInterval is the time between directory scans in poll and both modes
+	PollInterval time.Duration
+}
+
+// fileSnapshot is what the poller remembers about a file between scans
+type fileSnapshot struct {
+	size    int64
+	modTime time.Time
+	inode   uint64
+}
+
+// poller detects new and changed files under a root by comparing
+// directory listings between scans
+type poller struct {
+	root     string
+	snapshot map[string]fileSnapshot
+}
+
+// newPoller creates a poller with an empty snapshot
+func newPoller(root string) *poller {
+	return &poller{
+		root:     root,
+		snapshot: make(map[string]fileSnapshot),
+	}
+}
+
+// scan walks the root and returns files that are new or whose size, mtime
+// or inode changed since the previous scan. Files that disappeared are
+// forgotten, so a file moved back in is reported again.
+func (p *poller) scan() ([]string, error) {
+	current := make(map[string]fileSnapshot, len(p.snapshot))
+	var changed []string
+
+	err := filepath.WalkDir(p.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Entries can vanish between listing and stat; keep walking
+			if path == p.root {
+				return err
+			}
+			return nil
+		}
+		if d.IsDir() || !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+
+		snap := fileSnapshot{
+			size:    info.Size(),
+			modTime: info.ModTime(),
+			inode:   inodeOf(info),
+		}
+		current[path] = snap
+
+		if prev, ok := p.snapshot[path]; !ok || prev != snap {
+			changed = append(changed, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p.snapshot = current
+	return changed, nil
+}
+
+// pollLoop scans a watch path every interval and feeds new and changed
+// files into the same pipeline as fsnotify events
+func (w *Watcher) pollLoop(p *poller, interval time.Duration) {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := p.scan()
+			if err != nil {
+				w.cfg.Logger.Error("Failed to poll path", "path", p.root, "error", err)
+				metrics.WatcherErrors.Inc()
+				continue
+			}
+
+			for _, path := range changed {
+				w.handlePolled(path)
+			}
+		}
+	}
+}
+
+// handlePolled handles a file reported by the poller
+func (w *Watcher) handlePolled(path string) {
+	// The file may have been picked up and moved since the scan
+	if !isRegularFile(path) {
+		return
+	}
+
+	ctx, span := w.tracer.Start(w.ctx, "handlePolled")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("event.path", path))
+
+	w.handleFile(ctx, path)
+}
+
+// pathOptions returns the options of a watch path with defaults applied
+func (w *Watcher) pathOptions(root string) PathOptions {
+	src := w.sourceOf(root)
+	opts := PathOptions{Mode: src.Mode, PollInterval: src.PollInterval}
+	if opts.Mode == "" {
+		opts.Mode = WatchModeFSNotify
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultPollInterval
+	}
+	return opts
+}
+
+// isRegularFile reports whether path is an existing regular file
+func isRegularFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
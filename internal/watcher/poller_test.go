@@ -0,0 +1,163 @@
+package watcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fabyo/gordon-watcher/internal/logger"
+)
+
+func TestPoller_Scan(t *testing.T) {
+	root := t.TempDir()
+	existing := filepath.Join(root, "existing.xml")
+	if err := os.WriteFile(existing, []byte("<a/>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p := newPoller(root)
+
+	changed, err := p.scan()
+	if err != nil {
+		t.Fatalf("scan() failed: %v", err)
+	}
+	if !reflect.DeepEqual(changed, []string{existing}) {
+		t.Errorf("first scan = %v, want %v", changed, []string{existing})
+	}
+
+	// Nothing changed
+	if changed, _ := p.scan(); len(changed) != 0 {
+		t.Errorf("unchanged scan = %v, want none", changed)
+	}
+
+	// New file in a new subdirectory
+	nested := filepath.Join(root, "partner", "nfe.xml")
+	if err := os.MkdirAll(filepath.Dir(nested), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(nested, []byte("<nfe/>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if changed, _ := p.scan(); !reflect.DeepEqual(changed, []string{nested}) {
+		t.Errorf("scan after create = %v, want %v", changed, []string{nested})
+	}
+
+	// Size change
+	if err := os.WriteFile(existing, []byte("<a>more</a>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if changed, _ := p.scan(); !reflect.DeepEqual(changed, []string{existing}) {
+		t.Errorf("scan after write = %v, want %v", changed, []string{existing})
+	}
+
+	// Same size, new mtime
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(nested, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if changed, _ := p.scan(); !reflect.DeepEqual(changed, []string{nested}) {
+		t.Errorf("scan after touch = %v, want %v", changed, []string{nested})
+	}
+
+	// Removed and recreated with the same content is reported again
+	if err := os.Remove(existing); err != nil {
+		t.Fatal(err)
+	}
+	if changed, _ := p.scan(); len(changed) != 0 {
+		t.Errorf("scan after remove = %v, want none", changed)
+	}
+	if err := os.WriteFile(existing, []byte("<a>more</a>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if changed, _ := p.scan(); !reflect.DeepEqual(changed, []string{existing}) {
+		t.Errorf("scan after recreate = %v, want %v", changed, []string{existing})
+	}
+}
+
+func TestPoller_ReplacedFile(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "nfe.xml")
+	if err := os.WriteFile(path, []byte("<v1/>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inodeOf(info) == 0 {
+		t.Skip("inode numbers not available")
+	}
+
+	p := newPoller(root)
+	if _, err := p.scan(); err != nil {
+		t.Fatalf("scan() failed: %v", err)
+	}
+
+	// Replace atomically with same size and mtime; only the inode differs
+	tmp := filepath.Join(t.TempDir(), "nfe.xml")
+	if err := os.WriteFile(tmp, []byte("<v2/>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(tmp, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+
+	if changed, _ := p.scan(); !reflect.DeepEqual(changed, []string{path}) {
+		t.Errorf("scan after replace = %v, want %v", changed, []string{path})
+	}
+}
+
+func TestWatcher_PollMode(t *testing.T) {
+	tmpDir := t.TempDir()
+	incoming := filepath.Join(tmpDir, "incoming")
+
+	w, err := New(Config{
+		Paths: []string{incoming},
+		PathOptions: map[string]PathOptions{
+			incoming + "/": {Mode: WatchModePoll, PollInterval: 20 * time.Millisecond},
+		},
+		MinFileSize:       1,
+		MaxFileSize:       1024,
+		StableAttempts:    2,
+		StableDelay:       20 * time.Millisecond,
+		CleanupInterval:   1 * time.Minute,
+		MaxWorkers:        1,
+		MaxFilesPerSecond: 10,
+		WorkingDir:        tmpDir,
+		Queue:             &MockQueue{},
+		Storage:           &MockStorage{processed: make(map[string]bool)},
+		Logger:            logger.New(logger.Config{Level: "error", Format: "text", Output: "stdout"}),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+
+	if got := w.pathOptions(incoming).Mode; got != WatchModePoll {
+		t.Fatalf("pathOptions(%s).Mode = %q, want %q", incoming, got, WatchModePoll)
+	}
+
+	if err := w.Start(context.Background()); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	defer func() { _ = w.Stop(context.Background()) }()
+
+	// Not seen by fsnotify, since the path is only polled
+	if err := os.WriteFile(filepath.Join(incoming, "nfe.xml"), []byte("<nfe/>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	processing := filepath.Join(tmpDir, "processing", "nfe.xml")
+	deadline := time.Now().Add(5 * time.Second)
+	for !isRegularFile(processing) {
+		if time.Now().After(deadline) {
+			t.Fatal("polled file was not picked up")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
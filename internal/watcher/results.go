@@ -0,0 +1,142 @@
+package watcher
+
+import (
+	"path/filepath"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/fabyo/gordon-watcher/internal/metrics"
+	"github.com/fabyo/gordon-watcher/internal/queue"
+)
+
+// resultRetryDelay is how long a result whose file could not be moved waits
+// before it is returned to the broker
+var resultRetryDelay = time.Second
+
+// resultLoop applies consumer results until the channel is closed
+func (w *Watcher) resultLoop(results <-chan queue.Result) {
+	defer w.wg.Done()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+
+		case res, ok := <-results:
+			if !ok {
+				w.cfg.Logger.Warn("Results channel closed")
+				return
+			}
+
+			// Results whose file could not be moved are not acked but
+			// returned to the broker after a pause, to be retried
+			if err := w.handleResult(res); err != nil {
+				select {
+				case <-w.ctx.Done():
+					return
+				case <-time.After(resultRetryDelay):
+				}
+				if res.Nack != nil {
+					res.Nack()
+				}
+				continue
+			}
+			if res.Ack != nil {
+				res.Ack()
+			}
+		}
+	}
+}
+
+// handleResult moves a file out of processing according to the consumer
+// result. It returns an error only when the file could not be moved, in
+// which case storage is left untouched.
+func (w *Watcher) handleResult(res queue.Result) error {
+	ctx, span := w.tracer.Start(w.ctx, "handleResult")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("message.id", res.ID),
+		attribute.String("result.status", string(res.Status)),
+	)
+
+	if res.ID == "" {
+		w.cfg.Logger.Warn("Ignoring result without message ID", "path", res.Path)
+		return nil
+	}
+
+	path, ok := w.resolveResultPath(res)
+	if !ok {
+		w.cfg.Logger.Warn("Ignoring result for unknown file",
+			"hash", res.ID,
+			"path", res.Path)
+		return nil
+	}
+
+	switch res.Status {
+	case queue.ResultSuccess:
+		if err := w.moveToProcessed(path); err != nil {
+			w.pending.Store(res.ID, path)
+			return err
+		}
+
+		if err := w.cfg.Storage.MarkProcessed(ctx, res.ID); err != nil {
+			w.cfg.Logger.Error("Failed to mark as processed", "hash", res.ID, "error", err)
+			metrics.StorageErrors.Inc()
+		}
+
+		metrics.ResultsSucceeded.Inc()
+
+		w.cfg.Logger.Info("File processed by consumer", "path", path, "hash", res.ID)
+
+	case queue.ResultFailure:
+		reason := res.Reason
+		if reason == "" {
+			reason = "consumer_failure"
+		}
+
+		if err := w.moveToFailed(path, reason); err != nil {
+			w.pending.Store(res.ID, path)
+			return err
+		}
+
+		if err := w.cfg.Storage.MarkFailed(ctx, res.ID, reason); err != nil {
+			w.cfg.Logger.Error("Failed to mark as failed", "hash", res.ID, "error", err)
+			metrics.StorageErrors.Inc()
+		}
+
+		metrics.ResultsFailed.Inc()
+
+	default:
+		w.cfg.Logger.Warn("Ignoring result with unknown status",
+			"hash", res.ID,
+			"status", res.Status)
+	}
+
+	return nil
+}
+
+// resolveResultPath finds the processing path of the file a result refers to.
+// Files published before a restart are not tracked, so fall back to the file
+// name reported by the consumer within the processing directory. ok is false
+// unless the path is an existing regular file directly in processing.
+func (w *Watcher) resolveResultPath(res queue.Result) (string, bool) {
+	processingDir := filepath.Join(w.cfg.WorkingDir, w.cfg.SubDirs.Processing)
+
+	var path string
+	if p, ok := w.pending.LoadAndDelete(res.ID); ok {
+		path = p.(string)
+	} else {
+		name := filepath.Base(res.Path)
+		if res.Path == "" || name == "." || name == ".." || name == string(filepath.Separator) {
+			return "", false
+		}
+		path = filepath.Join(processingDir, name)
+	}
+
+	if filepath.Dir(filepath.Clean(path)) != filepath.Clean(processingDir) || !isRegularFile(path) {
+		return "", false
+	}
+	return path, true
+}
@@ -0,0 +1,262 @@
+package watcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fabyo/gordon-watcher/internal/logger"
+	"github.com/fabyo/gordon-watcher/internal/queue"
+)
+
+func newResultsTestWatcher(t *testing.T) (*Watcher, *MockStorage, string) {
+	t.Helper()
+
+	tmpDir := t.TempDir()
+	store := &MockStorage{processed: make(map[string]bool)}
+
+	w, err := New(Config{
+		Paths:             []string{filepath.Join(tmpDir, "incoming")},
+		MaxWorkers:        1,
+		MaxFilesPerSecond: 10,
+		WorkingDir:        tmpDir,
+		CleanupInterval:   1 * time.Minute,
+		Queue:             &MockQueue{},
+		Storage:           store,
+		Logger:            logger.New(logger.Config{Level: "info", Format: "text", Output: "stdout"}),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+
+	if err := w.createDirectories(); err != nil {
+		t.Fatalf("Failed to create directories: %v", err)
+	}
+
+	return w, store, tmpDir
+}
+
+func TestHandleResult_Success(t *testing.T) {
+	w, store, tmpDir := newResultsTestWatcher(t)
+
+	processingPath := filepath.Join(tmpDir, "processing", "done.xml")
+	if err := os.WriteFile(processingPath, []byte("<xml/>"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	w.pending.Store("hash-1", processingPath)
+
+	if err := w.handleResult(queue.Result{ID: "hash-1", Status: queue.ResultSuccess}); err != nil {
+		t.Fatalf("handleResult() failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(tmpDir, "processed", "done.xml")); err != nil {
+		t.Errorf("File was not moved to processed: %v", err)
+	}
+
+	if !store.processed["hash-1"] {
+		t.Error("File was not marked as processed")
+	}
+
+	if _, ok := w.pending.Load("hash-1"); ok {
+		t.Error("Pending entry was not removed")
+	}
+}
+
+func TestHandleResult_Failure(t *testing.T) {
+	w, store, tmpDir := newResultsTestWatcher(t)
+
+	// Simulate a result for a file published before a restart (not pending)
+	processingPath := filepath.Join(tmpDir, "processing", "bad.xml")
+	if err := os.WriteFile(processingPath, []byte("<xml/>"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	if err := w.handleResult(queue.Result{
+		ID:     "hash-2",
+		Path:   processingPath,
+		Status: queue.ResultFailure,
+		Reason: "invalid document",
+	}); err != nil {
+		t.Fatalf("handleResult() failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(tmpDir, "failed", "bad.xml")); err != nil {
+		t.Errorf("File was not moved to failed: %v", err)
+	}
+
+	if store.processed["hash-2"] {
+		t.Error("Failed file should not be marked as processed")
+	}
+}
+
+func TestHandleResult_RejectsUnknownPath(t *testing.T) {
+	w, store, tmpDir := newResultsTestWatcher(t)
+
+	inFlight := filepath.Join(tmpDir, "processing", "in-flight.xml")
+	if err := os.WriteFile(inFlight, []byte("<xml/>"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	for _, path := range []string{"", ".", "..", "/", filepath.Join(tmpDir, "processing"), "missing.xml"} {
+		if err := w.handleResult(queue.Result{ID: "hash-3", Path: path, Status: queue.ResultSuccess}); err != nil {
+			t.Errorf("handleResult(%q) failed: %v", path, err)
+		}
+	}
+
+	if !isRegularFile(inFlight) {
+		t.Error("in-flight file was moved by a result for another file")
+	}
+	if store.processed["hash-3"] {
+		t.Error("result without a file was marked as processed")
+	}
+}
+
+func TestResultLoop_AcksAfterApplying(t *testing.T) {
+	w, store, tmpDir := newResultsTestWatcher(t)
+
+	processingPath := filepath.Join(tmpDir, "processing", "acked.xml")
+	if err := os.WriteFile(processingPath, []byte("<xml/>"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	w.pending.Store("hash-1", processingPath)
+
+	results := make(chan queue.Result)
+	acked := make(chan bool, 1)
+
+	w.wg.Add(1)
+	go w.resultLoop(results)
+
+	results <- queue.Result{ID: "hash-1", Status: queue.ResultSuccess, Ack: func() {
+		// The result must be applied by the time it is acked
+		acked <- store.processed["hash-1"] && isRegularFile(filepath.Join(tmpDir, "processed", "acked.xml"))
+	}}
+	close(results)
+	w.wg.Wait()
+
+	select {
+	case applied := <-acked:
+		if !applied {
+			t.Error("Result was acked before the file was moved and marked")
+		}
+	default:
+		t.Error("Result was not acked")
+	}
+}
+
+func TestResultLoop_RedeliversWhenMoveFails(t *testing.T) {
+	w, store, tmpDir := newResultsTestWatcher(t)
+
+	prev := resultRetryDelay
+	resultRetryDelay = 10 * time.Millisecond
+	t.Cleanup(func() { resultRetryDelay = prev })
+
+	processingPath := filepath.Join(tmpDir, "processing", "stuck.xml")
+	if err := os.WriteFile(processingPath, []byte("<xml/>"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	w.pending.Store("hash-1", processingPath)
+
+	// Without the processed directory the rename fails
+	processedDir := filepath.Join(tmpDir, "processed")
+	if err := os.RemoveAll(processedDir); err != nil {
+		t.Fatal(err)
+	}
+
+	// Broker stand-in: a nacked result is checked, then delivered again
+	results := make(chan queue.Result, 1)
+	acked := make(chan bool, 1)
+	var res queue.Result
+	res = queue.Result{
+		ID:     "hash-1",
+		Status: queue.ResultSuccess,
+		Ack:    func() { acked <- isRegularFile(filepath.Join(processedDir, "stuck.xml")) },
+		Nack: func() {
+			if store.processed["hash-1"] {
+				t.Error("File was marked as processed although it was not moved")
+			}
+			if !isRegularFile(processingPath) {
+				t.Error("File left processing")
+			}
+			if err := os.MkdirAll(processedDir, 0755); err != nil {
+				t.Error(err)
+			}
+			results <- res
+		},
+	}
+
+	w.wg.Add(1)
+	go w.resultLoop(results)
+	results <- res
+
+	select {
+	case moved := <-acked:
+		if !moved {
+			t.Error("Result was acked before the file was moved")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Result was not redelivered and acked")
+	}
+	close(results)
+	w.wg.Wait()
+
+	if !store.processed["hash-1"] {
+		t.Error("Redelivered result was not applied")
+	}
+}
+
+func TestProcessFile_TracksPendingOnlyWithResults(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		queue   queue.Queue
+		pending bool
+	}{
+		{"results", &mockResultQueue{results: make(chan queue.Result)}, true},
+		{"results disabled", &mockResultQueue{}, false},
+		{"no results", &MockQueue{}, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			w, err := New(Config{
+				Paths:             []string{filepath.Join(tmpDir, "incoming")},
+				FilePatterns:      []string{"*.xml"},
+				MaxFileSize:       1024,
+				MaxWorkers:        1,
+				MaxFilesPerSecond: 10,
+				WorkingDir:        tmpDir,
+				CleanupInterval:   1 * time.Minute,
+				Queue:             tt.queue,
+				Storage:           &MockStorage{processed: make(map[string]bool)},
+				Logger:            logger.New(logger.Config{Level: "error", Format: "text", Output: "stdout"}),
+			})
+			if err != nil {
+				t.Fatalf("Failed to create watcher: %v", err)
+			}
+			if err := w.createDirectories(); err != nil {
+				t.Fatal(err)
+			}
+			w.subscribeResults()
+
+			path := filepath.Join(tmpDir, "incoming", "a.xml")
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte("<xml/>"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			hash, err := w.calculateHash(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := w.processFile(context.Background(), path); err != nil {
+				t.Fatalf("processFile() failed: %v", err)
+			}
+
+			if _, ok := w.pending.Load(hash); ok != tt.pending {
+				t.Errorf("pending entry present = %v, want %v", ok, tt.pending)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package watcher
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 )
@@ -24,7 +25,14 @@ func DefaultRetryConfig() RetryConfig {
 	}
 }
 
-// Retry retries a function with exponential backoff
+// permanentError is implemented by errors that retrying cannot fix, such
+// as a message the broker cannot route
+type permanentError interface {
+	Permanent() bool
+}
+
+// Retry retries a function with exponential backoff. Permanent errors are
+// returned without retrying.
 func Retry(ctx context.Context, cfg RetryConfig, fn func() error) error {
 	var lastErr error
 	delay := cfg.InitialDelay
@@ -38,6 +46,11 @@ func Retry(ctx context.Context, cfg RetryConfig, fn func() error) error {
 
 		lastErr = err
 
+		var perm permanentError
+		if errors.As(err, &perm) && perm.Permanent() {
+			return err
+		}
+
 		// Last attempt, don't wait
 		if attempt == cfg.MaxAttempts-1 {
 			break
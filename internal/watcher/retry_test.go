@@ -3,6 +3,7 @@ package watcher
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 )
@@ -195,3 +196,29 @@ func TestRetry_ZeroAttempts(t *testing.T) {
 		t.Error("Expected error with zero max attempts")
 	}
 }
+
+// permanentTestError is an error that retrying cannot fix
+type permanentTestError struct{}
+
+func (permanentTestError) Error() string   { return "unroutable" }
+func (permanentTestError) Permanent() bool { return true }
+
+func TestRetry_PermanentError(t *testing.T) {
+	attempts := 0
+	fn := func() error {
+		attempts++
+		return fmt.Errorf("publish: %w", permanentTestError{})
+	}
+
+	cfg := DefaultRetryConfig()
+	cfg.InitialDelay = 10 * time.Millisecond
+
+	err := Retry(context.Background(), cfg, fn)
+	if !errors.As(err, &permanentTestError{}) {
+		t.Errorf("Expected the permanent error, got %v", err)
+	}
+
+	if attempts != 1 {
+		t.Errorf("Expected 1 attempt, got %d", attempts)
+	}
+}
@@ -0,0 +1,229 @@
+package watcher
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// Source is a watch path with its own pipeline settings. Zero values
+// inherit the top-level Config, so a source only lists what differs.
+type Source struct {
+	// Path to watch
+	Path string
+
+	// File patterns to match and to exclude (see PatternSet)
+	FilePatterns    []string
+	ExcludePatterns []string
+
+	// File size constraints (bytes)
+	MinFileSize int64
+	MaxFileSize int64
+
+	// Stability check settings
+	StabilityMode  string
+	StableAttempts int
+	StableDelay    time.Duration
+
+	// Marker configures the marker stability mode; fields left empty
+	// inherit Config.Marker
+	Marker MarkerConfig
+
+	// Watch mode and poll interval; empty inherits Config.PathOptions
+	Mode         string
+	PollInterval time.Duration
+
+	// MaxFilesPerSecond gives the source a rate limit of its own instead
+	// of sharing the global one
+	MaxFilesPerSecond int
+}
+
+// source is a Source with its settings resolved and matchers built
+type source struct {
+	Source
+
+	include   *PatternSet
+	exclude   *PatternSet
+	markers   *markerMatcher
+	stability *StabilityChecker
+	rateLimit *RateLimiter
+}
+
+// buildSources resolves each configured source against the top-level
+// settings. Sources that do not override patterns, stability or rate limit
+// share the watcher-wide ones.
+func (w *Watcher) buildSources(include, exclude *PatternSet) error {
+	w.sources = make([]*source, 0, len(w.cfg.Sources))
+
+	for _, raw := range w.cfg.Sources {
+		src := raw
+		if err := inheritSource(&w.cfg, &src); err != nil {
+			return fmt.Errorf("source %s: %w", raw.Path, err)
+		}
+
+		s := &source{
+			Source:    src,
+			include:   include,
+			exclude:   exclude,
+			stability: w.stability,
+			rateLimit: w.rateLimit,
+		}
+
+		var err error
+		if raw.FilePatterns != nil {
+			if s.include, err = CompilePatterns(src.FilePatterns); err != nil {
+				return fmt.Errorf("source %s: file_patterns: %w", raw.Path, err)
+			}
+		}
+		if raw.ExcludePatterns != nil {
+			if s.exclude, err = CompilePatterns(src.ExcludePatterns); err != nil {
+				return fmt.Errorf("source %s: exclude_patterns: %w", raw.Path, err)
+			}
+		}
+
+		if src.StabilityMode == StabilityModeMarker {
+			// Templates were checked by inheritSource
+			s.markers, _ = newMarkerMatcher(src.Marker.Templates)
+		}
+
+		if raw.StableAttempts != 0 || raw.StableDelay != 0 {
+			s.stability = NewStabilityChecker(src.StableAttempts, src.StableDelay)
+		}
+		if raw.MaxFilesPerSecond != 0 {
+			s.rateLimit = NewRateLimiter(src.MaxFilesPerSecond)
+		}
+
+		w.sources = append(w.sources, s)
+	}
+
+	return nil
+}
+
+// sourceOf returns the source a file belongs to. Files outside every watch
+// path fall back to the first source.
+func (w *Watcher) sourceOf(path string) *source {
+	root := w.sourceFor(path)
+	for _, s := range w.sources {
+		if s.Path == root {
+			return s
+		}
+	}
+	return w.sources[0]
+}
+
+// usesStabilityMode reports whether any source uses mode
+func (w *Watcher) usesStabilityMode(mode string) bool {
+	for _, s := range w.sources {
+		if s.StabilityMode == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveSources turns the flat configuration into one implicit source per
+// watch path when no sources are listed, and derives the watch paths from
+// the sources otherwise
+func resolveSources(cfg *Config) error {
+	if len(cfg.Sources) == 0 {
+		for _, path := range cfg.Paths {
+			cfg.Sources = append(cfg.Sources, Source{Path: path})
+		}
+		return nil
+	}
+
+	seen := make(map[string]bool, len(cfg.Sources))
+	cfg.Paths = make([]string, 0, len(cfg.Sources))
+	for _, src := range cfg.Sources {
+		if src.Path == "" {
+			return fmt.Errorf("source path is required")
+		}
+		if seen[filepath.Clean(src.Path)] {
+			return fmt.Errorf("source %s: duplicate path", src.Path)
+		}
+		seen[filepath.Clean(src.Path)] = true
+		cfg.Paths = append(cfg.Paths, src.Path)
+	}
+
+	return nil
+}
+
+// inheritSource fills the unset settings of src from the top-level cfg
+// and validates the result
+func inheritSource(cfg *Config, src *Source) error {
+	if src.FilePatterns == nil {
+		src.FilePatterns = cfg.FilePatterns
+	}
+	if src.ExcludePatterns == nil {
+		src.ExcludePatterns = cfg.ExcludePatterns
+	}
+	if src.MinFileSize == 0 {
+		src.MinFileSize = cfg.MinFileSize
+	}
+	if src.MaxFileSize == 0 {
+		src.MaxFileSize = cfg.MaxFileSize
+	}
+	if src.StableAttempts == 0 {
+		src.StableAttempts = cfg.StableAttempts
+	}
+	if src.StableDelay == 0 {
+		src.StableDelay = cfg.StableDelay
+	}
+	if src.MaxFilesPerSecond == 0 {
+		src.MaxFilesPerSecond = cfg.MaxFilesPerSecond
+	}
+	if src.MinFileSize < 0 || src.MaxFileSize < 0 || src.MaxFilesPerSecond < 0 {
+		return fmt.Errorf("sizes and max_files_per_second must not be negative")
+	}
+
+	opts := cfg.PathOptions[filepath.Clean(src.Path)]
+	if src.Mode == "" {
+		src.Mode = opts.Mode
+	}
+	if src.PollInterval == 0 {
+		src.PollInterval = opts.PollInterval
+	}
+	switch src.Mode {
+	case "", WatchModeFSNotify, WatchModePoll, WatchModeBoth:
+	default:
+		return fmt.Errorf("unknown mode %q", src.Mode)
+	}
+	if src.PollInterval < 0 {
+		return fmt.Errorf("poll interval must not be negative")
+	}
+
+	if src.StabilityMode == "" {
+		src.StabilityMode = cfg.StabilityMode
+	}
+	switch src.StabilityMode {
+	case "", StabilityModePoll, StabilityModeCloseWrite:
+	case StabilityModeMarker:
+		inheritMarker(&src.Marker, cfg.Marker)
+		if err := validateMarkerConfig(&src.Marker, cfg.WorkingDir); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown stability mode %q", src.StabilityMode)
+	}
+
+	return nil
+}
+
+// inheritMarker fills the unset fields of a source's marker settings
+func inheritMarker(m *MarkerConfig, parent MarkerConfig) {
+	if len(m.Templates) == 0 {
+		m.Templates = parent.Templates
+	}
+	if m.Timeout == 0 {
+		m.Timeout = parent.Timeout
+	}
+	if m.PollInterval == 0 {
+		m.PollInterval = parent.PollInterval
+	}
+	if m.Action == "" {
+		m.Action = parent.Action
+	}
+	if m.ArchiveDir == "" {
+		m.ArchiveDir = parent.ArchiveDir
+	}
+}
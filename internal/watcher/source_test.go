@@ -0,0 +1,243 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fabyo/gordon-watcher/internal/logger"
+)
+
+func newSourceTestConfig(tmpDir string) Config {
+	return Config{
+		FilePatterns:      []string{"*.xml"},
+		MinFileSize:       1,
+		MaxFileSize:       1024,
+		StableAttempts:    3,
+		StableDelay:       20 * time.Millisecond,
+		CleanupInterval:   1 * time.Minute,
+		MaxWorkers:        1,
+		MaxFilesPerSecond: 10,
+		WorkerQueueSize:   10,
+		WorkingDir:        tmpDir,
+		Queue:             &MockQueue{},
+		Storage:           &MockStorage{processed: make(map[string]bool)},
+		Logger:            logger.New(logger.Config{Level: "error", Format: "text", Output: "stdout"}),
+	}
+}
+
+func TestNew_ImplicitSources(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := newSourceTestConfig(tmpDir)
+	cfg.Paths = []string{filepath.Join(tmpDir, "a"), filepath.Join(tmpDir, "b")}
+
+	w, err := New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+
+	if len(w.sources) != 2 {
+		t.Fatalf("got %d sources, want one per path", len(w.sources))
+	}
+	for i, src := range w.sources {
+		if src.Path != cfg.Paths[i] {
+			t.Errorf("source %d path = %s, want %s", i, src.Path, cfg.Paths[i])
+		}
+		if src.MaxFileSize != 1024 || src.StabilityMode != "" {
+			t.Errorf("source %s did not inherit the flat settings: %+v", src.Path, src.Source)
+		}
+		if src.rateLimit != w.rateLimit || src.stability != w.stability {
+			t.Errorf("source %s does not share the global rate limit and stability checker", src.Path)
+		}
+	}
+}
+
+func TestNew_Sources(t *testing.T) {
+	tmpDir := t.TempDir()
+	nfe := filepath.Join(tmpDir, "nfe")
+	bulk := filepath.Join(tmpDir, "bulk")
+
+	cfg := newSourceTestConfig(tmpDir)
+	cfg.Paths = []string{filepath.Join(tmpDir, "ignored-by-sources")}
+	cfg.Sources = []Source{
+		{Path: nfe},
+		{Path: bulk, FilePatterns: []string{"*.csv"}, MaxFileSize: 4096, MaxFilesPerSecond: 1},
+	}
+
+	w, err := New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+
+	if got := strings.Join(w.cfg.Paths, ","); got != nfe+","+bulk {
+		t.Errorf("Paths = %s, want the source paths", got)
+	}
+
+	b := w.sourceOf(filepath.Join(bulk, "x", "data.csv"))
+	if b.Path != bulk {
+		t.Fatalf("sourceOf() = %s, want %s", b.Path, bulk)
+	}
+	if b.MaxFileSize != 4096 || b.MinFileSize != 1 {
+		t.Errorf("bulk sizes = %d..%d, want 1..4096", b.MinFileSize, b.MaxFileSize)
+	}
+	if b.rateLimit == w.rateLimit {
+		t.Error("bulk source shares the global rate limit despite its own")
+	}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{filepath.Join(nfe, "1.xml"), true},
+		{filepath.Join(nfe, "1.csv"), false},
+		{filepath.Join(bulk, "1.csv"), true},
+		{filepath.Join(bulk, "1.xml"), false},
+	}
+	for _, tt := range tests {
+		if got := w.matchesPatterns(tt.path); got != tt.want {
+			t.Errorf("matchesPatterns(%s) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	for _, invalid := range [][]Source{
+		{{Path: nfe}, {Path: nfe + "/"}},
+		{{Path: ""}},
+		{{Path: nfe, StabilityMode: "eventually"}},
+		{{Path: nfe, Mode: "inotify"}},
+		{{Path: nfe, FilePatterns: []string{"re:("}}},
+		{{Path: nfe, StabilityMode: StabilityModeMarker, Marker: MarkerConfig{Templates: []string{"done"}}}},
+	} {
+		cfg := newSourceTestConfig(tmpDir)
+		cfg.Sources = invalid
+		if _, err := New(cfg); err == nil {
+			t.Errorf("New() with sources %+v succeeded, want error", invalid)
+		}
+	}
+}
+
+func TestWatcher_SourceSizeLimits(t *testing.T) {
+	tmpDir := t.TempDir()
+	nfe := filepath.Join(tmpDir, "nfe")
+	bulk := filepath.Join(tmpDir, "bulk")
+
+	cfg := newSourceTestConfig(tmpDir)
+	cfg.Sources = []Source{
+		{Path: nfe},
+		{Path: bulk, MaxFileSize: 4096},
+	}
+
+	w, err := New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	if err := w.Start(context.Background()); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	defer func() { _ = w.Stop(context.Background()) }()
+
+	data := []byte("<nfe>" + strings.Repeat("x", 2000) + "</nfe>")
+	if err := os.WriteFile(filepath.Join(nfe, "big.xml"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(bulk, "big-bulk.xml"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForFile(t, filepath.Join(tmpDir, "ignored", "big.xml"))
+	waitForFile(t, filepath.Join(tmpDir, "processing", "big-bulk.xml"))
+}
+
+func TestReconcileOrphans_ReturnsToSource(t *testing.T) {
+	tmpDir := t.TempDir()
+	nfe := filepath.Join(tmpDir, "nfe")
+	bulk := filepath.Join(tmpDir, "bulk")
+
+	cfg := newSourceTestConfig(tmpDir)
+	cfg.Sources = []Source{{Path: nfe}, {Path: bulk}}
+
+	w, err := New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	if err := w.createDirectories(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Moved to processing by a run that crashed before publishing, with
+	// its subdirectory removed since
+	data := filepath.Join(bulk, "sub", "1.xml")
+	if err := os.MkdirAll(filepath.Dir(data), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(data, []byte("<nfe/>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.moveToProcessing(data); err != nil {
+		t.Fatalf("moveToProcessing() failed: %v", err)
+	}
+	if err := os.Remove(filepath.Dir(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Left over from before origins were recorded
+	if err := os.WriteFile(filepath.Join(tmpDir, "processing", "2.xml"), []byte("<nfe/>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.reconcileOrphans(); err != nil {
+		t.Fatalf("reconcileOrphans() failed: %v", err)
+	}
+
+	if !isRegularFile(data) {
+		t.Error("orphan did not go back to its own source and subdirectory")
+	}
+	if !isRegularFile(filepath.Join(nfe, "2.xml")) {
+		t.Error("orphan without origin did not go to the first source")
+	}
+	if isRegularFile(filepath.Join(w.originsDir(), "1.xml")) {
+		t.Error("origin record left behind")
+	}
+}
+
+func TestMoveToProcessing_RejectsCollision(t *testing.T) {
+	tmpDir := t.TempDir()
+	nfe := filepath.Join(tmpDir, "nfe")
+	bulk := filepath.Join(tmpDir, "bulk")
+
+	cfg := newSourceTestConfig(tmpDir)
+	cfg.Sources = []Source{{Path: nfe}, {Path: bulk}}
+
+	w, err := New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	if err := w.createDirectories(); err != nil {
+		t.Fatal(err)
+	}
+
+	first := filepath.Join(nfe, "1.xml")
+	second := filepath.Join(bulk, "1.xml")
+	for _, path := range []string{first, second} {
+		if err := os.WriteFile(path, []byte(path), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := w.moveToProcessing(first); err != nil {
+		t.Fatalf("moveToProcessing(%s) failed: %v", first, err)
+	}
+	if _, err := w.moveToProcessing(second); !errors.Is(err, ErrProcessingCollision) {
+		t.Fatalf("moveToProcessing(%s) error = %v, want ErrProcessingCollision", second, err)
+	}
+
+	if !isRegularFile(second) {
+		t.Error("colliding file left its source")
+	}
+	if got := w.originOf(filepath.Join(tmpDir, "processing", "1.xml")); got != first {
+		t.Errorf("origin = %s, want %s", got, first)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -23,17 +24,28 @@ import (
 	"github.com/fabyo/gordon-watcher/internal/storage"
 )
 
+// ErrLockLost is the cause of publish cancellation when the file lock's
+// lease could not be kept
+var ErrLockLost = errors.New("file lock lost")
+
 // Config holds watcher configuration
 type Config struct {
 	// Paths to watch
 	Paths []string
 
-	// File patterns to match
-	FilePatterns []string
+	// Sources give watch paths settings of their own. When empty, each
+	// path in Paths is an implicit source using the settings below; when
+	// set, Paths is derived from the sources.
+	Sources []Source
 
-	// Patterns to exclude
+	// File patterns to match and to exclude, as ordered rules on the path
+	// relative to the watch root (see PatternSet)
+	FilePatterns    []string
 	ExcludePatterns []string
 
+	// Per-path watch mode, keyed by watch path; paths not listed use fsnotify
+	PathOptions map[string]PathOptions
+
 	// File size constraints (bytes)
 	MinFileSize int64
 	MaxFileSize int64
@@ -42,6 +54,12 @@ type Config struct {
 	StableAttempts int
 	StableDelay    time.Duration
 
+	// StabilityMode is poll (default), close_write or marker
+	StabilityMode string
+
+	// Marker configures the marker stability mode
+	Marker MarkerConfig
+
 	// Cleanup interval for empty directories
 	CleanupInterval time.Duration
 
@@ -79,6 +97,8 @@ type Watcher struct {
 	rateLimit *RateLimiter
 	cleaner   *Cleaner
 	stability *StabilityChecker
+	detector  completionDetector
+	sources   []*source
 	cb        *CircuitBreaker
 
 	ctx    context.Context
@@ -89,6 +109,19 @@ type Watcher struct {
 
 	// Track recently processed files to deduplicate fsnotify events
 	processedFiles sync.Map // map[string]time.Time
+
+	// Track published files awaiting a consumer result
+	pending sync.Map // map[string]string (message ID -> processing path)
+
+	// Set by Start once consumer results are being consumed
+	resultsEnabled bool
+
+	// Files waiting for their marker, and extracted files that need none
+	awaitingMarker sync.Map // map[string]struct{}
+	markerExempt   sync.Map // map[string]struct{}
+
+	// Processing names being moved into (see claimProcessing)
+	moving sync.Map // map[string]struct{}
 }
 
 // New creates a new Watcher instance
@@ -98,26 +131,39 @@ func New(cfg Config) (*Watcher, error) {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
 
-	// Create fsnotify watcher
-	fsWatcher, err := fsnotify.NewWatcher()
+	include, err := CompilePatterns(cfg.FilePatterns)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create fsnotify watcher: %w", err)
+		return nil, fmt.Errorf("invalid configuration: file_patterns: %w", err)
+	}
+	exclude, err := CompilePatterns(cfg.ExcludePatterns)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: exclude_patterns: %w", err)
 	}
-
-	// Create context
-	ctx, cancel := context.WithCancel(context.Background())
 
 	w := &Watcher{
 		cfg:       cfg,
-		fsWatcher: fsWatcher,
-		ctx:       ctx,
-		cancel:    cancel,
+		rateLimit: NewRateLimiter(cfg.MaxFilesPerSecond),
+		stability: NewStabilityChecker(cfg.StableAttempts, cfg.StableDelay),
 		tracer:    otel.Tracer("gordon-watcher"),
 	}
 
+	if err := w.buildSources(include, exclude); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	// Create fsnotify watcher
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create fsnotify watcher: %w", err)
+	}
+	w.fsWatcher = fsWatcher
+
+	// Create context
+	w.ctx, w.cancel = context.WithCancel(context.Background())
+
 	// Initialize components
 	// Define protected directories (should not be removed even if empty)
-	protectedDirs := make([]string, 0, len(cfg.Paths)+5)
+	protectedDirs := make([]string, 0, len(cfg.Paths)+6)
 	protectedDirs = append(protectedDirs, cfg.Paths...)
 	protectedDirs = append(protectedDirs,
 		filepath.Join(cfg.WorkingDir, cfg.SubDirs.Processing),
@@ -125,14 +171,23 @@ func New(cfg Config) (*Watcher, error) {
 		filepath.Join(cfg.WorkingDir, cfg.SubDirs.Failed),
 		filepath.Join(cfg.WorkingDir, cfg.SubDirs.Ignored),
 		filepath.Join(cfg.WorkingDir, cfg.SubDirs.Tmp),
+		filepath.Join(cfg.WorkingDir, originsDirName),
 	)
+	protectedDirs = append(protectedDirs, w.markerArchiveDirs()...)
 
 	w.pool = NewWorkerPool(cfg.MaxWorkers, cfg.WorkerQueueSize, w.processFile)
-	w.rateLimit = NewRateLimiter(cfg.MaxFilesPerSecond)
 	w.cleaner = NewCleaner(cfg.WorkingDir, protectedDirs, cfg.CleanupInterval, cfg.Logger)
-	w.stability = NewStabilityChecker(cfg.StableAttempts, cfg.StableDelay)
 	w.cb = NewCircuitBreaker(5, 30*time.Second) // 5 failures, 30s reset timeout
 
+	if w.usesStabilityMode(StabilityModeCloseWrite) {
+		detector, err := newCompletionDetector()
+		if err != nil {
+			cfg.Logger.Warn("Close-write detection unavailable, polling for stability", "error", err)
+		} else {
+			w.detector = detector
+		}
+	}
+
 	return w, nil
 }
 
@@ -149,6 +204,9 @@ func (w *Watcher) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to create directories: %w", err)
 	}
 
+	// Orphan reconciliation depends on whether results will arrive
+	results := w.subscribeResults()
+
 	// Reconcile orphan files in processing directory
 	if err := w.reconcileOrphans(); err != nil {
 		w.cfg.Logger.Error("Failed to reconcile orphans", "error", err)
@@ -158,6 +216,13 @@ func (w *Watcher) Start(ctx context.Context) error {
 	// Start worker pool
 	w.pool.Start()
 
+	// Results are applied only after reconciliation, so the two never
+	// race on the same orphan
+	if w.resultsEnabled {
+		w.wg.Add(1)
+		go w.resultLoop(results)
+	}
+
 	// Start cleaner
 	w.cleaner.Start()
 
@@ -169,10 +234,28 @@ func (w *Watcher) Start(ctx context.Context) error {
 	for _, path := range w.cfg.Paths {
 		w.cfg.Logger.Info("Adding watch path", "path", path)
 
+		opts := w.pathOptions(path)
+
+		// The poller's first snapshot is taken before the initial scan, so
+		// files created in between are reported rather than missed
+		var p *poller
+		if opts.Mode != WatchModeFSNotify {
+			p = newPoller(path)
+			if _, err := p.scan(); err != nil {
+				w.cfg.Logger.Error("Failed to poll path", "path", path, "error", err)
+			}
+		}
+
 		// Scan existing files
-		if err := w.scanAndWatch(path); err != nil {
+		if err := w.scanAndWatch(path, opts.Mode != WatchModePoll); err != nil {
 			w.cfg.Logger.Error("Failed to scan path", "path", path, "error", err)
 		}
+
+		if p != nil {
+			w.cfg.Logger.Info("Polling watch path", "path", path, "mode", opts.Mode, "interval", opts.PollInterval)
+			w.wg.Add(1)
+			go w.pollLoop(p, opts.PollInterval)
+		}
 	}
 
 	// Start event loop
@@ -199,6 +282,13 @@ func (w *Watcher) Stop(ctx context.Context) error {
 	// Stop worker pool
 	w.pool.Stop()
 
+	// Close the close-write detector; waiters left behind fall back to polling
+	if w.detector != nil {
+		if err := w.detector.Close(); err != nil {
+			w.cfg.Logger.Error("Error closing close-write detector", "error", err)
+		}
+	}
+
 	// Stop cleaner
 	w.cleaner.Stop()
 
@@ -262,7 +352,7 @@ func (w *Watcher) handleEvent(event fsnotify.Event) {
 
 	if info.IsDir() {
 		// Add directory to watcher
-		if err := w.fsWatcher.Add(event.Name); err != nil {
+		if err := w.addWatch(event.Name); err != nil {
 			w.cfg.Logger.Error("Failed to add directory to watcher",
 				"path", event.Name,
 				"error", err)
@@ -272,7 +362,7 @@ func (w *Watcher) handleEvent(event fsnotify.Event) {
 			// Scan the new directory for existing files
 			// This is crucial for when a folder with files is moved into the watched directory
 			go func(dirPath string) {
-				if err := w.scanAndWatch(dirPath); err != nil {
+				if err := w.scanAndWatch(dirPath, true); err != nil {
 					w.cfg.Logger.Error("Failed to scan new directory", "path", dirPath, "error", err)
 				}
 			}(event.Name)
@@ -280,18 +370,32 @@ func (w *Watcher) handleEvent(event fsnotify.Event) {
 		return
 	}
 
+	w.handleFile(ctx, event.Name)
+}
+
+// handleFile runs a detected file through stability checking and submits
+// it to the worker pool. It is shared by fsnotify events and the poller.
+func (w *Watcher) handleFile(ctx context.Context, name string) {
 	// Auto-delete Windows Zone.Identifier files
-	if strings.HasSuffix(event.Name, ":Zone.Identifier") || strings.HasSuffix(event.Name, ".Zone.Identifier") {
-		w.cfg.Logger.Debug("Deleting Zone.Identifier file", "path", event.Name)
-		if err := os.Remove(event.Name); err != nil {
-			w.cfg.Logger.Warn("Failed to delete Zone.Identifier", "path", event.Name, "error", err)
+	if strings.HasSuffix(name, ":Zone.Identifier") || strings.HasSuffix(name, ".Zone.Identifier") {
+		w.cfg.Logger.Debug("Deleting Zone.Identifier file", "path", name)
+		if err := os.Remove(name); err != nil {
+			w.cfg.Logger.Warn("Failed to delete Zone.Identifier", "path", name, "error", err)
 		}
 		return
 	}
 
-	// Deduplicate file detection events (fsnotify fires multiple events: CREATE, WRITE, CHMOD)
+	src := w.sourceOf(name)
+
+	// Markers are consumed together with their data file
+	if src.markers != nil && src.markers.isMarker(name) {
+		return
+	}
+
+	// Deduplicate file detection events (fsnotify fires multiple events: CREATE, WRITE, CHMOD,
+	// and a path watched in both modes is also reported by the poller)
 	// Only process each file once by checking if we've seen it recently (within 1 second)
-	if lastSeen, exists := w.processedFiles.Load(event.Name); exists {
+	if lastSeen, exists := w.processedFiles.Load(name); exists {
 		if time.Since(lastSeen.(time.Time)) < 1*time.Second {
 			// File was just processed, skip duplicate event
 			return
@@ -299,17 +403,24 @@ func (w *Watcher) handleEvent(event fsnotify.Event) {
 	}
 
 	// Mark file as seen
-	w.processedFiles.Store(event.Name, time.Now())
+	w.processedFiles.Store(name, time.Now())
 
 	// Check if file matches patterns
-	if !w.matchesPatterns(event.Name) {
+	if !w.matchesPatterns(name) {
 		// Move non-matching files to ignored
-		w.cfg.Logger.Info("File does not match patterns, moving to ignored", "path", event.Name)
-		w.moveToIgnored(event.Name, "pattern_mismatch")
+		w.cfg.Logger.Info("File does not match patterns, moving to ignored", "path", name)
+		w.moveToIgnored(name, "", "pattern_mismatch")
 		return
 	}
 
-	w.cfg.Logger.Info("File detected", "path", event.Name)
+	// One marker wait per file, however many write events it gets
+	if src.markers != nil {
+		if _, waiting := w.awaitingMarker.LoadOrStore(name, struct{}{}); waiting {
+			return
+		}
+	}
+
+	w.cfg.Logger.Info("File detected", "path", name)
 
 	// Launch goroutine for stability check and processing
 	// This prevents blocking the event loop while waiting for file stability
@@ -317,15 +428,26 @@ func (w *Watcher) handleEvent(event fsnotify.Event) {
 	go func(path string, parentCtx context.Context) {
 		defer w.wg.Done()
 
+		timeout := 5 * time.Minute // Safety timeout
+		if src.markers != nil {
+			timeout = src.Marker.Timeout
+			defer w.awaitingMarker.Delete(path)
+		}
+
 		// Create a new context for this operation that respects the trace context
 		// but can also be cancelled independently if needed
-		ctx, cancel := context.WithTimeout(parentCtx, 5*time.Minute) // Safety timeout
+		ctx, cancel := context.WithTimeout(parentCtx, timeout)
 		defer cancel()
 
-		// Wait for file to stabilize
-		if !w.stability.WaitForStability(ctx, path) {
+		if src.markers != nil {
+			// Wait for the uploader's marker
+			if !w.waitForMarker(ctx, src, path) {
+				return
+			}
+		} else if !w.waitForCompletion(ctx, src, path) {
+			// Wait for file to stabilize
 			w.cfg.Logger.Warn("File did not stabilize", "path", path)
-			w.moveToIgnored(path, "file_not_stable")
+			w.moveToIgnored(path, "", "file_not_stable")
 			return
 		}
 
@@ -338,16 +460,17 @@ func (w *Watcher) handleEvent(event fsnotify.Event) {
 		}
 
 		// Apply rate limiting
-		if !w.rateLimit.Allow() {
+		if !src.rateLimit.Allow() {
 			w.cfg.Logger.Warn("Rate limit exceeded, dropping file", "path", path)
 			metrics.RateLimitDropped.Inc()
-			w.moveToIgnored(path, "rate_limit_exceeded")
+			w.moveToIgnored(path, "", "rate_limit_exceeded")
+			w.finishMarker(path)
 			return
 		}
 
 		// Submit to worker pool
 		w.pool.Submit(path)
-	}(event.Name, ctx)
+	}(name, ctx)
 }
 
 // processFile processes a single file (called by worker pool)
@@ -363,6 +486,11 @@ func (w *Watcher) processFile(ctx context.Context, path string) error {
 
 	span.SetAttributes(attribute.String("file.path", path))
 
+	src := w.sourceOf(path)
+
+	// Whatever happens to the file, its marker goes once the file has moved
+	defer w.finishMarker(path)
+
 	w.cfg.Logger.Info("Processing file", "path", path)
 
 	// Get file info
@@ -376,16 +504,16 @@ func (w *Watcher) processFile(ctx context.Context, path string) error {
 	size := info.Size()
 	metrics.FileSizeBytes.Observe(float64(size))
 
-	if size < w.cfg.MinFileSize {
-		w.cfg.Logger.Warn("File too small", "path", path, "size", size, "min", w.cfg.MinFileSize)
-		w.moveToIgnored(path, "file_too_small")
+	if size < src.MinFileSize {
+		w.cfg.Logger.Warn("File too small", "path", path, "size", size, "min", src.MinFileSize)
+		w.moveToIgnored(path, "", "file_too_small")
 		metrics.FilesRejected.Inc()
 		return nil
 	}
 
-	if size > w.cfg.MaxFileSize {
-		w.cfg.Logger.Warn("File too large", "path", path, "size", size, "max", w.cfg.MaxFileSize)
-		w.moveToIgnored(path, "file_too_large")
+	if size > src.MaxFileSize {
+		w.cfg.Logger.Warn("File too large", "path", path, "size", size, "max", src.MaxFileSize)
+		w.moveToIgnored(path, "", "file_too_large")
 		metrics.FilesRejected.Inc()
 		return nil
 	}
@@ -400,7 +528,7 @@ func (w *Watcher) processFile(ctx context.Context, path string) error {
 		extractedFiles, err := ExtractZip(path, extractDir)
 		if err != nil {
 			w.cfg.Logger.Error("Failed to extract ZIP", "path", path, "error", err)
-			w.moveToFailed(path, "zip_extraction_failed")
+			_ = w.moveToFailed(path, "zip_extraction_failed")
 			metrics.WatcherErrors.Inc()
 			return fmt.Errorf("failed to extract ZIP: %w", err)
 		}
@@ -409,6 +537,13 @@ func (w *Watcher) processFile(ctx context.Context, path string) error {
 			"path", path,
 			"files_extracted", len(extractedFiles))
 
+		// The ZIP's marker covers its contents
+		if src.markers != nil {
+			for _, f := range extractedFiles {
+				w.markerExempt.Store(f, struct{}{})
+			}
+		}
+
 		// Delete the ZIP file after successful extraction
 		if err := os.Remove(path); err != nil {
 			w.cfg.Logger.Warn("Failed to delete ZIP after extraction", "path", path, "error", err)
@@ -429,6 +564,13 @@ func (w *Watcher) processFile(ctx context.Context, path string) error {
 
 	span.SetAttributes(attribute.String("file.hash", hash))
 
+	if rec, ok := w.cfg.Storage.(storage.HistoryRecorder); ok {
+		if err := rec.RecordDetected(ctx, hash, path); err != nil {
+			w.cfg.Logger.Error("Failed to record detected file", "hash", hash, "error", err)
+			metrics.StorageErrors.Inc()
+		}
+	}
+
 	// Check if already processed (idempotency)
 	processed, err := w.cfg.Storage.IsProcessed(ctx, hash)
 	if err != nil {
@@ -438,7 +580,7 @@ func (w *Watcher) processFile(ctx context.Context, path string) error {
 
 	if processed {
 		w.cfg.Logger.Info("File already processed (duplicate)", "path", path, "hash", hash)
-		w.moveToIgnored(path, "duplicate")
+		w.moveToIgnored(path, hash, "duplicate")
 		metrics.FilesDuplicated.Inc()
 		return nil
 	}
@@ -453,28 +595,53 @@ func (w *Watcher) processFile(ctx context.Context, path string) error {
 	}
 	defer func() { _ = lock.Release(ctx) }()
 
+	// Publishing stops if the lease is lost; the fencing token lets
+	// consumers reject anything that still gets through
+	lockCtx, cancelLock := w.watchLock(ctx, lock, hash)
+	defer cancelLock()
+
 	// Move to processing directory
 	processingPath, err := w.moveToProcessing(path)
+	if errors.Is(err, ErrProcessingCollision) {
+		w.cfg.Logger.Error("File name already in processing", "path", path, "error", err)
+		_ = w.moveToFailed(path, "processing_collision")
+		if err := w.cfg.Storage.MarkFailed(ctx, hash, "processing_collision"); err != nil {
+			w.cfg.Logger.Error("Failed to mark as failed", "hash", hash, "error", err)
+			metrics.StorageErrors.Inc()
+		}
+		return fmt.Errorf("failed to move to processing: %w", err)
+	}
 	if err != nil {
 		w.cfg.Logger.Error("Failed to move to processing", "path", path, "error", err)
 		return fmt.Errorf("failed to move to processing: %w", err)
 	}
 
-	// Mark as enqueued
-	if err := w.cfg.Storage.MarkEnqueued(ctx, hash, processingPath); err != nil {
-		w.cfg.Logger.Error("Failed to mark as enqueued", "hash", hash, "error", err)
-		metrics.StorageErrors.Inc()
+	// Register before publishing so a fast consumer result can be matched.
+	// Without results nothing would ever remove the entry.
+	if w.resultsEnabled {
+		w.pending.Store(hash, processingPath)
+	}
+
+	// Mark as enqueued, unless the queue records it atomically on publish
+	if rec, ok := w.cfg.Queue.(queue.StateRecorder); !ok || !rec.RecordsEnqueued() {
+		if err := w.cfg.Storage.MarkEnqueued(ctx, hash, processingPath); err != nil {
+			w.cfg.Logger.Error("Failed to mark as enqueued", "hash", hash, "error", err)
+			metrics.StorageErrors.Inc()
+		}
 	}
 
 	// Create message
 	msg := &queue.Message{
-		ID:        hash,
-		Path:      processingPath,
-		Filename:  filepath.Base(path),
-		Kind:      w.getFileKind(path),
-		Size:      size,
-		Hash:      hash,
-		Timestamp: time.Now(),
+		SchemaVersion: queue.SchemaVersion,
+		ID:            hash,
+		Path:          processingPath,
+		Filename:      filepath.Base(path),
+		Kind:          w.getFileKind(path),
+		Size:          size,
+		Hash:          hash,
+		Source:        w.sourceFor(path),
+		Timestamp:     time.Now(),
+		FencingToken:  lock.Token(),
 	}
 
 	// Publish to queue
@@ -482,16 +649,21 @@ func (w *Watcher) processFile(ctx context.Context, path string) error {
 
 	// Wrap Retry with Circuit Breaker
 	err = w.cb.Call(func() error {
-		return Retry(ctx, retryCfg, func() error {
-			return w.cfg.Queue.Publish(ctx, msg)
+		return Retry(lockCtx, retryCfg, func() error {
+			return w.cfg.Queue.Publish(lockCtx, msg)
 		})
 	})
 
+	if err != nil && errors.Is(context.Cause(lockCtx), ErrLockLost) {
+		err = ErrLockLost
+	}
+
 	if err != nil {
 		w.cfg.Logger.Error("Failed to publish to queue after retries", "path", path, "error", err)
+		w.pending.Delete(hash)
 
 		// Move to failed directory
-		w.moveToFailed(processingPath, fmt.Sprintf("queue_error: %v", err))
+		_ = w.moveToFailed(processingPath, fmt.Sprintf("queue_error: %v", err))
 
 		// Mark as failed
 		if err := w.cfg.Storage.MarkFailed(ctx, hash, err.Error()); err != nil {
@@ -528,11 +700,14 @@ func (w *Watcher) createDirectories() error {
 		filepath.Join(w.cfg.WorkingDir, w.cfg.SubDirs.Failed),
 		filepath.Join(w.cfg.WorkingDir, w.cfg.SubDirs.Ignored),
 		filepath.Join(w.cfg.WorkingDir, w.cfg.SubDirs.Tmp),
+		w.originsDir(),
 	}
 
 	// Add watch paths
 	dirs = append(dirs, w.cfg.Paths...)
 
+	dirs = append(dirs, w.markerArchiveDirs()...)
+
 	for _, dir := range dirs {
 		if err := os.MkdirAll(dir, 0755); err != nil {
 			return fmt.Errorf("failed to create directory %s: %w", dir, err)
@@ -542,8 +717,9 @@ func (w *Watcher) createDirectories() error {
 	return nil
 }
 
-// scanAndWatch performs initial scan and adds watchers recursively
-func (w *Watcher) scanAndWatch(root string) error {
+// scanAndWatch performs initial scan and, when watch is set, adds fsnotify
+// watchers recursively
+func (w *Watcher) scanAndWatch(root string, watch bool) error {
 	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			w.cfg.Logger.Error("Error accessing path during scan",
@@ -553,8 +729,12 @@ func (w *Watcher) scanAndWatch(root string) error {
 		}
 
 		if info.IsDir() {
+			if !watch {
+				return nil
+			}
+
 			// Add directory to watcher
-			if err := w.fsWatcher.Add(path); err != nil {
+			if err := w.addWatch(path); err != nil {
 				w.cfg.Logger.Error("Failed to add directory to watcher",
 					"path", path,
 					"error", err)
@@ -571,8 +751,23 @@ func (w *Watcher) scanAndWatch(root string) error {
 				return nil
 			}
 
+			src := w.sourceOf(path)
+
+			// Markers are consumed together with their data file
+			if src.markers != nil && src.markers.isMarker(path) {
+				return nil
+			}
+
 			// Process existing file
 			if w.matchesPatterns(path) {
+				if src.markers != nil {
+					if _, ok := src.markers.existingMarker(path); !ok {
+						w.cfg.Logger.Info("Existing file has no marker yet, waiting", "path", path)
+						w.handleFile(w.ctx, path)
+						return nil
+					}
+				}
+
 				w.cfg.Logger.Info("Processing existing file from scan", "path", path)
 				// Use blocking submit to ensure all existing files are processed
 				// regardless of queue size
@@ -580,7 +775,7 @@ func (w *Watcher) scanAndWatch(root string) error {
 			} else {
 				// Move non-matching files to ignored
 				w.cfg.Logger.Info("Moving non-matching file found during scan to ignored", "path", path)
-				w.moveToIgnored(path, "pattern_mismatch_scan")
+				w.moveToIgnored(path, "", "pattern_mismatch_scan")
 			}
 		}
 
@@ -640,7 +835,17 @@ func (w *Watcher) moveToProcessing(path string) (string, error) {
 		filename,
 	)
 
+	release, err := w.claimProcessing(destPath)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	// Recorded first, so a crash right after the move still knows the source
+	w.recordOrigin(path)
+
 	if err := moveFile(path, destPath); err != nil {
+		w.clearOrigin(destPath)
 		return "", fmt.Errorf("failed to move file: %w", err)
 	}
 
@@ -649,8 +854,31 @@ func (w *Watcher) moveToProcessing(path string) (string, error) {
 	return destPath, nil
 }
 
+// moveToProcessed moves file to processed directory
+func (w *Watcher) moveToProcessed(path string) error {
+	filename := filepath.Base(path)
+	destPath := filepath.Join(
+		w.cfg.WorkingDir,
+		w.cfg.SubDirs.Processed,
+		filename,
+	)
+
+	if err := os.Rename(path, destPath); err != nil {
+		w.cfg.Logger.Error("Failed to move file to processed",
+			"src", path,
+			"dest", destPath,
+			"error", err)
+		return err
+	}
+
+	w.clearOrigin(path)
+
+	w.cfg.Logger.Debug("File moved to processed", "path", destPath)
+	return nil
+}
+
 // moveToFailed moves file to failed directory
-func (w *Watcher) moveToFailed(path, reason string) {
+func (w *Watcher) moveToFailed(path, reason string) error {
 	filename := filepath.Base(path)
 	destPath := filepath.Join(
 		w.cfg.WorkingDir,
@@ -663,15 +891,19 @@ func (w *Watcher) moveToFailed(path, reason string) {
 			"src", path,
 			"dest", destPath,
 			"error", err)
-		return
+		return err
 	}
 
+	w.clearOrigin(path)
+
 	w.cfg.Logger.Warn("File moved to failed",
 		"path", destPath,
 		"reason", reason)
+	return nil
 }
 
-// reconcileOrphans moves files from processing back to incoming to be re-processed
+// reconcileOrphans moves files from processing back to the watch path they
+// came from to be re-processed
 func (w *Watcher) reconcileOrphans() error {
 	processingDir := filepath.Join(w.cfg.WorkingDir, w.cfg.SubDirs.Processing)
 
@@ -691,11 +923,9 @@ func (w *Watcher) reconcileOrphans() error {
 
 	w.cfg.Logger.Info("Found orphan files in processing directory", "count", len(entries))
 
-	// Use the first watch path as the destination (incoming)
 	if len(w.cfg.Paths) == 0 {
 		return fmt.Errorf("no watch paths configured")
 	}
-	incomingDir := w.cfg.Paths[0]
 
 	for _, entry := range entries {
 		if entry.IsDir() {
@@ -703,9 +933,22 @@ func (w *Watcher) reconcileOrphans() error {
 		}
 
 		srcPath := filepath.Join(processingDir, entry.Name())
-		destPath := filepath.Join(incomingDir, entry.Name())
+		destPath := w.originOf(srcPath)
 
-		w.cfg.Logger.Info("Reconciling orphan file", "file", entry.Name())
+		// Files storage already knows about are settled, not re-injected
+		if w.settleOrphan(srcPath) {
+			continue
+		}
+
+		w.cfg.Logger.Info("Reconciling orphan file", "file", entry.Name(), "dest", destPath)
+
+		// The subdirectory may be gone; patterns can depend on it
+		if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+			w.cfg.Logger.Error("Failed to recreate orphan directory",
+				"file", entry.Name(),
+				"error", err)
+			continue
+		}
 
 		if err := os.Rename(srcPath, destPath); err != nil {
 			w.cfg.Logger.Error("Failed to move orphan file back to incoming",
@@ -713,13 +956,84 @@ func (w *Watcher) reconcileOrphans() error {
 				"error", err)
 			continue
 		}
+		w.clearOrigin(srcPath)
 	}
 
 	return nil
 }
 
-// moveToIgnored moves file to ignored directory
-func (w *Watcher) moveToIgnored(path, reason string) {
+// subscribeResults subscribes to consumer results if the queue supports it
+// and records whether they are enabled. The channel is nil otherwise.
+func (w *Watcher) subscribeResults() <-chan queue.Result {
+	rs, ok := w.cfg.Queue.(queue.ResultSource)
+	if !ok {
+		return nil
+	}
+
+	results, err := rs.Results(w.ctx)
+	switch {
+	case errors.Is(err, queue.ErrResultsDisabled):
+		w.cfg.Logger.Info("Results loop disabled, files will remain in processing")
+		return nil
+	case err != nil:
+		w.cfg.Logger.Error("Failed to start results loop", "error", err)
+		return nil
+	}
+
+	w.resultsEnabled = true
+	return results
+}
+
+// settleOrphan finishes a file in processing according to its state in
+// storage. It returns false when the file should be re-injected instead.
+func (w *Watcher) settleOrphan(path string) bool {
+	hash, err := w.calculateHash(path)
+	if err != nil {
+		w.cfg.Logger.Error("Failed to hash orphan file", "path", path, "error", err)
+		return false
+	}
+
+	rec, err := w.cfg.Storage.Get(w.ctx, hash)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			w.cfg.Logger.Error("Failed to look up orphan file", "path", path, "hash", hash, "error", err)
+			metrics.StorageErrors.Inc()
+		}
+		return false
+	}
+
+	switch rec.Status {
+	case storage.StatusProcessed:
+		// The result was recorded but the file was never moved
+		w.cfg.Logger.Info("Moving processed orphan file", "path", path, "hash", hash)
+		_ = w.moveToProcessed(path)
+		return true
+
+	case storage.StatusFailed:
+		reason := rec.Reason
+		if reason == "" {
+			reason = "orphan_failed"
+		}
+		_ = w.moveToFailed(path, reason)
+		return true
+
+	case storage.StatusEnqueued:
+		// Published files wait for the consumer result, when results are
+		// consumed; otherwise nothing would ever settle them
+		if w.resultsEnabled {
+			w.cfg.Logger.Info("Keeping published file in processing", "path", path, "hash", hash)
+			return true
+		}
+	}
+
+	return false
+}
+
+// moveToIgnored moves file to ignored directory. hash is empty when the
+// file was rejected before it was hashed.
+func (w *Watcher) moveToIgnored(path, hash, reason string) {
+	w.recordIgnored(hash, path, reason)
+
 	filename := filepath.Base(path)
 	destPath := filepath.Join(
 		w.cfg.WorkingDir,
@@ -742,33 +1056,77 @@ func (w *Watcher) moveToIgnored(path, reason string) {
 	metrics.FilesIgnored.Inc()
 }
 
+// watchLock returns a context that is cancelled when the lock is lost
+func (w *Watcher) watchLock(ctx context.Context, lock storage.Lock, hash string) (context.Context, context.CancelFunc) {
+	lockCtx, cancel := context.WithCancelCause(ctx)
+
+	go func() {
+		select {
+		case <-lock.Lost():
+			w.cfg.Logger.Warn("Lock lost while processing", "hash", hash, "token", lock.Token())
+			metrics.LocksLost.Inc()
+			cancel(ErrLockLost)
+		case <-lockCtx.Done():
+		}
+	}()
+
+	return lockCtx, func() { cancel(nil) }
+}
+
+// recordIgnored adds an ignored event to the storage history, if it keeps one
+func (w *Watcher) recordIgnored(hash, path, reason string) {
+	rec, ok := w.cfg.Storage.(storage.HistoryRecorder)
+	if !ok {
+		return
+	}
+
+	ctx := w.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if err := rec.RecordIgnored(ctx, hash, path, reason); err != nil {
+		w.cfg.Logger.Error("Failed to record ignored file", "path", path, "error", err)
+		metrics.StorageErrors.Inc()
+	}
+}
+
 // ═══════════════════════════════════════════════════════════
 //  HELPER FUNCTIONS - FILE OPERATIONS
 // ═══════════════════════════════════════════════════════════
 
-// matchesPatterns checks if a file matches the configured patterns
+// matchesPatterns checks if a file matches the patterns of its source
 func (w *Watcher) matchesPatterns(path string) bool {
-	filename := filepath.Base(path)
+	src := w.sourceOf(path)
+	rel := w.relPath(path)
 
 	// Check exclude patterns first
-	for _, pattern := range w.cfg.ExcludePatterns {
-		if matchPattern(filename, pattern) {
-			return false
-		}
+	if excluded, _ := src.exclude.Match(rel); excluded {
+		return false
 	}
 
 	// Check include patterns
-	if len(w.cfg.FilePatterns) == 0 {
+	if src.include.Empty() {
 		return true // No patterns means match all
 	}
 
-	for _, pattern := range w.cfg.FilePatterns {
-		if matchPattern(filename, pattern) {
-			return true
-		}
+	included, _ := src.include.Match(rel)
+	return included
+}
+
+// relPath returns path relative to its watch root, slash-separated, or the
+// file name when it is outside every watch path
+func (w *Watcher) relPath(path string) string {
+	root := w.sourceFor(path)
+	if root == "" {
+		return filepath.Base(path)
 	}
 
-	return false
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return filepath.Base(path)
+	}
+	return filepath.ToSlash(rel)
 }
 
 // matchPattern matches a filename against a pattern
@@ -828,12 +1186,96 @@ func (w *Watcher) getFileKind(filename string) string {
 	return "unknown"
 }
 
+// sourceFor returns the watch path a file was detected in
+func (w *Watcher) sourceFor(path string) string {
+	source := ""
+	for _, root := range w.cfg.Paths {
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		// Prefer the most specific (longest) root for nested watch paths
+		if len(root) > len(source) {
+			source = root
+		}
+	}
+	return source
+}
+
 // ═══════════════════════════════════════════════════════════
 //  HELPER FUNCTIONS - VALIDATION
 // ═══════════════════════════════════════════════════════════
 
+// validateMarkerConfig checks the marker templates and sets defaults
+func validateMarkerConfig(m *MarkerConfig, workingDir string) error {
+	if _, err := newMarkerMatcher(m.Templates); err != nil {
+		return err
+	}
+
+	if m.Timeout <= 0 {
+		m.Timeout = DefaultMarkerTimeout
+	}
+	if m.PollInterval <= 0 {
+		m.PollInterval = DefaultMarkerPollInterval
+	}
+
+	switch m.Action {
+	case "":
+		m.Action = MarkerActionDelete
+	case MarkerActionDelete, MarkerActionArchive:
+	default:
+		return fmt.Errorf("unknown marker action %q", m.Action)
+	}
+
+	if m.Action == MarkerActionArchive && m.ArchiveDir == "" {
+		m.ArchiveDir = filepath.Join(workingDir, "markers")
+	}
+
+	return nil
+}
+
+// validatePathOptions checks watch modes and normalizes the option keys
+// so they match cleaned watch paths
+func validatePathOptions(cfg *Config) error {
+	if len(cfg.PathOptions) == 0 {
+		return nil
+	}
+
+	watched := make(map[string]bool, len(cfg.Paths))
+	for _, path := range cfg.Paths {
+		watched[filepath.Clean(path)] = true
+	}
+
+	options := make(map[string]PathOptions, len(cfg.PathOptions))
+	for path, opts := range cfg.PathOptions {
+		path = filepath.Clean(path)
+		if !watched[path] {
+			return fmt.Errorf("path options for %s: not a watch path", path)
+		}
+
+		switch opts.Mode {
+		case "", WatchModeFSNotify, WatchModePoll, WatchModeBoth:
+		default:
+			return fmt.Errorf("path options for %s: unknown mode %q", path, opts.Mode)
+		}
+
+		if opts.PollInterval < 0 {
+			return fmt.Errorf("path options for %s: poll interval must not be negative", path)
+		}
+
+		options[path] = opts
+	}
+	cfg.PathOptions = options
+
+	return nil
+}
+
 // validateConfig validates the watcher configuration
 func validateConfig(cfg *Config) error {
+	if err := resolveSources(cfg); err != nil {
+		return err
+	}
+
 	if len(cfg.Paths) == 0 {
 		return fmt.Errorf("at least one watch path is required")
 	}
@@ -862,6 +1304,20 @@ func validateConfig(cfg *Config) error {
 		return fmt.Errorf("logger is required")
 	}
 
+	if err := validatePathOptions(cfg); err != nil {
+		return err
+	}
+
+	switch cfg.StabilityMode {
+	case "", StabilityModePoll, StabilityModeCloseWrite:
+	case StabilityModeMarker:
+		if err := validateMarkerConfig(&cfg.Marker, cfg.WorkingDir); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown stability mode %q", cfg.StabilityMode)
+	}
+
 	// Set defaults for subdirectories if not provided
 	if cfg.SubDirs.Processing == "" {
 		cfg.SubDirs.Processing = "processing"
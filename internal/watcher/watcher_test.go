@@ -2,6 +2,7 @@ package watcher
 
 import (
 	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -33,6 +34,7 @@ func (m *MockQueue) Close() error {
 // MockStorage implements storage.Storage interface for testing
 type MockStorage struct {
 	processed map[string]bool
+	records   map[string]*storage.Record
 	err       error
 }
 
@@ -71,12 +73,42 @@ func (m *MockStorage) MarkEnqueued(ctx context.Context, hash, path string) error
 	return m.err
 }
 
-type MockLock struct{}
+func (m *MockStorage) Get(ctx context.Context, hash string) (*storage.Record, error) {
+	if rec, ok := m.records[hash]; ok {
+		return rec, nil
+	}
+	return nil, storage.ErrNotFound
+}
+
+func (m *MockStorage) SearchByFilename(ctx context.Context, prefix string, limit int) ([]storage.Record, error) {
+	return nil, m.err
+}
+
+func (m *MockStorage) List(ctx context.Context, opts storage.ListOptions) (*storage.Page, error) {
+	return &storage.Page{}, m.err
+}
+
+func (m *MockStorage) Count(ctx context.Context, status string) (int64, error) {
+	return 0, m.err
+}
+
+type MockLock struct {
+	token uint64
+	lost  chan struct{}
+}
 
 func (ml *MockLock) Release(ctx context.Context) error {
 	return nil
 }
 
+func (ml *MockLock) Lost() <-chan struct{} {
+	return ml.lost // nil never fires
+}
+
+func (ml *MockLock) Token() uint64 {
+	return ml.token
+}
+
 func (m *MockStorage) GetLock(ctx context.Context, hash string) (storage.Lock, error) {
 	if m.err != nil {
 		return nil, m.err
@@ -194,6 +226,34 @@ func TestValidateConfig(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "unknown watch mode",
+			cfg: &Config{
+				Paths:             []string{"/tmp"},
+				PathOptions:       map[string]PathOptions{"/tmp": {Mode: "inotify"}},
+				MaxWorkers:        5,
+				MaxFilesPerSecond: 10,
+				WorkingDir:        "/tmp",
+				Queue:             &MockQueue{},
+				Storage:           &MockStorage{processed: make(map[string]bool)},
+				Logger:            logger.New(logger.Config{Level: "info", Format: "text", Output: "stdout"}),
+			},
+			wantErr: true,
+		},
+		{
+			name: "path options for unwatched path",
+			cfg: &Config{
+				Paths:             []string{"/tmp"},
+				PathOptions:       map[string]PathOptions{"/mnt/smb": {Mode: WatchModePoll}},
+				MaxWorkers:        5,
+				MaxFilesPerSecond: 10,
+				WorkingDir:        "/tmp",
+				Queue:             &MockQueue{},
+				Storage:           &MockStorage{processed: make(map[string]bool)},
+				Logger:            logger.New(logger.Config{Level: "info", Format: "text", Output: "stdout"}),
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -293,6 +353,91 @@ func TestReconcileOrphans(t *testing.T) {
 	}
 }
 
+// mockResultQueue is a MockQueue that reports consumer results, or
+// ErrResultsDisabled when it has no results channel
+type mockResultQueue struct {
+	MockQueue
+	results chan queue.Result
+}
+
+func (m *mockResultQueue) Results(ctx context.Context) (<-chan queue.Result, error) {
+	if m.results == nil {
+		return nil, queue.ErrResultsDisabled
+	}
+	return m.results, nil
+}
+
+func TestReconcileOrphans_SettlesKnownFiles(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		queue       queue.Queue
+		keepsQueued bool
+	}{
+		{"results", &mockResultQueue{results: make(chan queue.Result)}, true},
+		{"results disabled", &mockResultQueue{}, false},
+		{"no results", &MockQueue{}, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			incomingDir := filepath.Join(tmpDir, "incoming")
+
+			store := &MockStorage{processed: make(map[string]bool), records: make(map[string]*storage.Record)}
+			w, err := New(Config{
+				Paths:             []string{incomingDir},
+				CleanupInterval:   1 * time.Minute,
+				MaxWorkers:        1,
+				MaxFilesPerSecond: 10,
+				WorkingDir:        tmpDir,
+				Queue:             tt.queue,
+				Storage:           store,
+				Logger:            logger.New(logger.Config{Level: "error", Format: "text", Output: "stdout"}),
+			})
+			if err != nil {
+				t.Fatalf("Failed to create watcher: %v", err)
+			}
+			if err := w.createDirectories(); err != nil {
+				t.Fatal(err)
+			}
+
+			for name, status := range map[string]string{
+				"enqueued.xml":  storage.StatusEnqueued,
+				"processed.xml": storage.StatusProcessed,
+				"failed.xml":    storage.StatusFailed,
+			} {
+				path := filepath.Join(tmpDir, "processing", name)
+				if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+					t.Fatal(err)
+				}
+				hash, err := w.calculateHash(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				store.records[hash] = &storage.Record{Hash: hash, Status: status}
+			}
+
+			w.subscribeResults()
+			if err := w.reconcileOrphans(); err != nil {
+				t.Fatalf("reconcileOrphans() failed: %v", err)
+			}
+
+			if !isRegularFile(filepath.Join(tmpDir, "processed", "processed.xml")) {
+				t.Error("processed orphan was not moved to processed")
+			}
+			if !isRegularFile(filepath.Join(tmpDir, "failed", "failed.xml")) {
+				t.Error("failed orphan was not moved to failed")
+			}
+
+			queued := "processing"
+			if !tt.keepsQueued {
+				queued = "incoming"
+			}
+			if !isRegularFile(filepath.Join(tmpDir, queued, "enqueued.xml")) {
+				t.Errorf("enqueued orphan is not in %s", queued)
+			}
+		})
+	}
+}
+
 func TestCalculateHash(t *testing.T) {
 	tmpDir := t.TempDir()
 	testFile := filepath.Join(tmpDir, "test.txt")
@@ -342,3 +487,43 @@ func TestCalculateHash(t *testing.T) {
 		t.Error("Hash is not deterministic")
 	}
 }
+
+func TestSourceFor(t *testing.T) {
+	w := &Watcher{cfg: Config{Paths: []string{"/data/incoming", "/data/incoming/partner-a", "/other"}}}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/data/incoming/a.xml", "/data/incoming"},
+		{"/data/incoming/partner-a/nfe/b.xml", "/data/incoming/partner-a"},
+		{"/other/c.xml", "/other"},
+		{"/data/incoming-old/d.xml", ""},
+	}
+
+	for _, tt := range tests {
+		if got := w.sourceFor(tt.path); got != tt.want {
+			t.Errorf("sourceFor(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestWatchLock_CancelsOnLost(t *testing.T) {
+	w := &Watcher{cfg: Config{Logger: logger.New(logger.Config{Level: "error", Format: "text", Output: "stdout"})}}
+	lock := &MockLock{token: 7, lost: make(chan struct{})}
+
+	ctx, cancel := w.watchLock(context.Background(), lock, "abc")
+	defer cancel()
+
+	close(lock.lost)
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("context not cancelled after the lock was lost")
+	}
+
+	if !errors.Is(context.Cause(ctx), ErrLockLost) {
+		t.Errorf("cause = %v, want ErrLockLost", context.Cause(ctx))
+	}
+}
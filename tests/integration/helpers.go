@@ -23,7 +23,7 @@ type TestEnvironment struct {
 	Config  watcher.Config
 	Watcher *watcher.Watcher
 	Queue   *MockQueue
-	Storage *MockStorage
+	Storage *storage.MemoryStorage
 	Logger  *logger.Logger
 	Ctx     context.Context
 	Cancel  context.CancelFunc
@@ -63,64 +63,6 @@ func (m *MockQueue) GetMessage(index int) *queue.Message {
 	return m.Messages[index]
 }
 
-// MockStorage implements storage.Storage for testing
-type MockStorage struct {
-	mu              sync.Mutex
-	ProcessedHashes map[string]bool
-	EnqueuedFiles   map[string]string
-	FailedFiles     map[string]string
-}
-
-func NewMockStorage() *MockStorage {
-	return &MockStorage{
-		ProcessedHashes: make(map[string]bool),
-		EnqueuedFiles:   make(map[string]string),
-		FailedFiles:     make(map[string]string),
-	}
-}
-
-func (m *MockStorage) IsProcessed(ctx context.Context, hash string) (bool, error) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	return m.ProcessedHashes[hash], nil
-}
-
-func (m *MockStorage) MarkEnqueued(ctx context.Context, hash, path string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	m.EnqueuedFiles[hash] = path
-	return nil
-}
-
-func (m *MockStorage) MarkProcessed(ctx context.Context, hash string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	m.ProcessedHashes[hash] = true
-	return nil
-}
-
-func (m *MockStorage) MarkFailed(ctx context.Context, hash, reason string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	m.FailedFiles[hash] = reason
-	return nil
-}
-
-func (m *MockStorage) GetLock(ctx context.Context, hash string) (storage.Lock, error) {
-	return &MockLock{}, nil
-}
-
-func (m *MockStorage) Close() error {
-	return nil
-}
-
-// MockLock implements storage.Lock for testing
-type MockLock struct{}
-
-func (m *MockLock) Release(ctx context.Context) error {
-	return nil
-}
-
 // setupTestEnvironment creates a test environment
 func setupTestEnvironment(t *testing.T) *TestEnvironment {
 	t.Helper()
@@ -146,9 +88,9 @@ func setupTestEnvironment(t *testing.T) *TestEnvironment {
 		Output: "stdout",
 	})
 
-	// Create mock queue and storage
+	// Create mock queue and in-memory storage
 	mockQueue := &MockQueue{Messages: make([]*queue.Message, 0)}
-	mockStorage := NewMockStorage()
+	memStorage := storage.NewMemoryStorage()
 
 	// Create context
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -175,7 +117,7 @@ func setupTestEnvironment(t *testing.T) *TestEnvironment {
 			Tmp:        "tmp",
 		},
 		Queue:   mockQueue,
-		Storage: mockStorage,
+		Storage: memStorage,
 		Logger:  log,
 	}
 
@@ -190,7 +132,7 @@ func setupTestEnvironment(t *testing.T) *TestEnvironment {
 		Config:  cfg,
 		Watcher: w,
 		Queue:   mockQueue,
-		Storage: mockStorage,
+		Storage: memStorage,
 		Logger:  log,
 		Ctx:     ctx,
 		Cancel:  cancel,
@@ -203,6 +145,7 @@ func (env *TestEnvironment) cleanup() {
 	if env.Watcher != nil {
 		_ = env.Watcher.Stop(context.Background())
 	}
+	_ = env.Storage.Close()
 	_ = os.RemoveAll(env.TempDir)
 }
 
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/fabyo/gordon-watcher/internal/storage"
 	"github.com/fabyo/gordon-watcher/internal/watcher"
 )
 
@@ -127,12 +128,12 @@ func TestWatcher_DuplicateHandling(t *testing.T) {
 	hash := env.Queue.GetMessage(0).Hash
 
 	// Verify hash was marked as enqueued in storage
-	env.Storage.mu.Lock()
-	_, exists := env.Storage.EnqueuedFiles[hash]
-	env.Storage.mu.Unlock()
-
-	if !exists {
-		t.Errorf("Expected hash to be marked as enqueued in storage")
+	rec, err := env.Storage.Get(env.Ctx, hash)
+	if err != nil {
+		t.Fatalf("Failed to look up hash: %v", err)
+	}
+	if rec.Status != storage.StatusEnqueued {
+		t.Errorf("Expected hash to be marked as enqueued in storage, got %s", rec.Status)
 	}
 
 	// Simulate marking as processed (this would normally be done by the consumer)